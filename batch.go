@@ -0,0 +1,362 @@
+package gs2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchStatus is the outcome of decoding and validating a file in a batch.
+type BatchStatus string
+
+// Batch statuses, from best to worst.
+const (
+	BatchOK      BatchStatus = "ok"      // Valid without warnings.
+	BatchWarning BatchStatus = "warning" // Valid with warnings.
+	BatchInvalid BatchStatus = "invalid" // Decoded, but failed validation.
+	BatchSyntax  BatchStatus = "syntax"  // Couldn't be decoded, or exceeded the decoding limits.
+	BatchFailed  BatchStatus = "failed"  // Couldn't be read.
+)
+
+// BatchStatuses are the batch statuses, from best to worst.
+var BatchStatuses = []BatchStatus{BatchOK, BatchWarning, BatchInvalid, BatchSyntax, BatchFailed}
+
+// Categories of problems that aren't found by rules.
+const (
+	CategorySyntax = "syntax" // The file couldn't be decoded.
+	CategoryLimits = "limits" // The file exceeded the decoding limits.
+	CategoryIO     = "io"     // The file couldn't be read.
+)
+
+// BatchProblem is a problem with a file in a batch.
+type BatchProblem struct {
+	Category string   `json:"category"` // ID of the rule finding the problem, or one of the categories above.
+	Severity Severity `json:"severity"`
+	Line     int      `json:"line,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// BatchResult is the result of decoding and validating a file in a batch.
+type BatchResult struct {
+	File     string         `json:"file"` // Name of the file, followed by the name of the file in it if it is a zip archive.
+	Status   BatchStatus    `json:"status"`
+	From     string         `json:"from,omitempty"` // Sender of the file.
+	Objects  int            `json:"objects"`        // Meter readings and time series.
+	Values   int            `json:"values"`         // Values of the time series.
+	Took     time.Duration  `json:"took"`
+	Problems []BatchProblem `json:"problems,omitempty"`
+}
+
+// SenderTotals are the totals of the files from a sender in a batch.
+type SenderTotals struct {
+	Files   int `json:"files"`
+	Invalid int `json:"invalid"` // Files that are neither ok nor valid with warnings.
+	Objects int `json:"objects"`
+	Values  int `json:"values"`
+}
+
+// BatchSummary summarizes the results of a batch.
+type BatchSummary struct {
+	Files      int                      `json:"files"`
+	Took       time.Duration            `json:"took"`
+	Statuses   map[BatchStatus]int      `json:"statuses"`
+	Categories map[string]int           `json:"categories"` // Number of files with problems of each category.
+	Senders    map[string]*SenderTotals `json:"senders"`    // Totals by sender. Files that can't be decoded have no sender.
+	Slowest    []BatchResult            `json:"slowest"`    // Slowest files, slowest first.
+}
+
+// Status returns the worst status of the files in s, or BatchOK if there are none.
+func (s *BatchSummary) Status() BatchStatus {
+	for i := len(BatchStatuses) - 1; i >= 0; i-- {
+		if s.Statuses[BatchStatuses[i]] > 0 {
+			return BatchStatuses[i]
+		}
+	}
+
+	return BatchOK
+}
+
+// Batch decodes and validates many files concurrently. Files compressed with gzip are decompressed, and each file in a zip
+// archive gets its own result, see OpenFile.
+type Batch struct {
+	Workers  int                         // Files decoded at once. Less than 1 means GOMAXPROCS.
+	Decoding []DecoderOption             // Options for decoding the files. Validators set here aren't run.
+	Rules    func(sender string) RuleSet // Rules to check the files from sender with. Nil means the enabled DefaultRules.
+	Report   io.Writer                   // If set, the result of each file is written to it as a line of JSON.
+	Slowest  int                         // Number of slowest files in the summary.
+}
+
+// Run decodes and validates the files, and returns a summary of the results. Results are written to the report in the
+// order the files are done. If ctx is done, the remaining files fail with its error, which is also returned. Other errors
+// are from writing the report.
+func (b *Batch) Run(ctx context.Context, files []string) (*BatchSummary, error) {
+	start := time.Now()
+
+	workers := b.Workers
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	rules := b.Rules
+	if rules == nil {
+		defaults := DefaultRules().Enabled()
+		rules = func(string) RuleSet { return defaults }
+	}
+
+	paths := make(chan string)
+	results := make(chan BatchResult)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// The validators are cleared last, so the rules are the only validation even if Decoding sets validators.
+			dec := NewDecoder(nil, append(append([]DecoderOption(nil), b.Decoding...), DecodeValidators())...)
+			for path := range paths {
+				for _, result := range b.run(ctx, dec, rules, path) {
+					results <- result
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for _, path := range files {
+			paths <- path
+		}
+		close(paths)
+		wg.Wait()
+		close(results)
+	}()
+
+	summary := newBatchSummary()
+	var reportErr error
+	for result := range results {
+		summary.add(result, b.Slowest)
+		if b.Report != nil && reportErr == nil {
+			reportErr = writeJSONLine(b.Report, result)
+		}
+	}
+	summary.Took = time.Since(start)
+
+	if reportErr != nil {
+		return summary, reportErr
+	}
+
+	return summary, ctx.Err()
+}
+
+// run decodes and validates the file at path with dec, and returns the result of each file in it.
+func (b *Batch) run(ctx context.Context, dec *Decoder, rules func(string) RuleSet, path string) []BatchResult {
+	start := time.Now()
+	failed := func(name string, err error) BatchResult {
+		return BatchResult{File: name, Status: BatchFailed, Took: time.Since(start),
+			Problems: []BatchProblem{{Category: CategoryIO, Severity: SeverityError, Message: err.Error()}}}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return []BatchResult{failed(path, err)}
+	}
+
+	in, err := OpenFile(path)
+	if err != nil {
+		return []BatchResult{failed(path, err)}
+	}
+	defer in.Close()
+
+	if len(in.Entries) == 0 {
+		return []BatchResult{failed(path, errors.New("zip archive has no files"))}
+	}
+
+	var results []BatchResult
+	for _, entry := range in.Entries {
+		name := path
+		if in.Compression == CompressionZip {
+			name = path + "/" + entry.Name
+		}
+
+		start = time.Now()
+		r, err := entry.Open()
+		if err != nil {
+			results = append(results, failed(name, err))
+			continue
+		}
+
+		dec.Reset(r)
+		g, err := dec.DecodeContext(ctx)
+		r.Close()
+		if err != nil {
+			results = append(results, decodeFailure(name, err, time.Since(start)))
+			continue
+		}
+
+		result := BatchResult{File: name, Status: BatchOK, From: g.StartMessage.From,
+			Objects: len(g.MeterReadings) + len(g.TimeSeries)}
+		for _, ts := range g.TimeSeries {
+			result.Values += len(ts.Value)
+		}
+
+		findings := rules(g.StartMessage.From).CheckContext(ctx, g)
+		if err := ctx.Err(); err != nil {
+			results = append(results, failed(name, err))
+			continue
+		}
+
+		for _, f := range findings {
+			p := BatchProblem{Category: f.Rule, Severity: f.Severity, Message: f.Err.Error()}
+
+			var objectErr *ObjectError
+			if errors.As(f.Err, &objectErr) {
+				p.Line = dec.BlockLine(objectErr.Object, objectErr.Index)
+			}
+			result.Problems = append(result.Problems, p)
+
+			switch {
+			case f.Severity >= SeverityError:
+				result.Status = BatchInvalid
+			case f.Severity == SeverityWarning && result.Status == BatchOK:
+				result.Status = BatchWarning
+			}
+		}
+
+		result.Took = time.Since(start)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// decodeFailure returns the result of a file that couldn't be decoded.
+func decodeFailure(name string, err error, took time.Duration) BatchResult {
+	result := BatchResult{File: name, Status: BatchSyntax, Took: took}
+
+	var limitErr *LimitError
+	var syntaxErr *SyntaxError
+	switch {
+	case errors.As(err, &limitErr):
+		result.Problems = []BatchProblem{{Category: CategoryLimits, Severity: SeverityError, Line: limitErr.Line,
+			Message: fmt.Sprintf("%s exceeds limit of %d", limitErr.Limit, limitErr.Max)}}
+	case errors.As(err, &syntaxErr):
+		result.Problems = []BatchProblem{{Category: CategorySyntax, Severity: SeverityError, Line: syntaxErr.Line,
+			Message: syntaxErr.Err.Error()}}
+	default:
+		result.Status = BatchFailed
+		result.Problems = []BatchProblem{{Category: CategoryIO, Severity: SeverityError, Message: err.Error()}}
+	}
+
+	return result
+}
+
+func newBatchSummary() *BatchSummary {
+	return &BatchSummary{
+		Statuses:   map[BatchStatus]int{},
+		Categories: map[string]int{},
+		Senders:    map[string]*SenderTotals{},
+		Slowest:    []BatchResult{},
+	}
+}
+
+// add adds the result of a file to s, keeping the given number of slowest files.
+func (s *BatchSummary) add(r BatchResult, slowest int) {
+	s.Files++
+	s.Statuses[r.Status]++
+
+	categories := map[string]bool{}
+	for _, p := range r.Problems {
+		if p.Severity >= SeverityWarning && !categories[p.Category] {
+			categories[p.Category] = true
+			s.Categories[p.Category]++
+		}
+	}
+
+	totals, ok := s.Senders[r.From]
+	if !ok {
+		totals = &SenderTotals{}
+		s.Senders[r.From] = totals
+	}
+	totals.Files++
+	if r.Status != BatchOK && r.Status != BatchWarning {
+		totals.Invalid++
+	}
+	totals.Objects += r.Objects
+	totals.Values += r.Values
+
+	if slowest > 0 && (len(s.Slowest) < slowest || r.Took > s.Slowest[len(s.Slowest)-1].Took) {
+		i := sort.Search(len(s.Slowest), func(i int) bool { return s.Slowest[i].Took < r.Took })
+		s.Slowest = append(s.Slowest, BatchResult{})
+		copy(s.Slowest[i+1:], s.Slowest[i:])
+		s.Slowest[i] = r
+		if len(s.Slowest) > slowest {
+			s.Slowest = s.Slowest[:slowest]
+		}
+	}
+}
+
+// BatchFiles returns the files matching the glob patterns, see filepath.Match, in order. Directories, given or matched, are
+// walked recursively, leaving out hidden files and directories. A pattern matching nothing is an error.
+func BatchFiles(patterns ...string) ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no such file", pattern)
+		}
+
+		for _, match := range matches {
+			fi, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !fi.IsDir() {
+				add(match)
+				continue
+			}
+
+			err = filepath.Walk(match, func(path string, fi os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if path != match && strings.HasPrefix(fi.Name(), ".") {
+					if fi.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if fi.Mode().IsRegular() {
+					add(path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// writeJSONLine writes v to w as a line of JSON.
+func writeJSONLine(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,188 @@
+package gs2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// encodeTest encodes g without validators.
+func encodeTest(t *testing.T, g *GS2) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, EncodeValidators()).Encode(g); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// batchTestFiles writes files with each batch status to a new directory, and returns the directory.
+func batchTestFiles(t *testing.T) string {
+	ok := longTimeSeries(10)
+	ok.StartMessage.From = "a"
+
+	warning := longTimeSeries(10)
+	warning.StartMessage.From = "b"
+	warning.TimeSeries[0].Stop = warning.TimeSeries[0].Stop.Add(time.Hour)
+
+	invalid := longTimeSeries(10)
+	invalid.StartMessage.From = "a"
+	invalid.TimeSeries[0].Sum = 1
+
+	dir := t.TempDir()
+	for name, data := range map[string][]byte{
+		"ok.gs2":                encodeTest(t, ok),
+		"sub/warning.gs2":       encodeTest(t, warning),
+		"sub/invalid.gs2.gz":    compressGzip(t, encodeTest(t, invalid)),
+		"sub/deeper/syntax.gs2": []byte("##Start-message\n#Id=1\n##Time-series\n#Step=x\n"),
+		"archive.zip":           compressZip(t, "ok.gs2", string(encodeTest(t, ok)), "invalid.gs2", string(encodeTest(t, invalid))),
+		".hidden/ok.gs2":        encodeTest(t, ok),
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestBatch_Run(t *testing.T) {
+	dir := batchTestFiles(t)
+	files, err := BatchFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := DefaultRules().Only("no-of-objects", "time-series-values", "time-series-period")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report bytes.Buffer
+	b := Batch{Workers: 3, Rules: func(string) RuleSet { return rules.Enabled() }, Report: &report, Slowest: 2}
+	summary, err := b.Run(context.Background(), files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Files != 6 {
+		t.Errorf("expected 6 files, but got %d", summary.Files)
+	}
+
+	expectedStatuses := map[BatchStatus]int{BatchOK: 2, BatchWarning: 1, BatchInvalid: 2, BatchSyntax: 1}
+	if !reflect.DeepEqual(summary.Statuses, expectedStatuses) {
+		t.Errorf("expected statuses %v, but got %v", expectedStatuses, summary.Statuses)
+	}
+	if status := summary.Status(); status != BatchSyntax {
+		t.Errorf("expected status %s, but got %s", BatchSyntax, status)
+	}
+
+	expectedCategories := map[string]int{"time-series-period": 1, "time-series-values": 2, CategorySyntax: 1}
+	if !reflect.DeepEqual(summary.Categories, expectedCategories) {
+		t.Errorf("expected categories %v, but got %v", expectedCategories, summary.Categories)
+	}
+
+	expectedSenders := map[string]*SenderTotals{
+		"a": {Files: 4, Invalid: 2, Objects: 4, Values: 40},
+		"b": {Files: 1, Objects: 1, Values: 10},
+		"":  {Files: 1, Invalid: 1},
+	}
+	if !reflect.DeepEqual(summary.Senders, expectedSenders) {
+		t.Errorf("expected senders %+v, but got %+v", expectedSenders, summary.Senders)
+	}
+
+	if len(summary.Slowest) != 2 || summary.Slowest[0].Took < summary.Slowest[1].Took {
+		t.Errorf("expected the 2 slowest files, slowest first, but got %+v", summary.Slowest)
+	}
+
+	var lines []BatchResult
+	for _, line := range strings.Split(strings.TrimSpace(report.String()), "\n") {
+		var result BatchResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, result)
+	}
+	if len(lines) != 6 {
+		t.Fatalf("expected a line for each of 6 files, but got %d", len(lines))
+	}
+	for _, result := range lines {
+		if result.File == filepath.Join(dir, "sub", "deeper", "syntax.gs2") {
+			expected := []BatchProblem{{Category: CategorySyntax, Severity: SeverityError, Line: 4, Message: `invalid duration "x"`}}
+			if !reflect.DeepEqual(result.Problems, expected) {
+				t.Errorf("expected problems %+v, but got %+v", expected, result.Problems)
+			}
+		}
+	}
+}
+
+func TestBatch_RunCancelled(t *testing.T) {
+	files, err := BatchFiles(batchTestFiles(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	summary, err := (&Batch{}).Run(ctx, files)
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, but got %v", err)
+	}
+	if summary.Statuses[BatchFailed] != len(files) {
+		t.Errorf("expected all %d files to fail, but got %v", len(files), summary.Statuses)
+	}
+}
+
+func TestBatch_RunDecodingValidators(t *testing.T) {
+	files, err := BatchFiles(batchTestFiles(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failing := func(*GS2) error { return errors.New("failed") }
+	b := Batch{Decoding: []DecoderOption{DecodeValidators(failing), DecodeAppendValidators(failing)}}
+	summary, err := b.Run(context.Background(), files[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Statuses[BatchOK] != 1 {
+		t.Errorf("expected the validators in Decoding not to run, but got %v", summary.Statuses)
+	}
+}
+
+func TestBatchFiles(t *testing.T) {
+	dir := batchTestFiles(t)
+
+	files, err := BatchFiles(filepath.Join(dir, "*.gs2"), filepath.Join(dir, "sub"), filepath.Join(dir, "ok.gs2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "ok.gs2"),
+		filepath.Join(dir, "sub", "deeper", "syntax.gs2"),
+		filepath.Join(dir, "sub", "invalid.gs2.gz"),
+		filepath.Join(dir, "sub", "warning.gs2"),
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %q, but got %q", expected, files)
+	}
+
+	if _, err := BatchFiles(filepath.Join(dir, "*.csv")); err == nil {
+		t.Error("expected an error for a pattern matching nothing")
+	}
+}
@@ -0,0 +1,450 @@
+// Command generate-codec writes MarshalGS2 and UnmarshalGS2 methods for structs with gs2 tags, so the gs2 Decoder and
+// Encoder can decode and encode them without walking their tags with reflection.
+//
+// Usage:
+//
+//	generate-codec -type T1,T2 [-output file] [dir]
+//
+// The types are read from the package in dir, or the current directory, and the methods are written to
+// <package>_codec.go in that directory unless an output file is given. It is meant to be run by go generate:
+//
+//	//go:generate go run github.com/3lvia/gs2/cmd/generate-codec -type MyTimeSeries
+//
+// Every field of the types must have a gs2 tag with the name of an attribute, optionally followed by omitempty, and a type
+// the Decoder supports: string, int, float64, time.Time, time.Duration, gs2.Triplet, []gs2.Triplet, or a named string, int
+// or float64 type. Named string types with MarshalText and UnmarshalText methods are encoded and decoded with them. Tags
+// without a name, unknown options, names used by more than one field and unsupported types are reported as errors, and no
+// file is written.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+const gs2Path = "github.com/3lvia/gs2"
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of type names; required")
+	output := flag.String("output", "", "output file name; default <dir>/<package>_codec.go")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: generate-codec -type T1,T2 [-output file] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+
+	if err := run(dir, strings.Split(*typeNames, ","), *output); err != nil {
+		fmt.Fprintf(os.Stderr, "generate-codec: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, typeNames []string, output string) error {
+	files, err := build.Default.ImportDir(dir, 0)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = filepath.Join(dir, files.Name+"_codec.go")
+	}
+
+	// The output is left out, since it is replaced and may not compile after the types changed.
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for _, name := range files.GoFiles {
+		path := filepath.Join(dir, name)
+		if same(path, output) {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, f)
+	}
+
+	exports, err := exportData(dir, files.Imports)
+	if err != nil {
+		return err
+	}
+
+	// Errors elsewhere in the package don't matter unless they make the fields of the types invalid.
+	lookup := func(path string) (io.ReadCloser, error) {
+		if exports[path] == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(exports[path])
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "gc", lookup), Error: func(error) {}}
+	pkg, typeErr := conf.Check(files.Name, fset, parsed, nil)
+
+	g := generator{pkg: pkg, typeErr: typeErr, self: pkg.Name() == "gs2" && pkg.Scope().Lookup("AttributeDecoder") != nil}
+	for _, name := range typeNames {
+		if err := g.generate(strings.TrimSpace(name)); err != nil {
+			return err
+		}
+	}
+
+	src, err := format.Source(g.source())
+	if err != nil {
+		return fmt.Errorf("formatting output: %w", err)
+	}
+
+	return os.WriteFile(output, src, 0o644)
+}
+
+// exportData returns the files with the export data of the given packages and their dependencies, as built by the go
+// command in dir.
+func exportData(dir string, imports []string) (map[string]string, error) {
+	exports := map[string]string{}
+	if len(imports) == 0 {
+		return exports, nil
+	}
+
+	cmd := exec.Command("go", append([]string{"list", "-export", "-deps", "-f", "{{.ImportPath}}={{.Export}}"}, imports...)...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing imports: %w", err)
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if path, export, ok := strings.Cut(line, "="); ok {
+			exports[path] = export
+		}
+	}
+
+	return exports, nil
+}
+
+// same reports whether the paths are the same file.
+func same(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+// kind is how a field is decoded and encoded.
+type kind int
+
+const (
+	kindString kind = iota
+	kindInt
+	kindFloat
+	kindTime
+	kindDuration
+	kindTriplet
+	kindTriplets
+)
+
+// methods are the methods of AttributeDecoder and AttributeEncoder by kind.
+var methods = map[kind]string{
+	kindString:   "String",
+	kindInt:      "Int",
+	kindFloat:    "Float",
+	kindTime:     "Time",
+	kindDuration: "Duration",
+	kindTriplet:  "Triplet",
+	kindTriplets: "Triplets",
+}
+
+// basic are the Go types of the kinds of named types.
+var basic = map[kind]string{
+	kindString: "string",
+	kindInt:    "int",
+	kindFloat:  "float64",
+}
+
+type field struct {
+	name      string // Name of the Go field.
+	attribute string
+	omitempty bool
+	kind      kind
+	named     bool // Named type converted to the Go type of the kind.
+	unmarshal bool // Decoded with UnmarshalText.
+	marshal   bool // Encoded with MarshalText.
+}
+
+type generator struct {
+	pkg     *types.Package
+	typeErr error // First error type-checking the package.
+	self    bool  // Generating for package gs2 itself.
+	buf     bytes.Buffer
+}
+
+// qualify returns name in package gs2 as seen from the generated code.
+func (g *generator) qualify(name string) string {
+	if g.self {
+		return name
+	}
+
+	return "gs2." + name
+}
+
+func (g *generator) source() []byte {
+	var header bytes.Buffer
+	fmt.Fprintf(&header, "// Code generated by generate-codec; DO NOT EDIT.\n\npackage %s\n\n", g.pkg.Name())
+	if !g.self {
+		fmt.Fprintf(&header, "import %q\n\n", gs2Path)
+	}
+
+	return append(header.Bytes(), g.buf.Bytes()...)
+}
+
+func (g *generator) generate(typeName string) error {
+	obj, ok := g.pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return fmt.Errorf("type %s not found in package %s", typeName, g.pkg.Name())
+	}
+
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return fmt.Errorf("type %s is not a struct", typeName)
+	}
+
+	fields, err := g.fields(typeName, st)
+	if err != nil {
+		return err
+	}
+
+	names := lowerFirst(typeName) + "Attributes"
+	if g.pkg.Scope().Lookup(names) != nil {
+		return fmt.Errorf("type %s: %s is already declared", typeName, names)
+	}
+
+	recv := strings.ToLower(typeName[:1])
+	if recv == "a" || recv == "e" {
+		recv = "v"
+	}
+
+	fmt.Fprintf(&g.buf, "// %s are the attribute names of %s by field.\nvar %s = []string{\n", names, typeName, names)
+	for _, f := range fields {
+		fmt.Fprintf(&g.buf, "\t%q,\n", f.attribute)
+	}
+	fmt.Fprintf(&g.buf, "}\n\n")
+
+	fmt.Fprintf(&g.buf, "// UnmarshalGS2 decodes the attribute with the given name.\n")
+	fmt.Fprintf(&g.buf, "func (%s *%s) UnmarshalGS2(name []byte, a *%s) (bool, error) {\n", recv, typeName, g.qualify("AttributeDecoder"))
+	fmt.Fprintf(&g.buf, "switch %s(name, %s) {\n", g.qualify("LookupAttribute"), names)
+	for i, f := range fields {
+		ptr := "&" + recv + "." + f.name
+		switch {
+		case f.unmarshal:
+			fmt.Fprintf(&g.buf, "case %d:\nreturn true, a.Text(%s)\n", i, ptr)
+		case f.named:
+			fmt.Fprintf(&g.buf, "case %d:\nreturn true, a.%s((*%s)(%s))\n", i, methods[f.kind], basic[f.kind], ptr)
+		default:
+			fmt.Fprintf(&g.buf, "case %d:\nreturn true, a.%s(%s)\n", i, methods[f.kind], ptr)
+		}
+	}
+	fmt.Fprintf(&g.buf, "}\n\nreturn false, nil\n}\n\n")
+
+	fmt.Fprintf(&g.buf, "// MarshalGS2 writes the attributes of %s.\n", recv)
+	fmt.Fprintf(&g.buf, "func (%s %s) MarshalGS2(e *%s) error {\n", recv, typeName, g.qualify("AttributeEncoder"))
+	for _, f := range fields {
+		v := recv + "." + f.name
+		switch {
+		case f.marshal:
+			fmt.Fprintf(&g.buf, "e.Text(%q, %s, %s == \"\", %t)\n", f.attribute, v, v, f.omitempty)
+		case f.named:
+			fmt.Fprintf(&g.buf, "e.%s(%q, %s(%s), %t)\n", methods[f.kind], f.attribute, basic[f.kind], v, f.omitempty)
+		default:
+			fmt.Fprintf(&g.buf, "e.%s(%q, %s, %t)\n", methods[f.kind], f.attribute, v, f.omitempty)
+		}
+	}
+	fmt.Fprintf(&g.buf, "\nreturn e.Err()\n}\n\n")
+
+	return nil
+}
+
+// fields returns the fields of st, checking their tags and types like the Decoder and Encoder would at runtime.
+func (g *generator) fields(typeName string, st *types.Struct) ([]field, error) {
+	var (
+		fields []field
+		errs   []string
+		seen   = map[string]string{}
+	)
+
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		where := typeName + "." + v.Name()
+		fail := func(format string, args ...interface{}) {
+			errs = append(errs, where+": "+fmt.Sprintf(format, args...))
+		}
+
+		if v.Embedded() {
+			fail("embedded fields are not supported")
+			continue
+		}
+
+		tag, ok := reflect.StructTag(st.Tag(i)).Lookup("gs2")
+		if !ok {
+			fail("missing gs2 tag")
+			continue
+		}
+
+		split := strings.Split(tag, ",")
+		f := field{name: v.Name(), attribute: split[0]}
+		if f.attribute == "" {
+			fail("missing attribute name in gs2 tag %q", tag)
+			continue
+		}
+		for _, option := range split[1:] {
+			if option != "omitempty" {
+				fail("unknown option %q in gs2 tag %q", option, tag)
+			}
+			f.omitempty = true
+		}
+
+		if other, ok := seen[strings.ToLower(f.attribute)]; ok {
+			fail("attribute %s is also the name of %s", f.attribute, other)
+			continue
+		}
+		seen[strings.ToLower(f.attribute)] = v.Name()
+
+		if err := g.classify(&f, v.Type()); err != nil {
+			fail("%v", err)
+			continue
+		}
+
+		fields = append(fields, f)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+
+	return fields, nil
+}
+
+// classify sets how f, of type t, is decoded and encoded.
+func (g *generator) classify(f *field, t types.Type) error {
+	switch {
+	case isNamed(t, "time", "Time"):
+		f.kind = kindTime
+		return nil
+	case isNamed(t, "time", "Duration"):
+		f.kind = kindDuration
+		return nil
+	case g.isTriplet(t):
+		f.kind = kindTriplet
+		return nil
+	}
+
+	if s, ok := t.(*types.Slice); ok && g.isTriplet(s.Elem()) {
+		f.kind = kindTriplets
+		return nil
+	}
+
+	if !validType(t) && g.typeErr != nil {
+		return g.typeErr
+	}
+
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return fmt.Errorf("unsupported type %s", types.TypeString(t, types.RelativeTo(g.pkg)))
+	}
+
+	switch b.Kind() {
+	case types.String:
+		f.kind = kindString
+		// Like the Decoder and Encoder, only strings are decoded and encoded as text.
+		f.unmarshal = implements(types.NewPointer(t), "UnmarshalText", textUnmarshaler)
+		f.marshal = implements(t, "MarshalText", textMarshaler)
+	case types.Int:
+		f.kind = kindInt
+	case types.Float64:
+		f.kind = kindFloat
+	default:
+		return fmt.Errorf("unsupported type %s", types.TypeString(t, types.RelativeTo(g.pkg)))
+	}
+	f.named = t != b
+
+	return nil
+}
+
+func (g *generator) isTriplet(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Name() != "Triplet" || named.Obj().Pkg() == nil {
+		return false
+	}
+
+	if g.self {
+		return named.Obj().Pkg() == g.pkg
+	}
+
+	return named.Obj().Pkg().Path() == gs2Path
+}
+
+// validType reports whether t, or the elements of t, type-checked.
+func validType(t types.Type) bool {
+	if s, ok := t.(*types.Slice); ok {
+		t = s.Elem()
+	}
+
+	return t.Underlying() != types.Typ[types.Invalid]
+}
+
+func isNamed(t types.Type, pkg, name string) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == pkg && named.Obj().Name() == name
+}
+
+var (
+	byteSlice = types.NewSlice(types.Typ[types.Byte])
+	errorType = types.Universe.Lookup("error").Type()
+
+	// textUnmarshaler is the signature of UnmarshalText.
+	textUnmarshaler = types.NewSignatureType(nil, nil, nil,
+		types.NewTuple(types.NewParam(token.NoPos, nil, "text", byteSlice)),
+		types.NewTuple(types.NewParam(token.NoPos, nil, "", errorType)), false)
+
+	// textMarshaler is the signature of MarshalText.
+	textMarshaler = types.NewSignatureType(nil, nil, nil, nil,
+		types.NewTuple(types.NewParam(token.NoPos, nil, "", byteSlice), types.NewParam(token.NoPos, nil, "", errorType)), false)
+)
+
+// implements reports whether t has a method with the given name and signature.
+func implements(t types.Type, name string, sig *types.Signature) bool {
+	sel := types.NewMethodSet(t).Lookup(nil, name)
+	if sel == nil {
+		return false
+	}
+
+	return types.Identical(sel.Type(), sig)
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
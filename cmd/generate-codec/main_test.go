@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePackage writes a package p with the given source after its package clause to a new directory, and returns the
+// directory.
+func writePackage(t *testing.T, src string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte("package p\n\n"+src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields string
+		err    string // Expected in the error, or empty if the codec is written.
+	}{
+		{"ok", "Reference string `gs2:\"Reference\"`\nValue float64 `gs2:\"Value,omitempty\"`\n", ""},
+		{"empty name", "Reference string `gs2:\",omitempty\"`\n", `T.Reference: missing attribute name in gs2 tag ",omitempty"`},
+		{"unknown option", "Reference string `gs2:\"Reference,omitmissing\"`\n",
+			`T.Reference: unknown option "omitmissing" in gs2 tag "Reference,omitmissing"`},
+		{"same name", "Reference string `gs2:\"Reference\"`\nOther string `gs2:\"reference\"`\n",
+			"T.Other: attribute reference is also the name of Reference"},
+		{"unsupported type", "Values map[string]float64 `gs2:\"Values\"`\n", "T.Values: unsupported type map[string]float64"},
+		{"missing tag", "Reference string\n", "T.Reference: missing gs2 tag"},
+		{"several errors", "Reference string `gs2:\"\"`\nValues []int `gs2:\"Values\"`\n",
+			"T.Reference: missing attribute name in gs2 tag \"\"\nT.Values: unsupported type []int"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writePackage(t, "type T struct {\n"+test.fields+"}\n")
+			output := filepath.Join(dir, "p_codec.go")
+
+			err := run(dir, []string{"T"}, "")
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				src, err := os.ReadFile(output)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Contains(src, []byte("func (t *T) UnmarshalGS2(name []byte, a *gs2.AttributeDecoder) (bool, error) {")) {
+					t.Errorf("expected UnmarshalGS2 of T, but got\n%s", src)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected error %q, but got %v", test.err, err)
+			}
+			if _, err := os.Stat(output); !os.IsNotExist(err) {
+				t.Errorf("expected no output, but got %v", err)
+			}
+		})
+	}
+}
+
+func TestRun_Type(t *testing.T) {
+	dir := writePackage(t, "type T struct {\nReference string `gs2:\"Reference\"`\n}\n\ntype S string\n")
+
+	for typeName, expected := range map[string]string{
+		"U": "type U not found in package p",
+		"S": "type S is not a struct",
+	} {
+		if err := run(dir, []string{typeName}, ""); err == nil || err.Error() != expected {
+			t.Errorf("%s: expected error %q, but got %v", typeName, expected, err)
+		}
+	}
+}
+
+// TestRun_Generated checks that gs2_codec.go is what go generate writes, by generating it again from a copy of package gs2
+// without it.
+func TestRun_Generated(t *testing.T) {
+	root := filepath.Join("..", "..")
+	gs2, err := os.ReadFile(filepath.Join(root, "gs2.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const directive = "//go:generate go run ./cmd/generate-codec -type "
+	var typeNames []string
+	for _, line := range strings.Split(string(gs2), "\n") {
+		if strings.HasPrefix(line, directive) {
+			typeNames = strings.Split(strings.TrimPrefix(line, directive), ",")
+		}
+	}
+	if typeNames == nil {
+		t.Fatal("no go:generate directive of generate-codec in gs2.go")
+	}
+
+	dir := t.TempDir()
+	names, err := filepath.Glob(filepath.Join(root, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range append(names, filepath.Join(root, "go.mod")) {
+		base := filepath.Base(name)
+		if base == "gs2_codec.go" || strings.HasSuffix(base, "_test.go") {
+			continue
+		}
+
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, base), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := run(dir, typeNames, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	generated, err := os.ReadFile(filepath.Join(dir, "gs2_codec.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	committed, err := os.ReadFile(filepath.Join(root, "gs2_codec.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(generated, committed) {
+		t.Error("gs2_codec.go is not what go generate writes. Run go generate ./...")
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime"
+	"sort"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/3lvia/gs2"
+)
+
+func init() {
+	register(&command{
+		name:  "batch",
+		args:  "pattern|dir ...",
+		short: "Decode and validate many GS2 files concurrently, and summarize the results.",
+		run:   runBatch,
+	})
+}
+
+// batchExitCodes are the exit codes of the batch statuses.
+var batchExitCodes = map[gs2.BatchStatus]int{
+	gs2.BatchOK:      exitOK,
+	gs2.BatchWarning: exitWarning,
+	gs2.BatchInvalid: exitInvalid,
+	gs2.BatchSyntax:  exitSyntax,
+	gs2.BatchFailed:  exitFailure,
+}
+
+func runBatch(e *env, args []string) int {
+	c := commands["batch"]
+
+	var f commonFlags
+	fs := newFlagSet(e, c, &f)
+	workers := fs.Int("workers", runtime.GOMAXPROCS(0), "decode and validate `n` files at once")
+	report := fs.String("report", "", "write the result of each file as a line of JSON to `file`")
+	slowest := fs.Int("slowest", 10, "list the `n` slowest files")
+	fs.Lookup("json").Usage = "write the summary as JSON"
+	var rf validationFlags
+	rf.add(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	v, err := rf.validation()
+	if err != nil {
+		return f.fail(e, c.name, exitUsage, err)
+	}
+
+	files, err := gs2.BatchFiles(fs.Args()...)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	out, err := openOutput(e, f.output)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer out.Close()
+
+	b := gs2.Batch{Workers: *workers, Decoding: v.decoding, Rules: v.rulesFor, Slowest: *slowest}
+	if *report != "" {
+		reportOut, err := openOutput(e, *report)
+		if err != nil {
+			return f.fail(e, c.name, exitFailure, err)
+		}
+		defer reportOut.Close()
+		b.Report = reportOut
+	}
+
+	// An interrupted batch still reports the files that are done.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	summary, runErr := b.Run(ctx, files)
+	if f.json {
+		err = writeJSON(out, summary)
+	} else {
+		err = writeBatchSummary(out, summary)
+	}
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	if runErr != nil {
+		return f.fail(e, c.name, exitFailure, runErr)
+	}
+
+	return batchExitCodes[summary.Status()]
+}
+
+func writeBatchSummary(w io.Writer, s *gs2.BatchSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Files\t%d\n", s.Files)
+	fmt.Fprintf(tw, "Took\t%v\n", s.Took)
+
+	fmt.Fprintf(tw, "\nStatus\n")
+	for _, status := range gs2.BatchStatuses {
+		fmt.Fprintf(tw, "  %s\t%d\n", status, s.Statuses[status])
+	}
+
+	fmt.Fprintf(tw, "\nProblems\n")
+	writeCounts(tw, s.Categories)
+
+	fmt.Fprintf(tw, "\nSenders\n")
+	fmt.Fprintf(tw, "  From\tFiles\tInvalid\tObjects\tValues\n")
+	var senders []string
+	for sender := range s.Senders {
+		senders = append(senders, sender)
+	}
+	sort.Strings(senders)
+	for _, sender := range senders {
+		t := s.Senders[sender]
+		name := sender
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Fprintf(tw, "  %s\t%d\t%d\t%d\t%d\n", name, t.Files, t.Invalid, t.Objects, t.Values)
+	}
+
+	if len(s.Slowest) > 0 {
+		fmt.Fprintf(tw, "\nSlowest\n")
+		for _, r := range s.Slowest {
+			fmt.Fprintf(tw, "  %s\t%v\t%s\n", r.File, r.Took, r.Status)
+		}
+	}
+
+	return tw.Flush()
+}
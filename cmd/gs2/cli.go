@@ -0,0 +1,185 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/3lvia/gs2"
+)
+
+// commonFlags are the flags supported by every command.
+type commonFlags struct {
+	output string
+	json   bool
+}
+
+func newFlagSet(e *env, c *command, f *commonFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.StringVar(&f.output, "o", "", "write output to `file` instead of stdout")
+	fs.BoolVar(&f.json, "json", false, "write output as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: gs2 %s [flags] %s\n\n%s\n\nFlags:\n", c.name, c.args, c.short)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// parseFlags parses args and returns false together with the exit code if the command should stop.
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+
+	return exitOK, true
+}
+
+// fail reports err on stderr and returns code. In JSON mode the error is written as a JSON object.
+func (f *commonFlags) fail(e *env, name string, code int, err error) int {
+	if f.json {
+		writeJSON(e.stderr, struct {
+			Error string `json:"error"`
+			Code  int    `json:"code"`
+		}{err.Error(), code})
+	} else {
+		fmt.Fprintf(e.stderr, "gs2 %s: %v\n", name, err)
+	}
+
+	return code
+}
+
+// openInput opens the named file, or stdin if name is empty or "-", for commands reading a single GS2 file. Files
+// compressed with gzip are decompressed, and zip archives must hold a single file. The returned name is the name of the
+// file to report, see entryName.
+func openInput(e *env, name string) (io.ReadCloser, string, error) {
+	in, err := openInputs(e, name)
+	if err != nil {
+		return nil, name, err
+	}
+
+	if len(in.Entries) != 1 {
+		in.Close()
+		return nil, in.Name, fmt.Errorf("%s: zip archive has %d files, expected 1", in.Name, len(in.Entries))
+	}
+
+	r, err := in.Entries[0].Open()
+	if err != nil {
+		in.Close()
+		return nil, in.Name, err
+	}
+
+	return readCloser{r, closers{r, in}}, entryName(in, in.Entries[0]), nil
+}
+
+// openInputs opens the named file, or stdin if name is empty or "-", as an input that may be compressed or hold several
+// GS2 files, see gs2.OpenFile.
+func openInputs(e *env, name string) (*gs2.Input, error) {
+	if name == "" || name == "-" {
+		return gs2.NewInput("<stdin>", e.stdin)
+	}
+
+	return gs2.OpenFile(name)
+}
+
+// entryName returns the name of a file in an input to report, which is the name of the input followed by the name of the
+// file if the input is a zip archive.
+func entryName(in *gs2.Input, entry *gs2.Entry) string {
+	if in.Compression == gs2.CompressionZip {
+		return in.Name + "/" + entry.Name
+	}
+
+	return in.Name
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// closers closes all its closers, and returns the first error.
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var first error
+	for _, c := range cs {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// openOutput creates the named file, or returns stdout if name is empty or "-". Files ending in .gz are compressed with
+// gzip.
+func openOutput(e *env, name string) (io.WriteCloser, error) {
+	if name == "" || name == "-" {
+		return nopWriteCloser{e.stdout}, nil
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(name, ".gz") {
+		zw := gzip.NewWriter(f)
+		return writeCloser{zw, closers{zw, f}}, nil
+	}
+
+	return f, nil
+}
+
+type writeCloser struct {
+	io.Writer
+	io.Closer
+}
+
+// inputArg returns the single optional file argument of fs.
+func inputArg(fs *flag.FlagSet) (string, error) {
+	switch fs.NArg() {
+	case 0:
+		return "", nil
+	case 1:
+		return fs.Arg(0), nil
+	default:
+		return "", fmt.Errorf("expected at most one input file, got %d", fs.NArg())
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeJSONLine writes v as JSON on a single line, for logs.
+func writeJSONLine(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// decode decodes r without running any validators, so syntax errors can be told apart from validation errors.
+func decode(r io.Reader, opts ...gs2.DecoderOption) (*gs2.GS2, error) {
+	return decodeContext(context.Background(), r, opts...)
+}
+
+// decodeContext is like decode, but stops when ctx is done.
+func decodeContext(ctx context.Context, r io.Reader, opts ...gs2.DecoderOption) (*gs2.GS2, error) {
+	return gs2.NewDecoder(r, append([]gs2.DecoderOption{gs2.DecodeValidators()}, opts...)...).DecodeContext(ctx)
+}
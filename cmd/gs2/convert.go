@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/3lvia/gs2"
+)
+
+func init() {
+	register(&command{
+		name:  "convert",
+		args:  "[file]",
+		short: "Convert between GS2, JSON and CSV.",
+		run:   runConvert,
+	})
+}
+
+func runConvert(e *env, args []string) int {
+	c := commands["convert"]
+
+	var f commonFlags
+	fs := newFlagSet(e, c, &f)
+	from := fs.String("from", "gs2", "input `format`: gs2 or json")
+	to := fs.String("to", "", "output `format`: gs2, json or csv. Defaults to json when -json is given")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if *to == "" && f.json {
+		*to = "json"
+	}
+
+	name, err := inputArg(fs)
+	if err != nil || !validFormat(*to) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	in, _, err := openInput(e, name)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer in.Close()
+
+	var g *gs2.GS2
+	switch *from {
+	case "gs2":
+		g, err = decode(in)
+	case "json":
+		g, err = readJSON(in)
+	default:
+		fs.Usage()
+		return exitUsage
+	}
+	if err != nil {
+		return f.fail(e, c.name, exitSyntax, err)
+	}
+
+	out, err := openOutput(e, f.output)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer out.Close()
+
+	if err := convert(out, g, *to); err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	return exitOK
+}
+
+func validFormat(format string) bool {
+	switch format {
+	case "gs2", "json", "csv":
+		return true
+	}
+
+	return false
+}
+
+// convert writes g to w in the given format.
+func convert(w io.Writer, g *gs2.GS2, format string) error {
+	switch format {
+	case "gs2":
+		return gs2.NewEncoder(w, gs2.EncodeValidators(), gs2.EncodeCanonical()).Encode(g)
+	case "json":
+		return writeJSON(w, g)
+	case "csv":
+		return writeCSV(w, g)
+	}
+
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+func readJSON(r io.Reader) (*gs2.GS2, error) {
+	var g gs2.GS2
+	if err := json.NewDecoder(r).Decode(&g); err != nil {
+		return nil, err
+	}
+
+	return &g, nil
+}
+
+// valueRow is a single value in a flat listing of the values in a GS2 object.
+type valueRow struct {
+	Object          string              `json:"object"`
+	Reference       string              `json:"reference"`
+	Meter           string              `json:"meter"`
+	Channel         string              `json:"channel"`
+	DirectionOfFlow gs2.DirectionOfFlow `json:"directionOfFlow"`
+	Unit            string              `json:"unit"`
+	Time            time.Time           `json:"time"`
+	Value           float64             `json:"value"`
+	Quality         gs2.Quality         `json:"quality"`
+}
+
+// valueRows lists every value in g.
+func valueRows(g *gs2.GS2) []valueRow {
+	rows := []valueRow{}
+
+	for _, mr := range g.MeterReadings {
+		rows = append(rows, valueRow{
+			"Meter-reading", mr.Reference, mr.Meter, mr.Channel, mr.DirectionOfFlow, mr.Unit,
+			mr.Time, mr.Value.Value, mr.Value.Quality,
+		})
+	}
+
+	for _, ts := range g.TimeSeries {
+		for i, v := range ts.Value {
+			rows = append(rows, valueRow{
+				"Time-series", ts.Reference, ts.Meter, ts.Channel, ts.DirectionOfFlow, ts.Unit,
+				ts.ValueTime(i), v.Value, v.Quality,
+			})
+		}
+	}
+
+	return rows
+}
+
+var csvHeader = []string{"object", "reference", "meter", "channel", "direction", "unit", "time", "value", "quality"}
+
+// writeCSV writes every value in g as a separate row.
+func writeCSV(w io.Writer, g *gs2.GS2) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, r := range valueRows(g) {
+		if err := cw.Write([]string{
+			r.Object, r.Reference, r.Meter, r.Channel, r.DirectionOfFlow.String(), r.Unit,
+			formatTime(r.Time), formatFloat(r.Value), r.Quality.String(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
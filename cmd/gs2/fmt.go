@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/3lvia/gs2"
+)
+
+func init() {
+	register(&command{
+		name:  "fmt",
+		args:  "[file ...]",
+		short: "Rewrite GS2 files in canonical form.",
+		run:   runFmt,
+	})
+}
+
+type fmtResult struct {
+	File    string `json:"file"`
+	Changed bool   `json:"changed"`
+	Error   string `json:"error,omitempty"`
+}
+
+func runFmt(e *env, args []string) int {
+	c := commands["fmt"]
+
+	var f commonFlags
+	fs := newFlagSet(e, c, &f)
+	write := fs.Bool("w", false, "write result to the source file instead of stdout")
+	list := fs.Bool("l", false, "list files whose formatting differs from the canonical form")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	if (*write && contains(files, "-")) || (f.output != "" && (*write || len(files) > 1)) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	out, err := openOutput(e, f.output)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer out.Close()
+
+	var results []fmtResult
+	code := exitOK
+	for _, file := range files {
+		result, formatted, err := formatFile(e, file)
+		if err != nil {
+			result.Error = err.Error()
+			if _, ok := err.(*os.PathError); ok {
+				code = exitFailure
+			} else if code == exitOK {
+				code = exitSyntax
+			}
+			if !f.json {
+				fmt.Fprintf(e.stderr, "gs2 %s: %s: %v\n", c.name, result.File, err)
+			}
+		}
+		results = append(results, result)
+
+		if err != nil || f.json {
+			continue
+		}
+
+		if *list && result.Changed {
+			fmt.Fprintln(out, result.File)
+		}
+
+		if *write && result.Changed {
+			if err := writeFileAtomic(file, formatted); err != nil {
+				return f.fail(e, c.name, exitFailure, err)
+			}
+		}
+
+		if !*list && !*write {
+			if _, err := out.Write(formatted); err != nil {
+				return f.fail(e, c.name, exitFailure, err)
+			}
+		}
+	}
+
+	if f.json {
+		if err := writeJSON(out, results); err != nil {
+			return f.fail(e, c.name, exitFailure, err)
+		}
+	}
+
+	return code
+}
+
+func formatFile(e *env, name string) (fmtResult, []byte, error) {
+	in, name, err := openInput(e, name)
+	result := fmtResult{File: name}
+	if err != nil {
+		return result, nil, err
+	}
+	defer in.Close()
+
+	src, err := ioutil.ReadAll(in)
+	if err != nil {
+		return result, nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := gs2.Format(&buf, src); err != nil {
+		return result, nil, err
+	}
+
+	result.Changed = !bytes.Equal(src, buf.Bytes())
+	return result, buf.Bytes(), nil
+}
+
+// writeFileAtomic replaces the content of name with data, keeping its permissions and its gzip compression. The data is
+// written to a temporary file which is renamed, so name is never left partially written. Files in zip archives can't be
+// replaced.
+func writeFileAtomic(name string, data []byte) error {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	in, err := gs2.OpenFile(name)
+	if err != nil {
+		return err
+	}
+	in.Close()
+
+	switch in.Compression {
+	case gs2.CompressionZip:
+		return fmt.Errorf("%s: can't replace files in zip archives", name)
+	case gs2.CompressionGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, bytes.NewReader(data)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), fi.Mode().Perm()); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // So -location works without time zone data on the system.
+
+	"github.com/3lvia/gs2"
+)
+
+func init() {
+	register(&command{
+		name:  "generate",
+		args:  "",
+		short: "Generate a valid GS2 file of settlement data with random values.",
+		run:   runGenerate,
+	})
+}
+
+func runGenerate(e *env, args []string) int {
+	c := commands["generate"]
+
+	var f commonFlags
+	fs := newFlagSet(e, c, &f)
+	seed := fs.Int64("seed", 0, "random seed. Defaults to the current time, which is written to stderr")
+	meters := fs.Int("meters", 1, "number of meters, each with a time series")
+	readings := fs.Int("readings", 0, "number of meter readings of each meter")
+	location := fs.String("location", "UTC", "time `zone` of the period, e.g. Europe/Oslo")
+	start := fs.String("start", "2024-01-01", "start of the period. RFC 3339 or a `time` in -location, e.g. 2020-03-27")
+	stop := fs.String("stop", "", "end of the period, in the same formats as -start. Defaults to one day after -start")
+	step := fs.Duration("step", time.Hour, "length of each value")
+	dst := fs.String("dst", "", "move the period to the next `change` to or from daylight saving time: spring or autumn")
+	profiles := fs.String("profile", "flat", "comma separated `list` of load profiles of the meters in turn: flat, residential, commercial or solar")
+	gaps := fs.Float64("gaps", 0, "`fraction` of values that are missing")
+	qualities := fs.Float64("qualities", 0, "`fraction` of values that are corrected or estimated")
+	size := fs.Int("size", 0, "add meters until the file is about `bytes` long")
+	errs := fs.Int("errors", 0, "make the file invalid with a wrong Sum or No-of-values in `n` time series")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+		fmt.Fprintf(e.stderr, "gs2 %s: seed %d\n", c.name, *seed)
+	}
+
+	gen := gs2.Generator{
+		Seed:       *seed,
+		Meters:     *meters,
+		Step:       *step,
+		DST:        gs2.DSTChange(*dst),
+		Gaps:       *gaps,
+		Qualities:  *qualities,
+		Readings:   *readings,
+		TargetSize: *size,
+		Errors:     *errs,
+	}
+	for _, p := range splitList(*profiles) {
+		gen.Profiles = append(gen.Profiles, gs2.Profile(p))
+	}
+
+	var err error
+	if gen.Location, err = time.LoadLocation(*location); err != nil {
+		return f.fail(e, c.name, exitUsage, err)
+	}
+	if gen.Start, err = parseQueryTime(*start, gen.Location); err != nil {
+		return f.fail(e, c.name, exitUsage, err)
+	}
+	if gen.Stop, err = parseQueryTime(*stop, gen.Location); err != nil {
+		return f.fail(e, c.name, exitUsage, err)
+	}
+
+	g, err := gen.Generate()
+	if err != nil {
+		return f.fail(e, c.name, exitUsage, err)
+	}
+
+	out, err := openOutput(e, f.output)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer out.Close()
+
+	if f.json {
+		err = writeJSON(out, g)
+	} else {
+		var opts []gs2.EncoderOption
+		if *errs > 0 {
+			// The requested errors would fail the validators of the Encoder.
+			opts = append(opts, gs2.EncodeValidators())
+		}
+		err = gs2.NewEncoder(out, opts...).Encode(g)
+	}
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	return exitOK
+}
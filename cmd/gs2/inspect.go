@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/3lvia/gs2"
+)
+
+func init() {
+	register(&command{
+		name:  "inspect",
+		args:  "[file]",
+		short: "Show a summary of the content of a GS2 file.",
+		run:   runInspect,
+	})
+}
+
+func runInspect(e *env, args []string) int {
+	c := commands["inspect"]
+
+	var f commonFlags
+	fs := newFlagSet(e, c, &f)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	name, err := inputArg(fs)
+	if err != nil {
+		fs.Usage()
+		return exitUsage
+	}
+
+	in, _, err := openInput(e, name)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer in.Close()
+
+	g, err := decode(in)
+	if err != nil {
+		return f.fail(e, c.name, exitSyntax, err)
+	}
+
+	out, err := openOutput(e, f.output)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer out.Close()
+
+	summary := g.Summary()
+	if f.json {
+		err = writeJSON(out, summary)
+	} else {
+		err = writeSummary(out, summary)
+	}
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	return exitOK
+}
+
+func writeSummary(w io.Writer, s gs2.Summary) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Header\n")
+	fmt.Fprintf(tw, "  Id\t%s\n", s.Header.ID)
+	fmt.Fprintf(tw, "  Message-type\t%s\n", s.Header.MessageType)
+	fmt.Fprintf(tw, "  Version\t%s\n", s.Header.Version)
+	fmt.Fprintf(tw, "  Time\t%s\n", formatTime(s.Header.Time))
+	fmt.Fprintf(tw, "  From\t%s\n", s.Header.From)
+	fmt.Fprintf(tw, "  To\t%s\n", s.Header.To)
+	fmt.Fprintf(tw, "  GMT-reference\t%+d\n", s.Header.GMTReference)
+
+	fmt.Fprintf(tw, "\nObjects\n")
+	for _, name := range []string{"Start-message", "Meter-reading", "Time-series", "End-message"} {
+		fmt.Fprintf(tw, "  %s\t%d\n", name, s.Objects[name])
+	}
+	fmt.Fprintf(tw, "  Distinct meters\t%d\n", len(s.Meters))
+	fmt.Fprintf(tw, "  Distinct references\t%d\n", len(s.References))
+
+	fmt.Fprintf(tw, "\nPeriod\n")
+	fmt.Fprintf(tw, "  UTC\t%s\t%s\n", formatTime(s.Period.Start), formatTime(s.Period.Stop))
+	fmt.Fprintf(tw, "  Local\t%s\t%s\n", formatLocalTime(s.Period.LocalStart), formatLocalTime(s.Period.LocalStop))
+
+	fmt.Fprintf(tw, "\nSteps\n")
+	writeCounts(tw, s.Steps)
+
+	fmt.Fprintf(tw, "\nUnits\n")
+	writeCounts(tw, s.Units)
+
+	fmt.Fprintf(tw, "\nEnergy\n")
+	fmt.Fprintf(tw, "  Direction\tUnit\tTotal\n")
+	for _, e := range s.Energy {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", e.DirectionOfFlow, e.Unit, formatStatistic(e.Total))
+	}
+
+	fmt.Fprintf(tw, "\nQualities\n")
+	writeCounts(tw, s.Qualities)
+
+	fmt.Fprintf(tw, "\nSeries\n")
+	fmt.Fprintf(tw, "  Reference\tMeter\tValues\tMin\tMax\tMean\tPeak\n")
+	for _, series := range s.Series {
+		fmt.Fprintf(tw, "  %s\t%s\t%d\t%s\t%s\t%s\t%s\n", series.Reference, series.Meter, series.Values,
+			formatStatistic(series.Min), formatStatistic(series.Max), formatStatistic(series.Mean), formatTime(series.Peak))
+	}
+
+	return tw.Flush()
+}
+
+func writeCounts(w io.Writer, counts map[string]int) {
+	var keys []string
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := key
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Fprintf(w, "  %s\t%d\n", name, counts[key])
+	}
+}
+
+// formatStatistic rounds f to six decimals to hide floating point noise from summing values.
+func formatStatistic(f float64) string {
+	return formatFloat(math.Round(f*1e6) / 1e6)
+}
+
+func formatLocalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,96 @@
+// Command gs2 is a tool for working with GS2 files.
+//
+// Usage:
+//
+//	gs2 <command> [flags] [file]
+//
+// Every command reads from the given file, or from stdin if the file is omitted or "-", and writes to stdout unless an
+// output file is given with -o. All commands support -json for machine-readable output.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Exit codes used by all commands.
+const (
+	exitOK      = 0 // Success.
+	exitFailure = 1 // Operational failure, e.g. unable to read or write a file.
+	exitUsage   = 2 // Invalid command line.
+	exitSyntax  = 3 // Input could not be decoded.
+	exitInvalid = 4 // Input was decoded, but failed validation.
+	exitWarning = 5 // Input is valid, but validation gave warnings.
+)
+
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(env *env, args []string) int
+}
+
+var commands = map[string]*command{}
+
+func register(c *command) {
+	commands[c.name] = c
+}
+
+// env holds the standard streams used by a command. It makes it possible to run commands without touching the
+// process' streams.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(&env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}, os.Args[1:]))
+}
+
+func run(e *env, args []string) int {
+	if len(args) == 0 {
+		usage(e.stderr)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage(e.stdout)
+		return exitOK
+	}
+
+	c, exists := commands[args[0]]
+	if !exists {
+		fmt.Fprintf(e.stderr, "gs2: unknown command %q\n\n", args[0])
+		usage(e.stderr)
+		return exitUsage
+	}
+
+	return c.run(e, args[1:])
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: gs2 <command> [flags] [file]\n\nCommands:\n")
+
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].short)
+	}
+
+	fmt.Fprintf(w, "\nExit codes:\n")
+	fmt.Fprintf(w, "  %d  success\n", exitOK)
+	fmt.Fprintf(w, "  %d  operational failure\n", exitFailure)
+	fmt.Fprintf(w, "  %d  invalid command line\n", exitUsage)
+	fmt.Fprintf(w, "  %d  input could not be decoded\n", exitSyntax)
+	fmt.Fprintf(w, "  %d  input failed validation\n", exitInvalid)
+	fmt.Fprintf(w, "  %d  input passed validation with warnings\n", exitWarning)
+	fmt.Fprintf(w, "\nRun 'gs2 <command> -h' for help on a command.\n")
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runTest runs gs2 with args and stdin, and returns the exit code, stdout and stderr.
+func runTest(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(&env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}, args)
+	return code, stdout.String(), stderr.String()
+}
+
+// testFiles writes testdata/timeseries.gs2 and variants of it to a new directory, and returns the directory. The variants
+// give warnings (warning.gs2), fail validation (invalid.gs2), have a time series that can't be repaired (ambiguous.gs2) and
+// can't be decoded (syntax.gs2).
+func testFiles(t *testing.T) string {
+	t.Helper()
+
+	data, err := os.ReadFile("../../testdata/timeseries.gs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := string(data)
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"ok.gs2":        ok,
+		"warning.gs2":   strings.Replace(ok, "#Stop=2020-03-28.00:00:00", "#Stop=2020-03-28.01:00:00", 1),
+		"invalid.gs2":   strings.Replace(ok, "#Sum=27", "#Sum=28", 1),
+		"ambiguous.gs2": strings.Replace(strings.Replace(ok, "#Stop=2020-03-28.00:00:00\n", "", 1), "#No-of-values=24", "#No-of-values=25", 1),
+		"syntax.gs2":    "##Start-message\n#Id 1\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := testFiles(t)
+	file := func(name string) string { return filepath.Join(dir, name) }
+	ok, err := os.ReadFile(file("ok.gs2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		stdin  string
+		args   []string
+		code   int
+		stdout string // Expected in stdout.
+		stderr string // Expected in stderr.
+	}{
+		{"no command", "", nil, exitUsage, "", "Usage: gs2"},
+		{"help", "", []string{"help"}, exitOK, "Exit codes:", ""},
+		{"unknown command", "", []string{"lint"}, exitUsage, "", `unknown command "lint"`},
+
+		{"validate", "", []string{"validate", file("ok.gs2")}, exitOK, "ok.gs2: ok", ""},
+		{"validate stdin", string(ok), []string{"validate", "-"}, exitOK, "<stdin>: ok", ""},
+		{"validate warning", "", []string{"validate", file("warning.gs2")}, exitWarning, "warning: time-series-period", ""},
+		{"validate invalid", "", []string{"validate", file("invalid.gs2")}, exitInvalid, "error: time-series-values", ""},
+		{"validate syntax", "", []string{"validate", file("syntax.gs2")}, exitSyntax, "error: syntax", ""},
+		{"validate missing file", "", []string{"validate", file("missing.gs2")}, exitFailure, "error: io", ""},
+		{"validate strict", "", []string{"validate", "-strict", file("ok.gs2")}, exitInvalid, "error: vocabulary", ""},
+		{"validate junit", "", []string{"validate", "-format", "junit", file("invalid.gs2")}, exitInvalid, "<failure ", ""},
+		{"validate sarif", "", []string{"validate", "-format", "sarif", file("invalid.gs2")}, exitInvalid, `"ruleId": "time-series-values"`, ""},
+		{"validate unknown format", "", []string{"validate", "-format", "xml", file("ok.gs2")}, exitUsage, "", "Usage: gs2 validate"},
+		{"validate unknown flag", "", []string{"validate", "-bogus"}, exitUsage, "", "flag provided but not defined"},
+		{"validate help", "", []string{"validate", "-h"}, exitOK, "", "Usage: gs2 validate"},
+
+		{"convert csv", "", []string{"convert", "-to", "csv", file("ok.gs2")}, exitOK, "Time-series,meterpoint1,", ""},
+		{"convert json", "", []string{"convert", "-json", file("ok.gs2")}, exitOK, `"Reference": "meterpoint1"`, ""},
+		{"convert from json", "", []string{"convert", "-from", "json", "-to", "gs2", "-"}, exitSyntax, "", "gs2 convert:"},
+		{"convert syntax", "", []string{"convert", "-to", "gs2", file("syntax.gs2")}, exitSyntax, "", "gs2 convert:"},
+		{"convert without format", "", []string{"convert", file("ok.gs2")}, exitUsage, "", "Usage: gs2 convert"},
+
+		{"fmt", "", []string{"fmt", file("ok.gs2")}, exitOK, "#Stop=2020-03-28.00:00:00\n", ""},
+		{"fmt list", "", []string{"fmt", "-l", file("ok.gs2")}, exitOK, file("ok.gs2"), ""},
+		{"fmt syntax", "", []string{"fmt", file("syntax.gs2")}, exitSyntax, "", "without value"},
+
+		{"inspect", "", []string{"inspect", file("ok.gs2")}, exitOK, "Time-series          5", ""},
+		{"inspect syntax", "", []string{"inspect", file("syntax.gs2")}, exitSyntax, "", "gs2 inspect:"},
+
+		{"query", "", []string{"query", "-ref", "meterpoint2", file("ok.gs2")}, exitOK, "#Reference=meterpoint2\n", ""},
+		{"query values", "", []string{"query", "-values", "-ref", "meterpoint2", file("ok.gs2")}, exitOK, "Time-series,meterpoint2,", ""},
+		{"query bad time", "", []string{"query", "-from", "bogus", file("ok.gs2")}, exitUsage, "", `unable to parse time "bogus"`},
+		{"query syntax", "", []string{"query", file("syntax.gs2")}, exitSyntax, "", "gs2 query:"},
+
+		{"repair", "", []string{"repair", file("invalid.gs2")}, exitOK, "#Sum=27\n", "Sum 28 -> 27"},
+		{"repair change log", "", []string{"repair", "-n", file("invalid.gs2")}, exitOK, "Sum 28 -> 27", ""},
+		{"repair ambiguous", "", []string{"repair", "-n", file("ambiguous.gs2")}, exitInvalid, "not repaired Time-series 0", ""},
+		{"repair syntax", "", []string{"repair", file("syntax.gs2")}, exitSyntax, "", "gs2 repair:"},
+
+		{"generate", "", []string{"generate", "-seed", "1"}, exitOK, "##Start-message\n", ""},
+		{"generate file", "", []string{"generate", file("ok.gs2")}, exitUsage, "", "Usage: gs2 generate"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, stdout, stderr := runTest(test.stdin, test.args...)
+			if code != test.code {
+				t.Errorf("expected exit code %d, but got %d\nstdout: %s\nstderr: %s", test.code, code, stdout, stderr)
+			}
+			if !strings.Contains(stdout, test.stdout) {
+				t.Errorf("expected %q in stdout, but got %q", test.stdout, stdout)
+			}
+			if !strings.Contains(stderr, test.stderr) {
+				t.Errorf("expected %q in stderr, but got %q", test.stderr, stderr)
+			}
+		})
+	}
+}
+
+func TestRun_JSON(t *testing.T) {
+	dir := testFiles(t)
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		args   []string
+		code   int
+		stdout bool // Whether the output is expected on stdout. Errors are written to stderr.
+	}{
+		{[]string{"validate", "-json", file("ok.gs2")}, exitOK, true},
+		{[]string{"validate", "-json", file("warning.gs2")}, exitWarning, true},
+		{[]string{"validate", "-json", file("invalid.gs2")}, exitInvalid, true},
+		{[]string{"validate", "-json", file("syntax.gs2")}, exitSyntax, true},
+		{[]string{"convert", "-json", file("ok.gs2")}, exitOK, true},
+		{[]string{"convert", "-json", file("syntax.gs2")}, exitSyntax, false},
+		{[]string{"fmt", "-json", file("syntax.gs2")}, exitSyntax, true},
+		{[]string{"inspect", "-json", file("ok.gs2")}, exitOK, true},
+		{[]string{"inspect", "-json", file("missing.gs2")}, exitFailure, false},
+		{[]string{"query", "-json", "-values", file("ok.gs2")}, exitOK, true},
+		{[]string{"query", "-json", "-from", "bogus", file("ok.gs2")}, exitUsage, false},
+		{[]string{"repair", "-json", "-n", file("invalid.gs2")}, exitOK, true},
+		{[]string{"repair", "-json", "-n", file("ambiguous.gs2")}, exitInvalid, true},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Join(test.args[:2], " ")+" "+filepath.Base(test.args[len(test.args)-1]), func(t *testing.T) {
+			code, stdout, stderr := runTest("", test.args...)
+			if code != test.code {
+				t.Errorf("expected exit code %d, but got %d\nstderr: %s", test.code, code, stderr)
+			}
+
+			if test.stdout {
+				if !json.Valid([]byte(stdout)) {
+					t.Errorf("expected JSON on stdout, but got %q", stdout)
+				}
+				return
+			}
+
+			var failure struct {
+				Error string `json:"error"`
+				Code  int    `json:"code"`
+			}
+			if err := json.Unmarshal([]byte(stderr), &failure); err != nil || failure.Error == "" || failure.Code != test.code {
+				t.Errorf("expected a JSON error with code %d on stderr, but got %q (%v)", test.code, stderr, err)
+			}
+		})
+	}
+}
+
+func TestRun_Output(t *testing.T) {
+	dir := testFiles(t)
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	for _, test := range []struct {
+		args   []string
+		stdout string // Expected on stdout when the output goes to a file.
+	}{
+		{[]string{"validate", file("invalid.gs2")}, ""},
+		{[]string{"convert", "-to", "csv", file("ok.gs2")}, ""},
+		{[]string{"fmt", file("ok.gs2")}, ""},
+		{[]string{"inspect", "-json", file("ok.gs2")}, ""},
+		{[]string{"query", "-ref", "meterpoint2", file("ok.gs2")}, ""},
+		{[]string{"repair", file("invalid.gs2")}, file("invalid.gs2") + ": repaired Time-series 0 (meterpoint1): Sum 28 -> 27\n"},
+	} {
+		command := test.args[0]
+		t.Run(command, func(t *testing.T) {
+			expectedCode, expected, _ := runTest("", test.args...)
+
+			for _, name := range []string{"out", "out.gz"} {
+				output := filepath.Join(t.TempDir(), name)
+				code, stdout, stderr := runTest("", append([]string{command, "-o", output}, test.args[1:]...)...)
+				if code != expectedCode || stdout != test.stdout {
+					t.Fatalf("%s: expected exit code %d and %q on stdout, but got %d and %q\nstderr: %s", name, expectedCode,
+						test.stdout, code, stdout, stderr)
+				}
+
+				if got := readOutput(t, output); got != expected {
+					t.Errorf("%s: expected the output\n%s\nbut got\n%s", name, expected, got)
+				}
+			}
+		})
+	}
+}
+
+// readOutput returns the content of the named file, decompressed if its name ends in .gz.
+func readOutput(t *testing.T, name string) string {
+	t.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(name, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
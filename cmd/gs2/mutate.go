@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/3lvia/gs2"
+)
+
+func init() {
+	register(&command{
+		name:  "mutate",
+		args:  "[file]",
+		short: "Write defective variants of a valid GS2 file, each labelled with its defect and the rule expected to catch it.",
+		run:   runMutate,
+	})
+}
+
+// mutation is a variant written by mutate.
+type mutation struct {
+	gs2.Variant
+	File   string `json:"file"`
+	Caught *bool  `json:"caught,omitempty"`
+}
+
+func runMutate(e *env, args []string) int {
+	c := commands["mutate"]
+
+	var f commonFlags
+	fs := newFlagSet(e, c, &f)
+	dir := fs.String("dir", ".", "write the variants to `directory`")
+	check := fs.Bool("check", false, "decode and validate each variant, and fail if its defect isn't caught by its rule")
+	fs.Lookup("json").Usage = "write the list of variants as JSON"
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	name, err := inputArg(fs)
+	if err != nil {
+		fs.Usage()
+		return exitUsage
+	}
+
+	in, inName, err := openInput(e, name)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer in.Close()
+
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	variants, err := gs2.Mutate(data)
+	var syntaxErr *gs2.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return f.fail(e, c.name, exitSyntax, err)
+	case err != nil:
+		return f.fail(e, c.name, exitInvalid, err)
+	}
+
+	base := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(inName), ".gz"), ".gs2")
+	if name == "" || name == "-" {
+		base = "stdin"
+	}
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	var mutations []mutation
+	missed := 0
+	for _, v := range variants {
+		m := mutation{Variant: v, File: filepath.Join(*dir, base+"."+string(v.Defect)+".gs2")}
+		if err := ioutil.WriteFile(m.File, v.Data, 0644); err != nil {
+			return f.fail(e, c.name, exitFailure, err)
+		}
+
+		if *check {
+			caught, err := v.Caught()
+			if err != nil {
+				return f.fail(e, c.name, exitFailure, fmt.Errorf("%s: %w", m.File, err))
+			}
+			if !caught {
+				missed++
+			}
+			m.Caught = &caught
+		}
+		mutations = append(mutations, m)
+	}
+
+	out, err := openOutput(e, f.output)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer out.Close()
+
+	if f.json {
+		err = writeJSON(out, mutations)
+	} else {
+		err = writeMutations(out, mutations)
+	}
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	if missed > 0 {
+		return f.fail(e, c.name, exitFailure, fmt.Errorf("%d of %d variants not caught by their rule", missed, len(mutations)))
+	}
+
+	return exitOK
+}
+
+func writeMutations(w io.Writer, mutations []mutation) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "File\tDefect\tRule\tSeverity\tDescription\n")
+	for _, m := range mutations {
+		rule := m.Rule
+		if m.Disabled {
+			rule += " (strict)"
+		}
+		description := m.Description
+		if m.Caught != nil && !*m.Caught {
+			description += " (not caught)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", m.File, m.Defect, rule, m.Severity, description)
+	}
+
+	return tw.Flush()
+}
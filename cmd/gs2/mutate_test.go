@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestRun_Mutate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "variants", "timeseries")
+
+	code, stdout, stderr := runTest("", "mutate", "-check", "-json", "-dir", dir, "../../testdata/timeseries.gs2")
+	if code != exitOK {
+		t.Fatalf("expected exit code %d, but got %d: %s", exitOK, code, stderr)
+	}
+
+	var mutations []mutation
+	if err := json.Unmarshal([]byte(stdout), &mutations); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected []string
+	for _, m := range mutations {
+		if filepath.Dir(m.File) != dir || m.Caught == nil || !*m.Caught {
+			t.Errorf("expected %s to be written to %s and caught, but got %+v", m.Defect, dir, m)
+		}
+		expected = append(expected, filepath.Base(m.File))
+	}
+	if len(expected) == 0 {
+		t.Fatal("expected variants")
+	}
+	sort.Strings(expected)
+	expectNames(t, dir, expected...)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/3lvia/gs2"
+)
+
+func init() {
+	register(&command{
+		name:  "query",
+		args:  "[file]",
+		short: "Select meter readings and time series by meter, reference and time window.",
+		run:   runQuery,
+	})
+}
+
+// queryTimeLayouts are the accepted layouts for -from and -to. Layouts without a time zone are in the local time of the file.
+var queryTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02.15:04:05",
+	"2006-01-02",
+}
+
+func runQuery(e *env, args []string) int {
+	c := commands["query"]
+
+	var f commonFlags
+	fs := newFlagSet(e, c, &f)
+	references := fs.String("ref", "", "comma separated `list` of references")
+	meters := fs.String("meter", "", "comma separated `list` of meters")
+	channels := fs.String("channel", "", "comma separated `list` of channels")
+	installations := fs.String("installation", "", "comma separated `list` of installations")
+	units := fs.String("unit", "", "comma separated `list` of units")
+	direction := fs.String("direction", "", "direction of flow")
+	from := fs.String("from", "", "start of time window. RFC 3339 or a `time` in the local time of the file, e.g. 2020-03-27")
+	to := fs.String("to", "", "exclusive end of time window, in the same formats as -from")
+	values := fs.Bool("values", false, "write a flat listing of the values as CSV, or JSON with -json, instead of GS2")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	name, err := inputArg(fs)
+	if err != nil {
+		fs.Usage()
+		return exitUsage
+	}
+
+	in, _, err := openInput(e, name)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer in.Close()
+
+	g, err := decode(in)
+	if err != nil {
+		return f.fail(e, c.name, exitSyntax, err)
+	}
+
+	filter := gs2.Filter{
+		References:      splitList(*references),
+		Meters:          splitList(*meters),
+		Channels:        splitList(*channels),
+		Installations:   splitList(*installations),
+		Units:           splitList(*units),
+		DirectionOfFlow: gs2.ParseDirectionOfFlow(*direction),
+	}
+
+	loc := g.StartMessage.Location()
+	if filter.From, err = parseQueryTime(*from, loc); err != nil {
+		return f.fail(e, c.name, exitUsage, err)
+	}
+	if filter.To, err = parseQueryTime(*to, loc); err != nil {
+		return f.fail(e, c.name, exitUsage, err)
+	}
+
+	result := g.Filter(filter)
+
+	out, err := openOutput(e, f.output)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer out.Close()
+
+	switch {
+	case *values && f.json:
+		err = writeJSON(out, valueRows(result))
+	case *values:
+		err = writeCSV(out, result)
+	case f.json:
+		err = writeJSON(out, result)
+	default:
+		err = convert(out, result, "gs2")
+	}
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	return exitOK
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	list := strings.Split(s, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+
+	return list
+}
+
+func parseQueryTime(s string, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range queryTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unable to parse time %q", s)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/3lvia/gs2"
+)
+
+func init() {
+	register(&command{
+		name:  "repair",
+		args:  "[file]",
+		short: "Recompute Sum, No-of-values, a missing Stop and Number-of-objects from the content of a GS2 file.",
+		run:   runRepair,
+	})
+}
+
+type repairChange struct {
+	Object    string `json:"object"`
+	Index     int    `json:"index"`
+	Reference string `json:"reference,omitempty"`
+	Attribute string `json:"attribute"`
+	Old       string `json:"old"`
+	New       string `json:"new"`
+}
+
+type repairReport struct {
+	File       string         `json:"file"`
+	Changes    []repairChange `json:"changes"`
+	Unresolved []string       `json:"unresolved,omitempty"`
+}
+
+func runRepair(e *env, args []string) int {
+	c := commands["repair"]
+
+	var f commonFlags
+	fs := newFlagSet(e, c, &f)
+	write := fs.Bool("w", false, "write result to the source file instead of stdout, and the change log to stdout")
+	dryRun := fs.Bool("n", false, "only write the change log to stdout")
+	fs.Lookup("json").Usage = "write the change log as JSON"
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	name, err := inputArg(fs)
+	if err != nil || (*write && (name == "" || name == "-" || f.output != "")) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	path := name
+	in, name, err := openInput(e, name)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer in.Close()
+
+	src, err := ioutil.ReadAll(in)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	g, err := decode(bytes.NewReader(src))
+	if err != nil {
+		return f.fail(e, c.name, exitSyntax, err)
+	}
+
+	changes, err := gs2.Repair(g)
+	report := newRepairReport(name, changes, err)
+
+	// Only the changed values are replaced, so attributes and blocks unknown to the GS2 type are kept.
+	repaired, err := gs2.ApplyChanges(src, g, changes)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	// The change log goes to stdout unless stdout has the repaired file.
+	logOut := e.stdout
+	switch {
+	case *dryRun:
+	case *write:
+		if len(changes) > 0 {
+			if err := writeFileAtomic(path, repaired); err != nil {
+				return f.fail(e, c.name, exitFailure, err)
+			}
+		}
+	default:
+		out, err := openOutput(e, f.output)
+		if err != nil {
+			return f.fail(e, c.name, exitFailure, err)
+		}
+		defer out.Close()
+
+		if _, err := out.Write(repaired); err != nil {
+			return f.fail(e, c.name, exitFailure, err)
+		}
+		if f.output == "" || f.output == "-" {
+			logOut = e.stderr
+		}
+	}
+
+	if f.json {
+		err = writeJSON(logOut, report)
+	} else {
+		err = writeChangeLog(logOut, report)
+	}
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	if len(report.Unresolved) > 0 {
+		return exitInvalid
+	}
+
+	return exitOK
+}
+
+func newRepairReport(name string, changes []gs2.Change, err error) repairReport {
+	report := repairReport{File: name, Changes: []repairChange{}}
+	for _, change := range changes {
+		report.Changes = append(report.Changes, repairChange(change))
+	}
+
+	for _, err := range gs2.Errors(err) {
+		report.Unresolved = append(report.Unresolved, err.Error())
+	}
+
+	return report
+}
+
+func writeChangeLog(w io.Writer, report repairReport) error {
+	var buf bytes.Buffer
+	for _, change := range report.Changes {
+		fmt.Fprintf(&buf, "%s: repaired %s\n", report.File, gs2.Change(change))
+	}
+	for _, unresolved := range report.Unresolved {
+		fmt.Fprintf(&buf, "%s: not repaired %s\n", report.File, unresolved)
+	}
+
+	_, err := io.Copy(w, &buf)
+	return err
+}
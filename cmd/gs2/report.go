@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/3lvia/gs2"
+)
+
+type reporter func(w io.Writer, rules gs2.RuleSet, results []validationResult) error
+
+var reporters = map[string]reporter{
+	"text":  reportText,
+	"json":  reportJSON,
+	"junit": reportJUnit,
+	"sarif": reportSARIF,
+}
+
+func reportText(w io.Writer, _ gs2.RuleSet, results []validationResult) error {
+	for _, r := range results {
+		if len(r.Findings) == 0 {
+			if _, err := fmt.Fprintf(w, "%s: ok (%v)\n", r.File, r.Took); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, f := range r.Findings {
+			if _, err := fmt.Fprintf(w, "%s: %s: %s: %s\n", f.location(), f.Severity, f.Rule, f.describe()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// location returns the file and line of f.
+func (f finding) location() string {
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+
+	return f.File
+}
+
+// describe returns the message of f prefixed by the object it concerns.
+func (f finding) describe() string {
+	switch {
+	case f.Object == "":
+		return f.Message
+	case f.Reference == "":
+		return fmt.Sprintf("%s %d: %s", f.Object, *f.Index, f.Message)
+	default:
+		return fmt.Sprintf("%s %d (%s): %s", f.Object, *f.Index, f.Reference, f.Message)
+	}
+}
+
+func reportJSON(w io.Writer, _ gs2.RuleSet, results []validationResult) error {
+	return writeJSON(w, results)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// reportJUnit writes a test suite per file with a test case per rule. Syntax errors are reported as errors, validation
+// errors as failures and warnings as output of an otherwise passing test case.
+func reportJUnit(w io.Writer, _ gs2.RuleSet, results []validationResult) error {
+	var suites junitTestSuites
+
+	for _, r := range results {
+		suite := junitTestSuite{Name: r.File, Time: fmt.Sprintf("%.3f", r.Took.Seconds())}
+
+		byRule := make(map[string][]finding)
+		for _, f := range r.Findings {
+			byRule[f.Rule] = append(byRule[f.Rule], f)
+		}
+
+		ruleIDs := r.Rules
+		for _, id := range []string{"io", syntaxRule.ID, limitRule.ID} {
+			if _, exists := byRule[id]; exists {
+				ruleIDs = []string{id}
+			}
+		}
+
+		for _, id := range ruleIDs {
+			tc := junitTestCase{Name: id, Classname: r.File}
+
+			var errorLines, warningLines []string
+			for _, f := range byRule[id] {
+				line := f.location() + ": " + f.describe()
+
+				if f.Severity == severityError {
+					errorLines = append(errorLines, line)
+				} else {
+					warningLines = append(warningLines, line)
+				}
+			}
+
+			if len(errorLines) > 0 {
+				msg := &junitMessage{Message: byRule[id][0].Message, Type: id, Text: strings.Join(errorLines, "\n")}
+				if id == syntaxRule.ID || id == limitRule.ID || id == "io" {
+					tc.Error = msg
+					suite.Errors++
+				} else {
+					tc.Failure = msg
+					suite.Failures++
+				}
+			}
+			tc.SystemOut = strings.Join(warningLines, "\n")
+
+			suite.Cases = append(suite.Cases, tc)
+			suite.Tests++
+		}
+
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Enabled bool   `json:"enabled"`
+		Level   string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation struct {
+		URI string `json:"uri"`
+	} `json:"artifactLocation"`
+	Region *sarifRegion `json:"region,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName,omitempty"`
+	Kind               string `json:"kind"`
+}
+
+func reportSARIF(w io.Writer, rules gs2.RuleSet, results []validationResult) error {
+	run := sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: "gs2"}}, Results: []sarifResult{}}
+
+	for _, rule := range append(gs2.RuleSet{syntaxRule, limitRule}, rules...) {
+		sr := sarifRule{ID: rule.ID, ShortDescription: sarifMessage{rule.Description}}
+		sr.DefaultConfiguration.Enabled = !rule.Disabled
+		sr.DefaultConfiguration.Level = sarifLevel(rule.Severity.String())
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sr)
+	}
+
+	for _, r := range results {
+		for _, f := range r.Findings {
+			var location sarifLocation
+			location.PhysicalLocation.ArtifactLocation.URI = f.File
+			if f.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: f.Line}
+			}
+			if f.Object != "" {
+				location.LogicalLocations = []sarifLogicalLocation{{
+					Name:               fmt.Sprintf("%s[%d]", f.Object, *f.Index),
+					FullyQualifiedName: f.Reference,
+					Kind:               "object",
+				}}
+			}
+
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    f.Rule,
+				Level:     sarifLevel(f.Severity),
+				Message:   sarifMessage{f.Message},
+				Locations: []sarifLocation{location},
+			})
+		}
+	}
+
+	return writeJSON(w, sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifLevel returns the SARIF level of severity.
+func sarifLevel(severity string) string {
+	if severity == gs2.SeverityInfo.String() {
+		return "note"
+	}
+
+	return severity
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/3lvia/gs2"
+)
+
+func init() {
+	register(&command{
+		name:  "schema",
+		args:  "[block ...]",
+		short: "Show the attributes of GS2 blocks, their types, allowed values and whether they are mandatory.",
+		run:   runSchema,
+	})
+}
+
+func runSchema(e *env, args []string) int {
+	c := commands["schema"]
+
+	var f commonFlags
+	fs := newFlagSet(e, c, &f)
+	messageType := fs.String("message-type", gs2.MessageTypeSettlementData, "show mandatory attributes for `type`")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	schemas := gs2.Schemas()
+	if fs.NArg() > 0 {
+		schemas = nil
+		for _, name := range fs.Args() {
+			schema, ok := gs2.LookupSchema(name)
+			if !ok {
+				return f.fail(e, c.name, exitUsage, fmt.Errorf("unknown block %q", name))
+			}
+			schemas = append(schemas, schema)
+		}
+	}
+
+	out, err := openOutput(e, f.output)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer out.Close()
+
+	if f.json {
+		err = writeJSON(out, schemas)
+	} else {
+		err = writeSchemas(out, schemas, *messageType)
+	}
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	return exitOK
+}
+
+func writeSchemas(w io.Writer, schemas []gs2.BlockSchema, messageType string) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	for i, schema := range schemas {
+		if i > 0 {
+			fmt.Fprintf(tw, "\n")
+		}
+		fmt.Fprintf(tw, "%s\n", schema.Name)
+		for _, a := range schema.Attributes {
+			presence := "optional"
+			if a.Required(messageType) {
+				presence = "mandatory"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", a.Name, a.Type, presence, strings.Join(a.Values, "|"), a.Description)
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/3lvia/gs2"
+)
+
+func init() {
+	register(&command{
+		name:  "serve",
+		args:  "",
+		short: "Serve validation and conversion over HTTP.",
+		run:   runServe,
+	})
+}
+
+const defaultMaxRequestBytes = 32 << 20
+
+// contentTypes maps conversion formats to the content type of the response.
+var contentTypes = map[string]string{
+	"gs2":  "text/plain; charset=utf-8",
+	"json": "application/json",
+	"csv":  "text/csv; charset=utf-8",
+}
+
+type server struct {
+	maxBytes int64
+	timeout  time.Duration
+	rules    validationFlags
+	config   *validationConfig
+	log      *requestLogger
+}
+
+func runServe(e *env, args []string) int {
+	c := commands["serve"]
+
+	var f commonFlags
+	fs := newFlagSet(e, c, &f)
+	addr := fs.String("addr", "localhost:8080", "`address` to listen on")
+	rf := validationFlags{limits: gs2.Limits{MaxBytes: defaultMaxRequestBytes}}
+	rf.add(fs)
+	fs.Lookup("max-bytes").Usage = "maximum size of a request body in `bytes`"
+	timeout := fs.Duration("timeout", 30*time.Second, "stop decoding and validating a request after `duration`")
+	fs.Lookup("o").Usage = "write request log to `file` instead of stderr"
+	fs.Lookup("json").Usage = "write request log as JSON"
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() != 0 || rf.limits.MaxBytes <= 0 || *timeout <= 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	config, err := rf.config()
+	if err != nil {
+		return f.fail(e, c.name, exitUsage, err)
+	}
+	if _, err := newValidation(rf.ids, rf.strict, config); err != nil {
+		return f.fail(e, c.name, exitUsage, err)
+	}
+
+	logOut := io.WriteCloser(nopWriteCloser{e.stderr})
+	if f.output != "" {
+		if logOut, err = openOutput(e, f.output); err != nil {
+			return f.fail(e, c.name, exitFailure, err)
+		}
+	}
+	defer logOut.Close()
+
+	s := &server{maxBytes: int64(rf.limits.MaxBytes), timeout: *timeout, rules: rf, config: config, log: &requestLogger{w: logOut, json: f.json}}
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute,
+		WriteTimeout:      time.Minute,
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+
+	fmt.Fprintf(e.stderr, "gs2 %s: listening on %s\n", c.name, *addr)
+
+	select {
+	case err := <-errs:
+		return f.fail(e, c.name, exitFailure, err)
+	case <-stop:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	return exitOK
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/validate", s.post(s.handleValidate))
+	mux.HandleFunc("/convert", s.post(s.handleConvert))
+	mux.HandleFunc("/encode", s.post(s.handleEncode))
+
+	return s.log.wrap(mux)
+}
+
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, "json", func(w io.Writer) error {
+		return writeJSON(w, map[string]string{"status": "ok"})
+	})
+}
+
+// handleValidate responds with a validation report for the GS2 body. The status is 200 if the body is valid, and 422 if
+// it can't be decoded or fails validation.
+func (s *server) handleValidate(w http.ResponseWriter, r *http.Request, body []byte) {
+	ids := s.rules.ids
+	if rules := r.URL.Query().Get("rules"); rules != "" {
+		ids = rules
+	}
+
+	v, err := newValidation(ids, s.rules.strict || r.URL.Query().Get("strict") == "true", s.config)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, code := validateReader(r.Context(), "body", bytes.NewReader(body), v)
+
+	status := http.StatusOK
+	switch code {
+	case exitSyntax, exitInvalid:
+		status = http.StatusUnprocessableEntity
+	case exitFailure:
+		// Reading the body can't fail, so the request timed out or was cancelled.
+		status = http.StatusServiceUnavailable
+	}
+
+	writeResponse(w, status, "json", func(w io.Writer) error {
+		return writeJSON(w, result)
+	})
+}
+
+// handleConvert converts the GS2 body to the format given by the "to" query parameter.
+func (s *server) handleConvert(w http.ResponseWriter, r *http.Request, body []byte) {
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = "json"
+	}
+	if !validFormat(to) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported format %q", to))
+		return
+	}
+
+	g, err := decodeContext(r.Context(), bytes.NewReader(body), s.config.decoderOptions()...)
+	if err != nil {
+		writeError(w, errorStatus(r, http.StatusUnprocessableEntity), err)
+		return
+	}
+
+	writeResponse(w, http.StatusOK, to, func(w io.Writer) error {
+		return convert(w, g, to)
+	})
+}
+
+// handleEncode encodes the JSON body as GS2.
+func (s *server) handleEncode(w http.ResponseWriter, r *http.Request, body []byte) {
+	g, err := readJSON(bytes.NewReader(body))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := gs2.NewEncoder(&buf, gs2.EncodeCanonical()).EncodeContext(r.Context(), g); err != nil {
+		writeError(w, errorStatus(r, http.StatusUnprocessableEntity), err)
+		return
+	}
+
+	writeResponse(w, http.StatusOK, "gs2", func(w io.Writer) error {
+		_, err := w.Write(buf.Bytes())
+		return err
+	})
+}
+
+// post only accepts POST requests and reads the body, rejecting bodies larger than the limit of the server. The context
+// of the request handed to h is cancelled after the timeout of the server.
+func (s *server) post(h func(http.ResponseWriter, *http.Request, []byte)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+
+		if r.ContentLength > s.maxBytes {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body larger than %d bytes", s.maxBytes))
+			return
+		}
+
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, s.maxBytes+1))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if int64(len(body)) > s.maxBytes {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body larger than %d bytes", s.maxBytes))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+		defer cancel()
+
+		h(w, r.WithContext(ctx), body)
+	}
+}
+
+// errorStatus returns 503 if the request timed out or was cancelled, and status otherwise.
+func errorStatus(r *http.Request, status int) int {
+	if r.Context().Err() != nil {
+		return http.StatusServiceUnavailable
+	}
+
+	return status
+}
+
+// writeResponse buffers the response written by write, so errors can still be reported with a proper status.
+func writeResponse(w http.ResponseWriter, status int, format string, write func(io.Writer) error) {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	var buf bytes.Buffer
+	writeJSON(&buf, struct {
+		Error  string `json:"error"`
+		Status int    `json:"status"`
+	}{err.Error(), status})
+
+	w.Header().Set("Content-Type", contentTypes["json"])
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+type requestLogger struct {
+	w    io.Writer
+	json bool
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (l *requestLogger) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		h.ServeHTTP(rec, r)
+
+		took := time.Since(start)
+		if l.json {
+			writeJSONLine(l.w, struct {
+				Time   time.Time     `json:"time"`
+				Method string        `json:"method"`
+				Path   string        `json:"path"`
+				Status int           `json:"status"`
+				Took   time.Duration `json:"took"`
+			}{start, r.Method, r.URL.Path, rec.status, took})
+		} else {
+			fmt.Fprintf(l.w, "%s %s %s %d %v\n", start.Format(time.RFC3339), r.Method, r.URL.Path, rec.status, took)
+		}
+	})
+}
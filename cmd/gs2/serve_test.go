@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer returns a server with the default rules, a limit of maxBytes on request bodies and the given timeout.
+func newTestServer(t *testing.T, maxBytes int64, timeout time.Duration) http.Handler {
+	t.Helper()
+
+	var rf validationFlags
+	config, err := rf.config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{maxBytes: maxBytes, timeout: timeout, rules: rf, config: config, log: &requestLogger{w: io.Discard}}
+	return s.routes()
+}
+
+func TestServer(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/timeseries.gs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := string(data)
+	invalid := strings.Replace(ok, "#Sum=27", "#Sum=28", 1)
+	syntax := "##Start-message\n#Id 1\n"
+
+	code, encoded, _ := runTest("", "convert", "-json", "../../testdata/timeseries.gs2")
+	if code != exitOK {
+		t.Fatalf("unable to convert the test file to JSON: %d", code)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		body        string
+		status      int
+		contentType string
+		response    string // Expected in the response body.
+	}{
+		{"health", http.MethodGet, "/health", "", http.StatusOK, "application/json", `"status": "ok"`},
+
+		{"validate", http.MethodPost, "/validate", ok, http.StatusOK, "application/json", `"valid": true`},
+		{"validate invalid", http.MethodPost, "/validate", invalid, http.StatusUnprocessableEntity, "application/json", `"rule": "time-series-values"`},
+		{"validate syntax", http.MethodPost, "/validate", syntax, http.StatusUnprocessableEntity, "application/json", `"rule": "syntax"`},
+		{"validate json body", http.MethodPost, "/validate", encoded, http.StatusUnprocessableEntity, "application/json", `"valid": false`},
+		{"validate rules", http.MethodPost, "/validate?rules=no-of-objects", invalid, http.StatusOK, "application/json", `"valid": true`},
+		{"validate unknown rule", http.MethodPost, "/validate?rules=bogus", ok, http.StatusBadRequest, "application/json", `"status": 400`},
+		{"validate get", http.MethodGet, "/validate", "", http.StatusMethodNotAllowed, "application/json", "method GET not allowed"},
+
+		{"convert", http.MethodPost, "/convert", ok, http.StatusOK, "application/json", `"Reference": "meterpoint1"`},
+		{"convert csv", http.MethodPost, "/convert?to=csv", ok, http.StatusOK, "text/csv; charset=utf-8", "Time-series,meterpoint1,"},
+		{"convert gs2", http.MethodPost, "/convert?to=gs2", ok, http.StatusOK, "text/plain; charset=utf-8", "#Reference=meterpoint1\n"},
+		{"convert unknown format", http.MethodPost, "/convert?to=xml", ok, http.StatusBadRequest, "application/json", `unsupported format \"xml\"`},
+		{"convert syntax", http.MethodPost, "/convert", syntax, http.StatusUnprocessableEntity, "application/json", `"status": 422`},
+
+		{"encode", http.MethodPost, "/encode", encoded, http.StatusOK, "text/plain; charset=utf-8", "#Reference=meterpoint1\n"},
+		{"encode gs2 body", http.MethodPost, "/encode", ok, http.StatusBadRequest, "application/json", `"status": 400`},
+		{"encode invalid", http.MethodPost, "/encode", strings.Replace(encoded, `"Sum": 27`, `"Sum": 28`, 1),
+			http.StatusUnprocessableEntity, "application/json", `"status": 422`},
+		{"encode get", http.MethodGet, "/encode", "", http.StatusMethodNotAllowed, "application/json", `"status": 405`},
+	}
+
+	handler := newTestServer(t, defaultMaxRequestBytes, time.Minute)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(test.method, test.target, strings.NewReader(test.body)))
+
+			if w.Code != test.status {
+				t.Errorf("expected status %d, but got %d: %s", test.status, w.Code, w.Body)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != test.contentType {
+				t.Errorf("expected content type %q, but got %q", test.contentType, contentType)
+			}
+			if !strings.Contains(w.Body.String(), test.response) {
+				t.Errorf("expected %q in the response, but got %s", test.response, w.Body)
+			}
+			if test.contentType == "application/json" && !json.Valid(w.Body.Bytes()) {
+				t.Errorf("expected a JSON response, but got %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestServer_MaxBytes(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/timeseries.gs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/validate", "/convert", "/encode"} {
+		for _, maxBytes := range []int64{int64(len(data)) - 1, int64(len(data))} {
+			for _, known := range []bool{true, false} {
+				handler := newTestServer(t, maxBytes, time.Minute)
+
+				r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(string(data)))
+				if !known {
+					// The body is streamed without a Content-Length, so the limit is found while reading it.
+					r.ContentLength = -1
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+
+				tooLarge := w.Code == http.StatusRequestEntityTooLarge
+				if expected := maxBytes < int64(len(data)); tooLarge != expected {
+					t.Errorf("%s with limit %d and known length %v: expected too large %v, but got status %d", path, maxBytes,
+						known, expected, w.Code)
+				}
+			}
+		}
+	}
+}
+
+func TestServer_Cancelled(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/timeseries.gs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, encoded, _ := runTest("", "convert", "-json", "../../testdata/timeseries.gs2")
+	if code != exitOK {
+		t.Fatalf("unable to convert the test file to JSON: %d", code)
+	}
+
+	for path, body := range map[string]string{"/validate": string(data), "/convert": string(data), "/encode": encoded} {
+		// The request is cancelled by the client.
+		handler := newTestServer(t, defaultMaxRequestBytes, time.Minute)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)).WithContext(ctx))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s cancelled: expected status %d, but got %d: %s", path, http.StatusServiceUnavailable, w.Code, w.Body)
+		}
+
+		// The request times out in the server.
+		handler = newTestServer(t, defaultMaxRequestBytes, time.Nanosecond)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s timed out: expected status %d, but got %d: %s", path, http.StatusServiceUnavailable, w.Code, w.Body)
+		}
+	}
+}
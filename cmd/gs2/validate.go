@@ -0,0 +1,484 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/3lvia/gs2"
+)
+
+func init() {
+	register(&command{
+		name:  "validate",
+		args:  "[file ...]",
+		short: "Decode and validate GS2 files.",
+		run:   runValidate,
+	})
+}
+
+// syntaxRule is the rule reported when a file can't be decoded.
+var syntaxRule = gs2.Rule{ID: "syntax", Severity: gs2.SeverityError, Description: "The file can be decoded"}
+
+// limitRule is the rule reported when a file exceeds the decoding limits.
+var limitRule = gs2.Rule{ID: "limits", Severity: gs2.SeverityError, Description: "The file is within the decoding limits"}
+
+// severityError is the severity of findings that make a file invalid.
+var severityError = gs2.SeverityError.String()
+
+type finding struct {
+	File      string `json:"file"`
+	Line      int    `json:"line,omitempty"`
+	Rule      string `json:"rule"`
+	Severity  string `json:"severity"`
+	Object    string `json:"object,omitempty"`
+	Index     *int   `json:"index,omitempty"`
+	Reference string `json:"reference,omitempty"`
+	Values    []int  `json:"values,omitempty"`
+	Message   string `json:"message"`
+}
+
+type validationResult struct {
+	File     string        `json:"file"`
+	Valid    bool          `json:"valid"`
+	Took     time.Duration `json:"took"`
+	Rules    []string      `json:"rules"`
+	Findings []finding     `json:"findings,omitempty"`
+}
+
+func runValidate(e *env, args []string) int {
+	c := commands["validate"]
+
+	var f commonFlags
+	fs := newFlagSet(e, c, &f)
+	format := fs.String("format", "text", "output `format`: text, json, junit or sarif")
+	var rf validationFlags
+	rf.add(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if f.json {
+		*format = "json"
+	}
+
+	report, exists := reporters[*format]
+	if !exists {
+		fs.Usage()
+		return exitUsage
+	}
+
+	v, err := rf.validation()
+	if err != nil {
+		return f.fail(e, c.name, exitUsage, err)
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	out, err := openOutput(e, f.output)
+	if err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+	defer out.Close()
+
+	var results []validationResult
+	code := exitOK
+	for _, file := range files {
+		fileResults, fileCode := validateFile(e, file, v)
+		results = append(results, fileResults...)
+		code = worstExitCode(code, fileCode)
+	}
+
+	if err := report(out, v.rules, results); err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	return code
+}
+
+// validationFlags are the flags selecting the rules to run and how files are decoded, shared by the commands validating
+// files. Limits set before add are the defaults of their flags.
+type validationFlags struct {
+	ids       string
+	strict    bool
+	qualities string
+	registry  string
+	profile   string
+	limits    gs2.Limits
+	workers   int
+}
+
+func (rf *validationFlags) add(fs *flag.FlagSet) {
+	fs.StringVar(&rf.ids, "rules", "", "comma separated `list` of rules to run. Defaults to all rules except strict rules")
+	fs.BoolVar(&rf.strict, "strict", false, "also run strict rules")
+	fs.StringVar(&rf.qualities, "qualities", "", "check quality codes against the quality configuration in `file`")
+	fs.StringVar(&rf.registry, "registry", "", "check metering points against the registry in `file`, CSV or JSON")
+	fs.StringVar(&rf.profile, "profile", "", "change rules per sender with the rule profiles in `file`")
+	fs.IntVar(&rf.limits.MaxBytes, "max-bytes", rf.limits.MaxBytes, "maximum size of a file in `bytes`. 0 means no limit")
+	fs.IntVar(&rf.limits.MaxObjects, "max-objects", rf.limits.MaxObjects, "maximum `number` of blocks in a file. 0 means no limit")
+	fs.IntVar(&rf.limits.MaxValues, "max-values", rf.limits.MaxValues, "maximum `number` of values in a time series. 0 means no limit")
+	fs.IntVar(&rf.limits.MaxNameLength, "max-name-length", rf.limits.MaxNameLength, "maximum `length` of block and attribute names. 0 means no limit")
+	fs.IntVar(&rf.limits.MaxValueLength, "max-value-length", rf.limits.MaxValueLength, "maximum `length` of attribute values. 0 means no limit")
+	fs.IntVar(&rf.workers, "parallel", 1, "decode large files on `n` goroutines")
+}
+
+// config loads the configuration files named by the flags.
+func (rf *validationFlags) config() (*validationConfig, error) {
+	c := validationConfig{limits: rf.limits, workers: rf.workers}
+	var err error
+
+	if c.qualities, err = loadQualityConfig(rf.qualities); err != nil {
+		return nil, err
+	}
+	if c.registry, err = loadRegistry(rf.registry); err != nil {
+		return nil, err
+	}
+	if c.profile, err = loadRuleConfig(rf.profile); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (rf *validationFlags) validation() (*validation, error) {
+	c, err := rf.config()
+	if err != nil {
+		return nil, err
+	}
+
+	return newValidation(rf.ids, rf.strict, c)
+}
+
+// validationConfig holds the configuration given to the rules.
+type validationConfig struct {
+	qualities *gs2.QualityConfig
+	registry  gs2.Registry
+	profile   *gs2.RuleConfig
+	limits    gs2.Limits
+	workers   int
+}
+
+// decoderOptions returns the options for decoding files with the limits, on the goroutines of c.
+func (c *validationConfig) decoderOptions() []gs2.DecoderOption {
+	opts := []gs2.DecoderOption{gs2.DecodeLimits(c.limits)}
+	if c.workers > 1 {
+		opts = append(opts, gs2.DecodeParallel(c.workers))
+	}
+
+	return opts
+}
+
+// rules returns the default rules, where the qualities rule checks against the quality configuration and is enabled, and
+// with the metering-points rule if there is a registry.
+func (c *validationConfig) rules() gs2.RuleSet {
+	rules := gs2.DefaultRules()
+
+	if c.qualities != nil {
+		rule, _ := rules.Lookup("qualities")
+		rule.Validator = gs2.ValidateQualitiesWith(c.qualities)
+		rule.Disabled = false
+		rules = rules.With(rule)
+	}
+
+	if c.registry != nil {
+		rules = rules.With(gs2.Rule{
+			ID:               "metering-points",
+			Severity:         gs2.SeverityError,
+			Description:      "Metering points are registered, active in the period and match the registry",
+			ContextValidator: gs2.ValidateRegistryContext(c.registry),
+		})
+	}
+
+	return rules
+}
+
+// validation holds the rules to run, the profiles changing them for the sender of each file and the options for decoding
+// files.
+type validation struct {
+	rules    gs2.RuleSet
+	profile  *gs2.RuleConfig
+	decoding []gs2.DecoderOption
+}
+
+// newValidation returns a validation running the rules of c selected by ids and strict, see selectRules. The profile of c,
+// if any, is applied for each file.
+func newValidation(ids string, strict bool, c *validationConfig) (*validation, error) {
+	rules, err := selectRules(c.rules(), ids, strict)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.profile != nil {
+		if err := c.profile.Verify(rules); err != nil {
+			return nil, err
+		}
+	}
+
+	return &validation{rules: rules, profile: c.profile, decoding: c.decoderOptions()}, nil
+}
+
+// rulesFor returns the enabled rules for files from sender.
+func (v *validation) rulesFor(sender string) gs2.RuleSet {
+	if v.profile == nil {
+		return v.rules.Enabled()
+	}
+
+	// The profile is verified against the rules, so it applies.
+	rules, _ := v.profile.Apply(v.rules, sender)
+	return rules.Enabled()
+}
+
+// selectRules returns rules with only the rules with the given IDs enabled, or as they are if ids is empty. All rules are
+// enabled if strict is true.
+func selectRules(rules gs2.RuleSet, ids string, strict bool) (gs2.RuleSet, error) {
+	if ids != "" {
+		var only []string
+		for _, id := range strings.Split(ids, ",") {
+			only = append(only, strings.TrimSpace(id))
+		}
+		return rules.Only(only...)
+	}
+
+	if strict {
+		for i := range rules {
+			rules[i].Disabled = false
+		}
+	}
+
+	return rules, nil
+}
+
+// loadQualityConfig loads the named quality configuration, or returns nil if name is empty.
+func loadQualityConfig(name string) (*gs2.QualityConfig, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	config, err := gs2.LoadQualityConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return config, nil
+}
+
+// loadRegistry loads the named registry, or returns nil if name is empty. Files ending in .csv are read as CSV, and other
+// files as JSON.
+func loadRegistry(name string) (gs2.Registry, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	load := gs2.LoadRegistryJSON
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		load = gs2.LoadRegistryCSV
+	}
+
+	registry, err := load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return registry, nil
+}
+
+// loadRuleConfig loads the named rule profiles, or returns nil if name is empty.
+func loadRuleConfig(name string) (*gs2.RuleConfig, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	config, err := gs2.LoadRuleConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return config, nil
+}
+
+// validateFile decodes and validates the named file and returns the results together with the exit code for the file.
+// Files compressed with gzip are decompressed, and each file in a zip archive gets its own result.
+func validateFile(e *env, name string, v *validation) ([]validationResult, int) {
+	in, err := openInputs(e, name)
+	if err != nil {
+		return []validationResult{ioFailure(name, v.rulesFor(""), err)}, exitFailure
+	}
+	defer in.Close()
+
+	return validateInput(context.Background(), in, v)
+}
+
+// validateInput decodes and validates the files in the input, and returns their results together with the worst exit code.
+func validateInput(ctx context.Context, in *gs2.Input, v *validation) ([]validationResult, int) {
+	if len(in.Entries) == 0 {
+		return []validationResult{ioFailure(in.Name, v.rulesFor(""), errors.New("zip archive has no files"))}, exitFailure
+	}
+
+	var results []validationResult
+	code := exitOK
+	for _, entry := range in.Entries {
+		name := entryName(in, entry)
+		r, err := entry.Open()
+		if err != nil {
+			results = append(results, ioFailure(name, v.rulesFor(""), err))
+			code = worstExitCode(code, exitFailure)
+			continue
+		}
+
+		result, entryCode := validateReader(ctx, name, r, v)
+		r.Close()
+		results = append(results, result)
+		code = worstExitCode(code, entryCode)
+	}
+
+	return results, code
+}
+
+func newValidationResult(name string, rules gs2.RuleSet) validationResult {
+	result := validationResult{File: name, Valid: true, Rules: []string{}}
+	for _, rule := range rules {
+		result.Rules = append(result.Rules, rule.ID)
+	}
+
+	return result
+}
+
+// ioFailure returns the result for a file that can't be read.
+func ioFailure(name string, rules gs2.RuleSet, err error) validationResult {
+	result := newValidationResult(name, rules)
+	result.Valid = false
+	result.Findings = append(result.Findings, finding{File: name, Rule: "io", Severity: severityError, Message: err.Error()})
+	return result
+}
+
+// validateReader decodes and validates r and returns the result together with the exit code for the input. Findings of
+// rules with severity info don't change the exit code. If ctx is done, the input fails like an input that can't be read.
+func validateReader(ctx context.Context, name string, r io.Reader, v *validation) (validationResult, int) {
+	start := time.Now()
+
+	dec := gs2.NewDecoder(r, append([]gs2.DecoderOption{gs2.DecodeValidators()}, v.decoding...)...)
+	g, err := dec.DecodeContext(ctx)
+	if err != nil {
+		var limitErr *gs2.LimitError
+		if errors.As(err, &limitErr) {
+			result := newValidationResult(name, v.rulesFor(""))
+			result.Valid = false
+			result.Took = time.Since(start)
+			fi := finding{
+				File:     name,
+				Line:     limitErr.Line,
+				Rule:     limitRule.ID,
+				Severity: limitRule.Severity.String(),
+				Message:  fmt.Sprintf("%s exceeds limit of %d", limitErr.Limit, limitErr.Max),
+			}
+			if limitErr.Object != "" {
+				index := limitErr.Index
+				fi.Object = limitErr.Object
+				fi.Index = &index
+			}
+			if limitErr.Attribute != "" {
+				fi.Message = limitErr.Attribute + ": " + fi.Message
+			}
+			result.Findings = append(result.Findings, fi)
+			return result, exitSyntax
+		}
+
+		var syntaxErr *gs2.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			result := ioFailure(name, v.rulesFor(""), err)
+			result.Took = time.Since(start)
+			return result, exitFailure
+		}
+
+		result := newValidationResult(name, v.rulesFor(""))
+		result.Valid = false
+		result.Took = time.Since(start)
+		result.Findings = append(result.Findings, finding{
+			File:     name,
+			Line:     syntaxErr.Line,
+			Rule:     syntaxRule.ID,
+			Severity: syntaxRule.Severity.String(),
+			Message:  syntaxErr.Err.Error(),
+		})
+		return result, exitSyntax
+	}
+
+	rules := v.rulesFor(g.StartMessage.From)
+	result := newValidationResult(name, rules)
+
+	findings := rules.CheckContext(ctx, g)
+	if err := ctx.Err(); err != nil {
+		result := ioFailure(name, rules, err)
+		result.Took = time.Since(start)
+		return result, exitFailure
+	}
+
+	code := exitOK
+	for _, f := range findings {
+		fi := finding{File: name, Rule: f.Rule, Severity: f.Severity.String(), Message: f.Err.Error()}
+
+		var objectErr *gs2.ObjectError
+		if errors.As(f.Err, &objectErr) {
+			index := objectErr.Index
+			fi.Object = objectErr.Object
+			fi.Index = &index
+			fi.Reference = objectErr.Reference
+			fi.Values = objectErr.Values
+			fi.Line = dec.BlockLine(objectErr.Object, objectErr.Index)
+			fi.Message = objectErr.Err.Error()
+		}
+
+		result.Findings = append(result.Findings, fi)
+
+		switch f.Severity {
+		case gs2.SeverityError:
+			result.Valid = false
+			code = worstExitCode(code, exitInvalid)
+		case gs2.SeverityWarning:
+			code = worstExitCode(code, exitWarning)
+		}
+	}
+
+	result.Took = time.Since(start)
+	return result, code
+}
+
+// exitCodePrecedence lists exit codes from most to least severe.
+var exitCodePrecedence = []int{exitUsage, exitFailure, exitSyntax, exitInvalid, exitWarning, exitOK}
+
+func worstExitCode(a, b int) int {
+	for _, code := range exitCodePrecedence {
+		if a == code || b == code {
+			return code
+		}
+	}
+
+	return a
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/3lvia/gs2"
+)
+
+func init() {
+	register(&command{
+		name:  "watch",
+		args:  "inbox",
+		short: "Watch a directory for GS2 files, and accept or quarantine them after validation.",
+		run:   runWatch,
+	})
+}
+
+// processingDir is the directory in the inbox where files are moved while they are processed.
+const processingDir = ".processing"
+
+// partialSuffixes are suffixes of files that are still being written, and are never picked up.
+var partialSuffixes = []string{".tmp", ".part", ".partial", ".filepart", ".crdownload", "~"}
+
+// latencyBuckets are the upper bounds in seconds of the processing latency histogram.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type watcher struct {
+	inbox      string
+	accepted   string
+	quarantine string
+	converted  string
+	convertTo  string
+	settle     time.Duration
+	validation *validation
+	log        *watchLogger
+	metrics    *watchMetrics
+
+	// seen holds the size and modification time of files from the previous scan. A file is only picked up when it is
+	// unchanged between two scans.
+	seen map[string]os.FileInfo
+}
+
+func runWatch(e *env, args []string) int {
+	c := commands["watch"]
+
+	var f commonFlags
+	fs := newFlagSet(e, c, &f)
+	accepted := fs.String("accepted", "", "`directory` for valid files. Defaults to inbox/accepted")
+	quarantine := fs.String("quarantine", "", "`directory` for invalid files and their reports. Defaults to inbox/quarantine")
+	convertTo := fs.String("convert", "", "also write accepted files converted to `format`: gs2, json or csv")
+	converted := fs.String("converted", "", "`directory` for converted files. Defaults to inbox/converted")
+	var rf validationFlags
+	rf.add(fs)
+	interval := fs.Duration("interval", 2*time.Second, "how often to scan the inbox")
+	settle := fs.Duration("settle", time.Second, "how long a file must be unmodified before it is picked up")
+	metricsAddr := fs.String("metrics", "", "serve metrics on `address`, e.g. localhost:9090")
+	once := fs.Bool("once", false, "process the files in the inbox and exit")
+	fs.Lookup("o").Usage = "write processing log to `file` instead of stderr"
+	fs.Lookup("json").Usage = "write processing log as JSON"
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() != 1 || *interval <= 0 || (*convertTo != "" && !validFormat(*convertTo)) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	v, err := rf.validation()
+	if err != nil {
+		return f.fail(e, c.name, exitUsage, err)
+	}
+
+	inbox := fs.Arg(0)
+	w := &watcher{
+		inbox:      inbox,
+		accepted:   defaultDir(*accepted, inbox, "accepted"),
+		quarantine: defaultDir(*quarantine, inbox, "quarantine"),
+		convertTo:  *convertTo,
+		settle:     *settle,
+		validation: v,
+		metrics:    newWatchMetrics(),
+		seen:       map[string]os.FileInfo{},
+	}
+
+	dirs := []string{filepath.Join(inbox, processingDir), w.accepted, w.quarantine}
+	if w.convertTo != "" {
+		w.converted = defaultDir(*converted, inbox, "converted")
+		dirs = append(dirs, w.converted)
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return f.fail(e, c.name, exitFailure, err)
+		}
+	}
+
+	logOut := io.WriteCloser(nopWriteCloser{e.stderr})
+	if f.output != "" {
+		if logOut, err = openOutput(e, f.output); err != nil {
+			return f.fail(e, c.name, exitFailure, err)
+		}
+	}
+	defer logOut.Close()
+	w.log = &watchLogger{w: logOut, json: f.json}
+
+	// Files left in the processing directory were being processed when a previous run stopped.
+	if err := w.recover(); err != nil {
+		return f.fail(e, c.name, exitFailure, err)
+	}
+
+	if *once {
+		if err := w.scan(true); err != nil {
+			return f.fail(e, c.name, exitFailure, err)
+		}
+		return exitOK
+	}
+
+	errs := make(chan error, 1)
+	if *metricsAddr != "" {
+		srv := &http.Server{Addr: *metricsAddr, Handler: w.metrics.routes(), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			errs <- srv.ListenAndServe()
+		}()
+		defer srv.Close()
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	fmt.Fprintf(e.stderr, "gs2 %s: watching %s\n", c.name, inbox)
+
+	for {
+		if err := w.scan(false); err != nil {
+			return f.fail(e, c.name, exitFailure, err)
+		}
+
+		select {
+		case err := <-errs:
+			return f.fail(e, c.name, exitFailure, err)
+		case <-stop:
+			return exitOK
+		case <-ticker.C:
+		}
+	}
+}
+
+func defaultDir(dir, inbox, name string) string {
+	if dir != "" {
+		return dir
+	}
+
+	return filepath.Join(inbox, name)
+}
+
+// scan processes the files in the inbox that are ready. If once is true files don't have to be seen in an earlier scan.
+func (w *watcher) scan(once bool) error {
+	infos, err := ioutil.ReadDir(w.inbox)
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]os.FileInfo{}
+	for _, fi := range infos {
+		if !fi.Mode().IsRegular() || partial(fi.Name()) {
+			continue
+		}
+
+		prev, exists := w.seen[fi.Name()]
+		stable := once || (exists && prev.Size() == fi.Size() && prev.ModTime().Equal(fi.ModTime()))
+		if !stable || time.Since(fi.ModTime()) < w.settle {
+			seen[fi.Name()] = fi
+			continue
+		}
+
+		// Claim the file by moving it out of the inbox, so it is never processed twice.
+		claimed := filepath.Join(w.inbox, processingDir, fi.Name())
+		if err := os.Rename(filepath.Join(w.inbox, fi.Name()), claimed); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+
+		w.process(claimed)
+	}
+	w.seen = seen
+
+	return nil
+}
+
+// recover processes files left in the processing directory.
+func (w *watcher) recover() error {
+	dir := filepath.Join(w.inbox, processingDir)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range infos {
+		if fi.Mode().IsRegular() {
+			w.process(filepath.Join(dir, fi.Name()))
+		}
+	}
+
+	return nil
+}
+
+func partial(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+
+	for _, suffix := range partialSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// process validates the claimed file and moves it to the accepted or the quarantine directory.
+func (w *watcher) process(claimed string) {
+	start := time.Now()
+	name := filepath.Base(claimed)
+
+	result, code := w.validate(claimed, name)
+
+	entry := watchEntry{File: name, Status: "accepted", Findings: len(result.Findings)}
+
+	var err error
+	if code == exitOK || code == exitWarning {
+		entry.Path, err = w.accept(claimed, name)
+	} else {
+		entry.Status = "quarantined"
+		entry.Path, err = w.reject(claimed, name, result)
+	}
+	if err != nil {
+		entry.Status = "error"
+		entry.Error = err.Error()
+	}
+
+	entry.Took = time.Since(start)
+	w.metrics.observe(entry.Status, entry.Took)
+	w.log.write(entry)
+}
+
+// validate validates the claimed file. The files in a zip archive are reported together, and all must be valid.
+func (w *watcher) validate(claimed, name string) (validationResult, int) {
+	in, err := gs2.OpenFile(claimed)
+	if err != nil {
+		return ioFailure(name, w.validation.rulesFor(""), err), exitFailure
+	}
+	defer in.Close()
+	in.Name = name
+
+	results, code := validateInput(context.Background(), in, w.validation)
+	if len(results) == 1 {
+		return results[0], code
+	}
+
+	result := validationResult{File: name, Valid: true, Rules: results[0].Rules}
+	for _, r := range results {
+		result.Valid = result.Valid && r.Valid
+		result.Took += r.Took
+		result.Findings = append(result.Findings, r.Findings...)
+	}
+
+	return result, code
+}
+
+// accept writes the converted output, if any, and moves the file to the accepted directory.
+func (w *watcher) accept(claimed, name string) (string, error) {
+	if w.convertTo != "" {
+		if err := w.convert(claimed, name); err != nil {
+			return "", err
+		}
+	}
+
+	return moveFile(claimed, w.accepted, name)
+}
+
+// reject moves the file to the quarantine directory, and writes the validation report next to it.
+func (w *watcher) reject(claimed, name string, result validationResult) (string, error) {
+	path, err := moveFile(claimed, w.quarantine, name)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, result); err != nil {
+		return path, err
+	}
+
+	return path, ioutil.WriteFile(path+".report.json", buf.Bytes(), 0644)
+}
+
+// convert writes the claimed file in the format to convert to. Each file in a zip archive is converted on its own.
+func (w *watcher) convert(claimed, name string) error {
+	in, err := gs2.OpenFile(claimed)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	for _, result := range in.Decode(append([]gs2.DecoderOption{gs2.DecodeValidators()}, w.validation.decoding...)...) {
+		if result.Err != nil {
+			return result.Err
+		}
+
+		var buf bytes.Buffer
+		if err := convert(&buf, result.GS2, w.convertTo); err != nil {
+			return err
+		}
+
+		base := name
+		if in.Compression == gs2.CompressionZip {
+			base = filepath.Base(result.Entry)
+		}
+		base = strings.TrimSuffix(base, ".gz")
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+
+		path := uniquePath(w.converted, base+"."+w.convertTo)
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// moveFile moves the file to dir, and returns its new path. If a file with the same name exists in dir, a number is added
+// to the name. Files are copied if they can't be renamed, e.g. when dir is on another file system.
+func moveFile(path, dir, name string) (string, error) {
+	dst := uniquePath(dir, name)
+	if err := os.Rename(path, dst); err == nil {
+		return dst, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(dst, data, 0644); err != nil {
+		return "", err
+	}
+
+	return dst, os.Remove(path)
+}
+
+func uniquePath(dir, name string) string {
+	path := filepath.Join(dir, name)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s.%d%s", base, i, ext))
+	}
+}
+
+type watchEntry struct {
+	Time     time.Time     `json:"time"`
+	File     string        `json:"file"`
+	Status   string        `json:"status"`
+	Path     string        `json:"path,omitempty"`
+	Findings int           `json:"findings"`
+	Took     time.Duration `json:"took"`
+	Error    string        `json:"error,omitempty"`
+}
+
+type watchLogger struct {
+	w    io.Writer
+	json bool
+}
+
+func (l *watchLogger) write(entry watchEntry) {
+	entry.Time = time.Now()
+	if l.json {
+		writeJSONLine(l.w, entry)
+		return
+	}
+
+	fmt.Fprintf(l.w, "%s %s %s %d findings %v", entry.Time.Format(time.RFC3339), entry.File, entry.Status, entry.Findings, entry.Took)
+	if entry.Error != "" {
+		fmt.Fprintf(l.w, ": %s", entry.Error)
+	}
+	fmt.Fprintln(l.w)
+}
+
+// watchMetrics counts processed files, and is served in the Prometheus text format.
+type watchMetrics struct {
+	mu       sync.Mutex
+	files    map[string]int // Processed files by status.
+	buckets  []int
+	count    int
+	sum      float64
+	lastSeen time.Time
+}
+
+func newWatchMetrics() *watchMetrics {
+	return &watchMetrics{
+		files:   map[string]int{"accepted": 0, "quarantined": 0, "error": 0},
+		buckets: make([]int, len(latencyBuckets)),
+	}
+}
+
+func (m *watchMetrics) observe(status string, took time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seconds := took.Seconds()
+	m.files[status]++
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			m.buckets[i]++
+		}
+	}
+	m.count++
+	m.sum += seconds
+	m.lastSeen = time.Now()
+}
+
+func (m *watchMetrics) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", m.handleMetrics)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, http.StatusOK, "json", func(w io.Writer) error {
+			return writeJSON(w, map[string]string{"status": "ok"})
+		})
+	})
+
+	return mux
+}
+
+func (m *watchMetrics) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	m.write(&buf)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+func (m *watchMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var statuses []string
+	for status := range m.files {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	failed := m.files["quarantined"] + m.files["error"]
+
+	fmt.Fprintf(w, "# HELP gs2_watch_files_processed_total Number of processed files.\n")
+	fmt.Fprintf(w, "# TYPE gs2_watch_files_processed_total counter\n")
+	fmt.Fprintf(w, "gs2_watch_files_processed_total %d\n", m.count)
+	fmt.Fprintf(w, "# HELP gs2_watch_files_failed_total Number of files that were quarantined or could not be processed.\n")
+	fmt.Fprintf(w, "# TYPE gs2_watch_files_failed_total counter\n")
+	fmt.Fprintf(w, "gs2_watch_files_failed_total %d\n", failed)
+	fmt.Fprintf(w, "# HELP gs2_watch_files_total Number of processed files by status.\n")
+	fmt.Fprintf(w, "# TYPE gs2_watch_files_total counter\n")
+	for _, status := range statuses {
+		fmt.Fprintf(w, "gs2_watch_files_total{status=%q} %d\n", status, m.files[status])
+	}
+	fmt.Fprintf(w, "# HELP gs2_watch_processing_seconds Time spent processing a file.\n")
+	fmt.Fprintf(w, "# TYPE gs2_watch_processing_seconds histogram\n")
+	for i, le := range latencyBuckets {
+		fmt.Fprintf(w, "gs2_watch_processing_seconds_bucket{le=%q} %d\n", strconv.FormatFloat(le, 'g', -1, 64), m.buckets[i])
+	}
+	fmt.Fprintf(w, "gs2_watch_processing_seconds_bucket{le=\"+Inf\"} %d\n", m.count)
+	fmt.Fprintf(w, "gs2_watch_processing_seconds_sum %g\n", m.sum)
+	fmt.Fprintf(w, "gs2_watch_processing_seconds_count %d\n", m.count)
+	if !m.lastSeen.IsZero() {
+		fmt.Fprintf(w, "# HELP gs2_watch_last_processed_timestamp_seconds Time the last file was processed.\n")
+		fmt.Fprintf(w, "# TYPE gs2_watch_last_processed_timestamp_seconds gauge\n")
+		fmt.Fprintf(w, "gs2_watch_last_processed_timestamp_seconds %d\n", m.lastSeen.Unix())
+	}
+}
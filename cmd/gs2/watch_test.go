@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestWatcher returns a watcher of a new inbox with the default rules, with its directories created.
+func newTestWatcher(t *testing.T) *watcher {
+	t.Helper()
+
+	var rf validationFlags
+	v, err := rf.validation()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inbox := t.TempDir()
+	w := &watcher{
+		inbox:      inbox,
+		accepted:   filepath.Join(inbox, "accepted"),
+		quarantine: filepath.Join(inbox, "quarantine"),
+		validation: v,
+		log:        &watchLogger{w: &bytes.Buffer{}},
+		metrics:    newWatchMetrics(),
+		seen:       map[string]os.FileInfo{},
+	}
+	for _, dir := range []string{filepath.Join(inbox, processingDir), w.accepted, w.quarantine} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return w
+}
+
+// watchTestFile returns testdata/timeseries.gs2, with a wrong Sum if invalid is true.
+func watchTestFile(t *testing.T, invalid bool) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile("../../testdata/timeseries.gs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invalid {
+		data = bytes.Replace(data, []byte("#Sum=27"), []byte("#Sum=28"), 1)
+	}
+
+	return data
+}
+
+func writeTestFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// dirNames returns the names of the regular files in dir.
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func expectNames(t *testing.T, dir string, expected ...string) {
+	t.Helper()
+
+	if expected == nil {
+		expected = []string{}
+	}
+	if names := dirNames(t, dir); strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v in %s, but got %v", expected, filepath.Base(dir), names)
+	}
+}
+
+func TestWatcher_ScanSkipsPartialAndHidden(t *testing.T) {
+	w := newTestWatcher(t)
+	data := watchTestFile(t, false)
+
+	skipped := []string{".hidden.gs2", "a.gs2.crdownload", "a.gs2.filepart", "a.gs2.part", "a.gs2.partial", "a.gs2.tmp", "a.gs2~"}
+	for _, name := range append([]string{"a.gs2"}, skipped...) {
+		writeTestFile(t, filepath.Join(w.inbox, name), data)
+	}
+
+	if err := w.scan(true); err != nil {
+		t.Fatal(err)
+	}
+
+	expectNames(t, w.inbox, skipped...)
+	expectNames(t, w.accepted, "a.gs2")
+	expectNames(t, filepath.Join(w.inbox, processingDir))
+}
+
+func TestWatcher_ScanStable(t *testing.T) {
+	w := newTestWatcher(t)
+	path := filepath.Join(w.inbox, "a.gs2")
+	data := watchTestFile(t, false)
+	modified := time.Now().Add(-time.Minute)
+
+	writeTestFile(t, path, data[:100])
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	// A file is first seen.
+	if err := w.scan(false); err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, w.inbox, "a.gs2")
+
+	// The file is still being written.
+	writeTestFile(t, path, data)
+	modified = modified.Add(time.Second)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.scan(false); err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, w.inbox, "a.gs2")
+
+	// The file is unchanged since the last scan, but was modified too recently.
+	w.settle = time.Hour
+	if err := w.scan(false); err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, w.inbox, "a.gs2")
+
+	// The file is unchanged and settled.
+	w.settle = time.Second
+	if err := w.scan(false); err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, w.inbox)
+	expectNames(t, w.accepted, "a.gs2")
+	expectNames(t, w.quarantine)
+}
+
+func TestWatcher_Recover(t *testing.T) {
+	w := newTestWatcher(t)
+	processing := filepath.Join(w.inbox, processingDir)
+	writeTestFile(t, filepath.Join(processing, "a.gs2"), watchTestFile(t, false))
+	writeTestFile(t, filepath.Join(processing, "b.gs2"), watchTestFile(t, true))
+
+	if err := w.recover(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectNames(t, processing)
+	expectNames(t, w.accepted, "a.gs2")
+	expectNames(t, w.quarantine, "b.gs2", "b.gs2.report.json")
+}
+
+func TestWatcher_Quarantine(t *testing.T) {
+	w := newTestWatcher(t)
+	writeTestFile(t, filepath.Join(w.inbox, "a.gs2"), watchTestFile(t, true))
+	writeTestFile(t, filepath.Join(w.inbox, "b.gs2"), []byte("##Start-message\n#Id 1\n"))
+
+	if err := w.scan(true); err != nil {
+		t.Fatal(err)
+	}
+
+	expectNames(t, w.accepted)
+	expectNames(t, w.quarantine, "a.gs2", "a.gs2.report.json", "b.gs2", "b.gs2.report.json")
+
+	for name, rule := range map[string]string{"a.gs2": "time-series-values", "b.gs2": "syntax"} {
+		data, err := os.ReadFile(filepath.Join(w.quarantine, name+".report.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var report validationResult
+		if err := json.Unmarshal(data, &report); err != nil {
+			t.Fatal(err)
+		}
+		if report.File != name || report.Valid || len(report.Findings) != 1 || report.Findings[0].Rule != rule {
+			t.Errorf("expected a report of %s with a %s finding, but got %+v", name, rule, report)
+		}
+	}
+}
+
+func TestWatcher_NameCollisions(t *testing.T) {
+	w := newTestWatcher(t)
+	w.convertTo = "csv"
+	w.converted = filepath.Join(w.inbox, "converted")
+	if err := os.MkdirAll(w.converted, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		writeTestFile(t, filepath.Join(w.inbox, "a.gs2"), watchTestFile(t, false))
+		writeTestFile(t, filepath.Join(w.inbox, "b.gs2"), watchTestFile(t, true))
+		if err := w.scan(true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectNames(t, w.accepted, "a.1.gs2", "a.2.gs2", "a.gs2")
+	expectNames(t, w.converted, "a.1.csv", "a.2.csv", "a.csv")
+	expectNames(t, w.quarantine, "b.1.gs2", "b.1.gs2.report.json", "b.2.gs2", "b.2.gs2.report.json", "b.gs2",
+		"b.gs2.report.json")
+}
+
+func TestUniquePath(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, expected := range []string{"a.gs2", "a.1.gs2", "a.2.gs2"} {
+		path := uniquePath(dir, "a.gs2")
+		if path != filepath.Join(dir, expected) {
+			t.Errorf("expected %s, but got %s", expected, path)
+		}
+		writeTestFile(t, path, nil)
+	}
+}
+
+func TestRun_WatchOnce(t *testing.T) {
+	inbox := t.TempDir()
+	writeTestFile(t, filepath.Join(inbox, "a.gs2"), watchTestFile(t, false))
+	writeTestFile(t, filepath.Join(inbox, "b.gs2"), watchTestFile(t, true))
+	writeTestFile(t, filepath.Join(inbox, "c.gs2.part"), nil)
+	processing := filepath.Join(inbox, processingDir)
+	if err := os.MkdirAll(processing, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(processing, "d.gs2"), watchTestFile(t, false))
+
+	code, _, stderr := runTest("", "watch", "-once", "-settle", "0", "-json", inbox)
+	if code != exitOK {
+		t.Fatalf("expected exit code %d, but got %d: %s", exitOK, code, stderr)
+	}
+
+	expectNames(t, inbox, "c.gs2.part")
+	expectNames(t, processing)
+	expectNames(t, filepath.Join(inbox, "accepted"), "a.gs2", "d.gs2")
+	expectNames(t, filepath.Join(inbox, "quarantine"), "b.gs2", "b.gs2.report.json")
+
+	var statuses []string
+	for _, line := range strings.Split(strings.TrimSpace(stderr), "\n") {
+		var entry watchEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("expected a JSON log line, but got %q", line)
+		}
+		statuses = append(statuses, entry.File+" "+entry.Status)
+	}
+	sort.Strings(statuses)
+	if expected := "a.gs2 accepted,b.gs2 quarantined,d.gs2 accepted"; strings.Join(statuses, ",") != expected {
+		t.Errorf("expected log entries %s, but got %v", expected, statuses)
+	}
+}
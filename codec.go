@@ -0,0 +1,287 @@
+package gs2
+
+import (
+	"bytes"
+	"encoding"
+	"math"
+	"strconv"
+	"time"
+)
+
+// useCodecs makes the Decoder and Encoder use the methods of Unmarshaler and Marshaler. Tests turn it off to compare with
+// decoding and encoding by reflection.
+var useCodecs = true
+
+// Unmarshaler is implemented by blocks that decode their own attributes, usually with code written by generate-codec, see
+// cmd/generate-codec. The Decoder uses it instead of reflection.
+type Unmarshaler interface {
+	// UnmarshalGS2 decodes the value of the attribute with the given name using a, and reports whether the attribute is
+	// known. The Decoder skips the value of unknown attributes. name is only valid during the call.
+	UnmarshalGS2(name []byte, a *AttributeDecoder) (bool, error)
+}
+
+// Marshaler is implemented by blocks that encode their own attributes, usually with code written by generate-codec. The
+// Encoder uses it instead of reflection.
+type Marshaler interface {
+	// MarshalGS2 writes the attributes of the block using e, and returns e.Err().
+	MarshalGS2(e *AttributeEncoder) error
+}
+
+// LookupAttribute returns the index of name in names, or -1 if it isn't there. Names are compared like the Decoder does,
+// ignoring case if there is no exact match.
+func LookupAttribute(name []byte, names []string) int {
+	for i, n := range names {
+		if string(name) == n {
+			return i
+		}
+	}
+
+	for i, n := range names {
+		if bytes.EqualFold(name, []byte(n)) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// AttributeDecoder decodes the value of an attribute for an Unmarshaler. Each value must be decoded once, with the method
+// for its type.
+type AttributeDecoder struct {
+	d *Decoder
+}
+
+// String decodes a string.
+func (a *AttributeDecoder) String(p *string) error {
+	b, err := a.d.scanValue()
+	if err != nil {
+		return err
+	}
+
+	*p = a.d.intern(b)
+	return nil
+}
+
+// Int decodes an integer.
+func (a *AttributeDecoder) Int(p *int) error {
+	b, err := a.d.scanValue()
+	if err != nil {
+		return err
+	}
+
+	i, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*p = int(i)
+	return nil
+}
+
+// Float decodes a decimal.
+func (a *AttributeDecoder) Float(p *float64) error {
+	b, err := a.d.scanValue()
+	if err != nil {
+		return err
+	}
+
+	f, err := parseFloat(b)
+	if err != nil {
+		return err
+	}
+
+	*p = f
+	return nil
+}
+
+// Time decodes a time. Times are in the time zone of the file until the Decoder converts them to UTC.
+func (a *AttributeDecoder) Time(p *time.Time) error {
+	b, err := a.d.scanValue()
+	if err != nil {
+		return err
+	}
+
+	t, err := parseTimeBytes(b)
+	if err != nil {
+		return err
+	}
+
+	*p = t
+	return nil
+}
+
+// Duration decodes a step, e.g. 0000-00-00.01:00:00.
+func (a *AttributeDecoder) Duration(p *time.Duration) error {
+	b, err := a.d.scanValue()
+	if err != nil {
+		return err
+	}
+
+	d, err := parseDuration(string(b))
+	if err != nil {
+		return err
+	}
+
+	*p = d
+	return nil
+}
+
+// Triplet decodes a single triplet.
+func (a *AttributeDecoder) Triplet(p *Triplet) error {
+	b, err := a.d.scanValue()
+	if err != nil {
+		return err
+	}
+
+	t, err := parseTripletBytes(b)
+	if err != nil {
+		return err
+	}
+
+	*p = t
+	return nil
+}
+
+// Triplets decodes an array of triplets, replacing the values in p.
+func (a *AttributeDecoder) Triplets(p *[]Triplet) error {
+	triplets, err := a.d.triplets()
+	*p = triplets
+	return err
+}
+
+// Text decodes a value with its UnmarshalText method.
+func (a *AttributeDecoder) Text(u encoding.TextUnmarshaler) error {
+	b, err := a.d.scanValue()
+	if err != nil {
+		return err
+	}
+
+	return u.UnmarshalText(b)
+}
+
+// AttributeEncoder writes attributes for a Marshaler. Empty attributes are left out like the Encoder does: mandatory
+// attributes where zero is a value are always written, other attributes in the schema of the block are left out when they
+// are zero, and attributes that aren't in the schema are left out when zero if omitempty is true, i.e. if their tag has
+// omitempty. The first error stops the encoding, and is returned by Err.
+type AttributeEncoder struct {
+	e      *Encoder
+	schema BlockSchema
+	err    error
+}
+
+// Err returns the first error encoding the attributes.
+func (a *AttributeEncoder) Err() error {
+	return a.err
+}
+
+// begin writes the name of the attribute and reports whether its value should be written.
+func (a *AttributeEncoder) begin(name string, zero, omitempty bool) bool {
+	if a.err != nil {
+		return false
+	}
+
+	if s, ok := a.schema.Attribute(name); ok {
+		omitempty = !s.Required(a.e.messageType) || !s.Type.zeroIsValue()
+	}
+	if zero && omitempty {
+		return false
+	}
+
+	a.e.writeString("#")
+	a.e.writeString(name)
+	a.e.writeString("=")
+	return true
+}
+
+// String writes a string.
+func (a *AttributeEncoder) String(name, v string, omitempty bool) {
+	if a.begin(name, v == "", omitempty) {
+		a.e.writeString(v)
+		a.e.writeString("\n")
+	}
+}
+
+// Int writes an integer.
+func (a *AttributeEncoder) Int(name string, v int, omitempty bool) {
+	if a.begin(name, v == 0, omitempty) {
+		a.e.buf = strconv.AppendInt(a.e.buf, int64(v), 10)
+		a.e.writeString("\n")
+	}
+}
+
+// Float writes a decimal with the precision of the Encoder.
+func (a *AttributeEncoder) Float(name string, v float64, omitempty bool) {
+	if a.begin(name, math.Float64bits(v) == 0, omitempty) {
+		a.e.buf = strconv.AppendFloat(a.e.buf, v, 'f', a.e.options.floatPrecision, 64)
+		a.e.writeString("\n")
+	}
+}
+
+// Time writes a time.
+func (a *AttributeEncoder) Time(name string, v time.Time, omitempty bool) {
+	if a.begin(name, v == time.Time{}, omitempty) {
+		if a.e.loc != nil {
+			v = v.In(a.e.loc)
+		}
+		a.e.writeString(formatTime(v))
+		a.e.writeString("\n")
+	}
+}
+
+// Duration writes a step.
+func (a *AttributeEncoder) Duration(name string, v time.Duration, omitempty bool) {
+	if a.begin(name, v == 0, omitempty) {
+		a.e.writeString(encodeDuration(v))
+		a.e.writeString("\n")
+	}
+}
+
+// Triplet writes a single triplet.
+func (a *AttributeEncoder) Triplet(name string, v Triplet, omitempty bool) {
+	zero := math.Float64bits(v.Value) == 0 && v.Time == time.Time{} && v.Quality == ""
+	if a.begin(name, zero, omitempty) {
+		a.triplet(v)
+		a.e.writeString("\n")
+	}
+}
+
+// Triplets writes an array of triplets. Only a nil array is empty.
+func (a *AttributeEncoder) Triplets(name string, v []Triplet, omitempty bool) {
+	if !a.begin(name, v == nil, omitempty) {
+		return
+	}
+
+	a.e.writeString("< ")
+	for i, t := range v {
+		if i > 0 && i%cancelCheckInterval == 0 {
+			if a.err = a.e.ctx.Err(); a.err != nil {
+				return
+			}
+		}
+		a.triplet(t)
+		a.e.writeString(" ")
+	}
+	a.e.writeString(">\n")
+}
+
+func (a *AttributeEncoder) triplet(t Triplet) {
+	if a.e.loc != nil && !t.Time.IsZero() {
+		t.Time = t.Time.In(a.e.loc)
+	}
+	a.e.writeString(a.e.encodeTriplet(t))
+}
+
+// Text writes the value returned by the MarshalText method of v. zero tells whether v is empty.
+func (a *AttributeEncoder) Text(name string, v encoding.TextMarshaler, zero, omitempty bool) {
+	if !a.begin(name, zero, omitempty) {
+		return
+	}
+
+	text, err := v.MarshalText()
+	if err != nil {
+		a.err = err
+		return
+	}
+	a.e.write(text)
+	a.e.writeString("\n")
+}
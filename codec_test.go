@@ -0,0 +1,173 @@
+package gs2
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// withoutCodecs runs f with the Decoder and Encoder using reflection.
+func withoutCodecs(f func()) {
+	defer func() { useCodecs = true }()
+	useCodecs = false
+	f()
+}
+
+// decodeCodecs decodes data with and without the generated code, and fails if the results differ.
+func decodeCodecs(t *testing.T, data []byte) *GS2 {
+	t.Helper()
+
+	var expected *GS2
+	var expectedErr error
+	withoutCodecs(func() {
+		expected, expectedErr = NewDecoder(bytes.NewReader(data), DecodeValidators()).Decode()
+	})
+
+	g, err := NewDecoder(bytes.NewReader(data), DecodeValidators()).Decode()
+	if !reflect.DeepEqual(g, expected) || !reflect.DeepEqual(err, expectedErr) {
+		t.Fatalf("expected %+v, %v, but got %+v, %v", expected, expectedErr, g, err)
+	}
+
+	return g
+}
+
+func TestCodecs(t *testing.T) {
+	for _, name := range []string{"testdata/timeseries.gs2", "testdata/timeseries_noNewlines.gs2", "testdata/meterreading.gs2",
+		"testdata/durations.gs2"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			g := decodeCodecs(t, data)
+
+			for _, opts := range [][]EncoderOption{
+				{EncodeValidators()},
+				{EncodeValidators(), EncodeFloatPrecision(2)},
+				{EncodeValidators(), EncodeCanonical()},
+			} {
+				var expected bytes.Buffer
+				withoutCodecs(func() {
+					if err := NewEncoder(&expected, opts...).Encode(g); err != nil {
+						t.Fatal(err)
+					}
+				})
+
+				var buf bytes.Buffer
+				if err := NewEncoder(&buf, opts...).Encode(g); err != nil {
+					t.Fatal(err)
+				}
+				if buf.String() != expected.String() {
+					t.Errorf("expected\n%s\nbut got\n%s", expected.String(), buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCodecs_Empty(t *testing.T) {
+	g := &GS2{
+		StartMessage:  StartMessage{MessageType: MessageTypeSettlementData},
+		MeterReadings: []MeterReading{{Reference: "a"}},
+		TimeSeries: []TimeSeries{
+			{Reference: "b", Value: []Triplet{}},
+			{Reference: "c", TypeOfValue: "x", DirectionOfFlow: DirectionIn, Start: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	var expected bytes.Buffer
+	withoutCodecs(func() {
+		if err := NewEncoder(&expected, EncodeValidators()).Encode(g); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, EncodeValidators()).Encode(g); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected.String() {
+		t.Errorf("expected\n%s\nbut got\n%s", expected.String(), buf.String())
+	}
+}
+
+func TestLookupAttribute(t *testing.T) {
+	names := []string{"Value", "value", "No-of-values"}
+	tests := []struct {
+		name     string
+		expected int
+	}{
+		{"Value", 0},
+		{"value", 1},
+		{"VALUE", 0},
+		{"no-of-VALUES", 2},
+		{"Sum", -1},
+		{"", -1},
+	}
+
+	for _, test := range tests {
+		if i := LookupAttribute([]byte(test.name), names); i != test.expected {
+			t.Errorf("%q: expected %d, but got %d", test.name, test.expected, i)
+		}
+	}
+}
+
+func FuzzCodecs(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decodeCodecs(t, data)
+	})
+}
+
+func BenchmarkCodecs(b *testing.B) {
+	data, err := os.ReadFile("testdata/meterreading.gs2")
+	if err != nil {
+		b.Fatal(err)
+	}
+	g, err := NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, codecs := range []bool{false, true} {
+		name := "reflection"
+		if codecs {
+			name = "generated"
+		}
+
+		b.Run(name+"/decode", func(b *testing.B) {
+			defer func() { useCodecs = true }()
+			useCodecs = codecs
+
+			b.ReportAllocs()
+			d := NewDecoder(bytes.NewReader(data), DecodeValidators())
+			for n := 0; n < b.N; n++ {
+				d.Reset(bytes.NewReader(data))
+				if _, err := d.Decode(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(name+"/encode", func(b *testing.B) {
+			defer func() { useCodecs = true }()
+			useCodecs = codecs
+
+			b.ReportAllocs()
+			var buf bytes.Buffer
+			e := NewEncoder(&buf, EncodeValidators())
+			for n := 0; n < b.N; n++ {
+				buf.Reset()
+				if err := e.Encode(g); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
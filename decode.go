@@ -1,34 +1,70 @@
 package gs2
 
 import (
+	"bytes"
+	"context"
+	"encoding"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 )
 
 const gs2TimeLayout = "2006-01-02.15:04:05"
 
 const scanEnd = -1
 
-// Decoder reads and decodes GS2 input. NB: year, month and day is not supported in Step attribute. Only hour, minute and seconds
-// are used when decoding duration.
+// cancelCheckInterval is how many values of an array are decoded or encoded between checks for cancellation.
+const cancelCheckInterval = 1024
+
+// Decoder reads and decodes GS2 input. Steps of days, hours, minutes and seconds are supported. Steps of months or years are
+// rejected, as they can't be represented by a time.Duration.
 type Decoder struct {
 	options       decoderOptions
+	ctx           context.Context
 	rdr           io.Reader
-	scan          *scanner
+	scan          scanner
 	buf           []byte
 	bytesRead     int
 	lastByteRead  byte
 	lastScanState int
-	typeCache     map[reflect.Type]map[string]int
+	blockOffsets  map[string][]int
+	tok           token
+	strings       map[string]string
+	arena         strings.Builder
+	attr          AttributeDecoder
+
+	// Where the Decoder is, for LimitError.
+	objects       int
+	object        string
+	objectIndex   int
+	attributeName []byte
+}
+
+// SyntaxError is returned by the Decoder when the input can't be decoded.
+type SyntaxError struct {
+	Offset int // Number of bytes read before the error occurred.
+	Line   int // Line of the error, starting at 1.
+	Err    error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
 }
 
 type decoderOptions struct {
-	validators []Validator
+	validators        []Validator
+	contextValidators []ContextValidator
+	limits            Limits
+	workers           int
 }
 
 var defaultDecoderOptions = decoderOptions{
@@ -49,24 +85,99 @@ func DecodeValidators(v ...Validator) DecoderOption {
 	}
 }
 
+// DecodeAppendValidators adds validators to be run after decoding an object, keeping the default ones.
+func DecodeAppendValidators(v ...Validator) DecoderOption {
+	return func(o *decoderOptions) {
+		o.validators = append(append([]Validator(nil), o.validators...), v...)
+	}
+}
+
+// DecodeContextValidators sets the validators to be run with the context after the other validators. There are none by default.
+func DecodeContextValidators(v ...ContextValidator) DecoderOption {
+	return func(o *decoderOptions) {
+		o.contextValidators = v
+	}
+}
+
 // NewDecoder returna a new Decoder reading from r.
 func NewDecoder(r io.Reader, opt ...DecoderOption) *Decoder {
-	opts := defaultDecoderOptions
+	d := &Decoder{
+		options:      defaultDecoderOptions,
+		ctx:          context.Background(),
+		rdr:          r,
+		scan:         scanner{step: stateBeginScan},
+		blockOffsets: make(map[string][]int),
+	}
 
 	for _, o := range opt {
-		o(&opts)
+		o(&d.options)
+	}
+
+	return d
+}
+
+// Reset makes d decode the input read from r, as if it was returned by NewDecoder with the same options. The buffers of d
+// are reused, which saves allocations when decoding many files.
+func (d *Decoder) Reset(r io.Reader) {
+	d.ctx = context.Background()
+	d.rdr = r
+	d.scan.reset()
+	d.buf = d.buf[:0]
+	d.bytesRead, d.lastByteRead, d.lastScanState = 0, 0, 0
+	for name, offsets := range d.blockOffsets {
+		d.blockOffsets[name] = offsets[:0]
+	}
+	d.tok.reset()
+	d.objects, d.object, d.objectIndex, d.attributeName = 0, "", 0, nil
+}
+
+// BlockLine returns the line where the index'th block with the given name started in the decoded input, or 0 if there is no
+// such block. Only blocks that are part of the GS2 type are recorded.
+func (d *Decoder) BlockLine(name string, index int) int {
+	offsets := d.blockOffsets[name]
+	if index < 0 || index >= len(offsets) {
+		return 0
+	}
+
+	return d.line(offsets[index])
+}
+
+// line returns the line of the byte at the given offset.
+func (d *Decoder) line(offset int) int {
+	if offset > len(d.buf) {
+		offset = len(d.buf)
 	}
 
-	return &Decoder{
-		options:   opts,
-		rdr:       r,
-		scan:      newScanner(),
-		typeCache: make(map[reflect.Type]map[string]int),
+	return bytes.Count(d.buf[:offset], []byte{'\n'}) + 1
+}
+
+func (d *Decoder) syntaxError(err error) error {
+	// Exceeded limits and cancellation aren't syntax errors.
+	if limitErr, ok := err.(*LimitError); ok {
+		return limitErr
 	}
+	if ctxErr := d.ctx.Err(); ctxErr != nil && err == ctxErr {
+		return err
+	}
+
+	// The error was detected while looking at the last byte read.
+	last := d.bytesRead - 1
+	if last < 0 {
+		last = 0
+	}
+
+	return &SyntaxError{Offset: d.bytesRead, Line: d.line(last), Err: err}
 }
 
 // Decode reads the input and puts it in a GS2 object.
 func (d *Decoder) Decode() (*GS2, error) {
+	return d.DecodeContext(context.Background())
+}
+
+// DecodeContext is like Decode, but stops with the error of ctx when ctx is done. Cancellation is checked between blocks, within
+// long arrays and between validators, and ctx is given to the validators set with DecodeContextValidators.
+func (d *Decoder) DecodeContext(ctx context.Context) (*GS2, error) {
+	d.ctx = ctx
 	result := &GS2{}
 
 	err := d.decode(reflect.ValueOf(result))
@@ -74,24 +185,43 @@ func (d *Decoder) Decode() (*GS2, error) {
 		return nil, err
 	}
 
-	for _, validator := range d.options.validators {
-		if err := validator(result); err != nil {
-			return nil, err
-		}
-	}
-
 	var gmtOffset = gmtReferenceToOffset(result.StartMessage.GMTReference)
 
 	result.StartMessage.Time = addGmtOffset(result.StartMessage.Time, gmtOffset)
 	result.EndMessage.Time = addGmtOffset(result.EndMessage.Time, gmtOffset)
 
 	for i := range result.MeterReadings {
-		result.MeterReadings[i].Time = addGmtOffset(result.MeterReadings[i].Time, gmtOffset)
+		mr := &result.MeterReadings[i]
+		mr.Time = addGmtOffset(mr.Time, gmtOffset)
+		mr.Value.Time = addGmtOffset(mr.Value.Time, gmtOffset)
 	}
 
 	for i := range result.TimeSeries {
-		result.TimeSeries[i].Start = addGmtOffset(result.TimeSeries[i].Start, gmtOffset)
-		result.TimeSeries[i].Stop = addGmtOffset(result.TimeSeries[i].Stop, gmtOffset)
+		ts := &result.TimeSeries[i]
+		ts.Start = addGmtOffset(ts.Start, gmtOffset)
+		ts.Stop = addGmtOffset(ts.Stop, gmtOffset)
+		for j := range ts.Value {
+			ts.Value[j].Time = addGmtOffset(ts.Value[j].Time, gmtOffset)
+		}
+	}
+
+	// Validators see the times in UTC, like the caller.
+	for _, validator := range d.options.validators {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := validator(result); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, validator := range d.options.contextValidators {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := validator(ctx, result); err != nil {
+			return nil, err
+		}
 	}
 
 	return result, nil
@@ -109,44 +239,90 @@ func gmtReferenceToOffset(gmtReference int) time.Duration {
 	return time.Hour * time.Duration(-gmtReference)
 }
 
+// gmtReferenceLocation returns the time zone of times in a file with the given GMT-reference.
+func gmtReferenceLocation(gmtReference int) *time.Location {
+	return time.FixedZone("", gmtReference*60*60)
+}
+
 func (d *Decoder) decode(v reflect.Value) error {
 	if err := d.fillBuffer(); err != nil {
 		return err
 	}
 
-	// Scan to the first # in the file, which should be the first block. The following block will be identified by one # since
-	// the first # of the block will be the delimiter of the previous blocks last value. As per the specification spaces are not
-	// to be used as delimiters.
-	d.scanWhile(scanHash)
+	if d.options.workers > 1 && d.decodeParallel(v) {
+		return nil
+	}
+
+	return d.decodeRange(v, 0, len(d.buf)+1)
+}
+
+// decodeRange decodes the blocks from start to end into v, where start and end are offsets after the ## starting a block, or
+// the start of the input and after the end of the input. It returns errBoundary if the input is scanned past end.
+func (d *Decoder) decodeRange(v reflect.Value, start, end int) error {
+	if start == 0 {
+		// Scan to the first # in the file, which should be the first block. The following block will be identified by one #
+		// since the first # of the block will be the delimiter of the previous blocks last value. As per the specification
+		// spaces are not to be used as delimiters.
+		d.scanWhile(scanHash)
+	} else {
+		// Continue as if the first # of the block had just been scanned.
+		d.bytesRead = start - 1
+		d.scan.step = stateHash
+	}
 
 	for d.bytesRead < len(d.buf) {
 		d.scanNext()
 		switch d.lastScanState {
 		// Scan for two ## which is the start of a block.
 		case scanHash:
-			if err := d.block(v); err != nil {
+			if d.bytesRead == end {
+				if !d.scan.inState(stateBlock) {
+					return errBoundary
+				}
+				return nil
+			}
+			if err := d.ctx.Err(); err != nil {
 				return err
 			}
+			if err := d.block(v); err != nil {
+				return d.syntaxError(err)
+			}
+			if d.bytesRead >= end {
+				return errBoundary
+			}
 		case scanSkipSpace:
 			continue
 		default:
-			return fmt.Errorf("unable to find start of block. Got character %q", d.buf[d.bytesRead-1])
+			return d.syntaxError(fmt.Errorf("unable to find start of block. Got character %q", d.buf[d.bytesRead-1]))
 		}
 	}
 
+	if end <= len(d.buf) {
+		return errBoundary
+	}
+
 	return nil
 }
 
 func (d *Decoder) block(v reflect.Value) error {
 	dataStart := d.bytesRead
 
-	var blockName []byte
+	d.objects++
+	d.object, d.objectIndex, d.attributeName = "", 0, nil
+	if err := d.exceeds(LimitObjects, d.options.limits.MaxObjects, d.objects); err != nil {
+		return err
+	}
+
+	d.tok.reset()
 loop:
 	for {
 		d.scanNext()
 		switch d.lastScanState {
 		case scanContinue:
-			blockName = append(blockName, d.lastByteRead)
+			d.tok.add(d.buf, d.bytesRead-1)
+			if err := d.exceeds(LimitNameLength, d.options.limits.MaxNameLength, d.tok.len()); err != nil {
+				return err
+			}
 		case scanSkipSpace:
 		case scanHash:
 			break loop
@@ -155,46 +331,83 @@ loop:
 		}
 	}
 
-	field, exists := d.getField(string(blockName), reflect.Indirect(v).Type())
+	indirect := reflect.Indirect(v)
+	fields := cachedFields(indirect.Type())
+	field, exists := fields.lookup(d.tok.bytes(d.buf))
 	if !exists {
 		d.skipBlock()
 		return nil
 	}
 
-	vf := reflect.Indirect(v).Field(field)
+	name := fields.names[field]
+	offsets := d.blockOffsets[name]
+	if offsets == nil {
+		offsets = make([]int, 0, minBlocks)
+	}
+	d.blockOffsets[name] = append(offsets, dataStart-2)
+	d.object, d.objectIndex = name, len(offsets)
 
+	// The block is decoded in place. A block of a single field replaces the previous one.
+	vf := indirect.Field(field)
 	var block reflect.Value
 	if vf.Kind() == reflect.Slice {
-		block = reflect.New(vf.Type().Elem())
+		block = appendZero(vf)
 	} else {
-		block = reflect.New(vf.Type())
+		vf.Set(reflect.Zero(vf.Type()))
+		block = vf
+	}
+
+	// Blocks with generated code decode their own attributes.
+	var u Unmarshaler
+	if useCodecs {
+		u, _ = block.Addr().Interface().(Unmarshaler)
 	}
 
 	for d.lastScanState == scanHash && d.peek(0) != '#' {
-		if err := d.attribute(block); err != nil {
+		if err := d.attribute(block, u); err != nil {
 			return err
 		}
 	}
 
-	if vf.Kind() == reflect.Slice {
-		vf.Set(reflect.Append(vf, reflect.Indirect(block)))
+	return nil
+}
+
+// minBlocks is the capacity of the slices of blocks when the first block is decoded, so files with few blocks of a kind only
+// allocate once.
+const minBlocks = 8
+
+// appendZero appends a zero element to the slice v and returns it. Unlike reflect.Append, it only allocates when the slice
+// grows, and then at least to minBlocks elements.
+func appendZero(v reflect.Value) reflect.Value {
+	n := v.Len()
+	if n < v.Cap() {
+		v.SetLen(n + 1)
+		v.Index(n).Set(reflect.Zero(v.Type().Elem()))
 	} else {
-		vf.Set(reflect.Indirect(block))
+		grown := reflect.MakeSlice(v.Type(), n+1, 2*n+minBlocks)
+		reflect.Copy(grown, v)
+		v.Set(grown)
 	}
 
-	return nil
+	return v.Index(n)
 }
 
-func (d *Decoder) attribute(v reflect.Value) error {
+// attribute decodes the next attribute into v, or with u if it isn't nil.
+func (d *Decoder) attribute(v reflect.Value, u Unmarshaler) error {
 	dataStart := d.bytesRead
 
-	var attributeName []byte
+	d.attributeName = nil
+
+	d.tok.reset()
 loop:
 	for {
 		d.scanNext()
 		switch d.lastScanState {
 		case scanContinue:
-			attributeName = append(attributeName, d.lastByteRead)
+			d.tok.add(d.buf, d.bytesRead-1)
+			if err := d.exceeds(LimitNameLength, d.options.limits.MaxNameLength, d.tok.len()); err != nil {
+				return err
+			}
 		case scanBeginValue:
 			break loop
 		default:
@@ -202,41 +415,59 @@ loop:
 		}
 	}
 
-	field, exists := d.getField(string(attributeName), reflect.Indirect(v).Type())
+	// Names are contiguous in the input, so the name refers to the input and isn't overwritten by the value.
+	d.attributeName = d.tok.bytes(d.buf)
+
+	if u != nil {
+		d.attr.d = d
+		known, err := u.UnmarshalGS2(d.attributeName, &d.attr)
+		if !known && err == nil {
+			d.skipAttribute()
+		}
+		return err
+	}
+
+	indirect := reflect.Indirect(v)
+	field, exists := cachedFields(indirect.Type()).lookup(d.attributeName)
 	if !exists {
 		d.skipAttribute()
 		return nil
 	}
 
-	vf := reflect.Indirect(v).Field(field)
-	attribute := reflect.New(vf.Type())
-
-	if reflect.Indirect(attribute).Kind() == reflect.Slice {
-		if err := d.array(attribute); err != nil {
-			return err
-		}
-	} else {
-		if err := d.value(attribute); err != nil {
-			return err
-		}
+	vf := indirect.Field(field)
+	if vf.Kind() == reflect.Slice {
+		// A repeated attribute replaces the previous values.
+		vf.Set(reflect.Zero(vf.Type()))
+		return d.array(vf)
 	}
 
-	vf.Set(reflect.Indirect(attribute))
-
-	return nil
+	return d.value(vf)
 }
 
 func (d *Decoder) value(v reflect.Value) error {
+	b, err := d.scanValue()
+	if err != nil {
+		return err
+	}
+
+	return d.setValue(v, b)
+}
+
+// scanValue scans the next value. It is only valid until the next value is scanned.
+func (d *Decoder) scanValue() ([]byte, error) {
 	dataStart := d.bytesRead
 
-	var value []byte
+	d.tok.reset()
 loop:
 	for {
 		d.scanNext()
 
 		switch d.lastScanState {
 		case scanContinue:
-			value = append(value, d.lastByteRead)
+			d.tok.add(d.buf, d.bytesRead-1)
+			if err := d.exceeds(LimitValueLength, d.options.limits.MaxValueLength, d.tok.len()); err != nil {
+				return nil, err
+			}
 
 		case scanSkipSpace:
 		case scanHash:
@@ -244,111 +475,205 @@ loop:
 		case scanEnd:
 			break loop
 		default:
-			return fmt.Errorf("unexpected state while decoding value: %s", string(d.buf[dataStart:d.bytesRead]))
+			return nil, fmt.Errorf("unexpected state while decoding value: %s", string(d.buf[dataStart:d.bytesRead]))
 		}
 	}
 
-	indirect := reflect.Indirect(v)
-	switch indirect.Kind() {
+	return d.tok.bytes(d.buf), nil
+}
+
+// setValue sets v to the value in b. The types of the GS2 type are set without reflection.
+func (d *Decoder) setValue(v reflect.Value, b []byte) error {
+	switch p := v.Addr().Interface().(type) {
+	case *string:
+		*p = d.intern(b)
+	case *int:
+		pi, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			return err
+		}
+		*p = int(pi)
+	case *float64:
+		pf, err := parseFloat(b)
+		if err != nil {
+			return err
+		}
+		*p = pf
+	case *time.Time:
+		t, err := parseTimeBytes(b)
+		if err != nil {
+			return err
+		}
+		*p = t
+	case *time.Duration:
+		d, err := parseDuration(string(b))
+		if err != nil {
+			return err
+		}
+		*p = d
+	case *Triplet:
+		t, err := parseTripletBytes(b)
+		if err != nil {
+			return err
+		}
+		*p = t
+	case encoding.TextUnmarshaler:
+		if v.Kind() == reflect.String {
+			return p.UnmarshalText(b)
+		}
+		return setValueReflect(v, b)
+	default:
+		return setValueReflect(v, b)
+	}
+
+	return nil
+}
+
+// setValueReflect sets v to the value in b for types other than those of the GS2 type.
+func setValueReflect(v reflect.Value, b []byte) error {
+	switch v.Kind() {
 	case reflect.String:
-		indirect.SetString(string(value))
+		v.SetString(string(b))
 	case reflect.Int:
-		pi, err := strconv.ParseInt(string(value), 10, 64)
+		pi, err := strconv.ParseInt(string(b), 10, 64)
 		if err != nil {
 			return err
 		}
-		indirect.SetInt(pi)
-	case reflect.Int64:
-		switch indirect.Type() {
-		case reflect.TypeOf((*time.Duration)(nil)).Elem():
-			d, err := parseDuration(string(value))
-			if err != nil {
-				return err
-			}
-			indirect.Set(reflect.ValueOf(d))
-		default:
-			return fmt.Errorf("unsupported type %q", indirect.Type().Name())
-		}
+		v.SetInt(pi)
 	case reflect.Float64:
-		pf, err := strconv.ParseFloat(string(value), 64)
+		pf, err := parseFloat(b)
 		if err != nil {
 			return err
 		}
-		indirect.SetFloat(pf)
-	case reflect.Struct:
-		switch indirect.Type() {
-		case reflect.TypeOf((*time.Time)(nil)).Elem():
-			t, err := parseTime(string(value))
-			if err != nil {
-				return err
-			}
-			indirect.Set(reflect.ValueOf(t))
-		case reflect.TypeOf((*Triplet)(nil)).Elem():
-			t, err := parseTriplet(string(value))
-			if err != nil {
-				return err
-			}
-			indirect.Set(reflect.ValueOf(t))
-		default:
-			return fmt.Errorf("unsupported type %q", indirect.Type().Name())
-		}
+		v.SetFloat(pf)
 	default:
-		return fmt.Errorf("unsupported type %q", indirect.Type().Name())
+		return fmt.Errorf("unsupported type %q", v.Type().Name())
 	}
 
 	return nil
 }
 
 func (d *Decoder) array(v reflect.Value) error {
+	p, isTriplets := v.Addr().Interface().(*[]Triplet)
+	if !isTriplets {
+		d.scanWhile(scanArrayStart)
+		return fmt.Errorf("unsupported type %q for arrays", v.Type().Elem())
+	}
+
+	triplets, err := d.triplets()
+	*p = triplets
+	return err
+}
+
+// triplets scans the next value, which is an array of triplets.
+func (d *Decoder) triplets() ([]Triplet, error) {
 	dataStart := d.bytesRead
 
 	// Scan for the start of an array
 	d.scanWhile(scanArrayStart)
 
-	var value []byte
+	// Allocate the values at once.
+	n := countValues(d.buf[d.bytesRead:])
+	if max := d.options.limits.MaxValues; max > 0 && n > max+1 {
+		n = max + 1
+	}
+	triplets := make([]Triplet, 0, n)
+
+	d.tok.reset()
 loop:
 	for {
 		d.scanNext()
 
 		switch d.lastScanState {
 		case scanContinue:
-			value = append(value, d.lastByteRead)
+			d.tok.add(d.buf, d.bytesRead-1)
+			if err := d.exceeds(LimitValueLength, d.options.limits.MaxValueLength, d.tok.len()); err != nil {
+				return triplets, err
+			}
 		case scanArrayEnd:
 			fallthrough
 		case scanArraySeparator:
-			if len(value) > 0 {
-				indirect := reflect.Indirect(v)
-				switch indirect.Type().Elem().Kind() {
-				case reflect.Struct:
-					switch indirect.Type().Elem() {
-					case reflect.TypeOf((*Triplet)(nil)).Elem():
-						trip, err := parseTriplet(string(value))
-						if err != nil {
-							return err
-						}
-						indirect.Set(reflect.Append(indirect, reflect.ValueOf(trip)))
-					default:
-						return fmt.Errorf("unsupported type %q for arrays", indirect.Type().Elem())
+			if d.tok.len() > 0 {
+				trip, err := parseTripletBytes(d.tok.bytes(d.buf))
+				if err != nil {
+					return triplets, err
+				}
+				triplets = append(triplets, trip)
+				if err := d.exceeds(LimitValues, d.options.limits.MaxValues, len(triplets)); err != nil {
+					return triplets, err
+				}
+				if len(triplets)%cancelCheckInterval == 0 {
+					if err := d.ctx.Err(); err != nil {
+						return triplets, err
 					}
-				default:
-					return fmt.Errorf("unsupported type %q for arrays", indirect.Type().Elem())
 				}
-				value = value[:0]
+				d.tok.reset()
 			}
 		case scanSkipSpace:
 		case scanHash:
 			break loop
 		default:
-			return fmt.Errorf("unexpected state while decoding value: %s", string(d.buf[dataStart:d.bytesRead]))
+			return triplets, fmt.Errorf("unexpected state while decoding value: %s", string(d.buf[dataStart:d.bytesRead]))
 		}
 	}
 
-	return nil
+	return triplets, nil
 }
 
-func (d *Decoder) fillBuffer() (err error) {
-	d.buf, err = ioutil.ReadAll(d.rdr)
-	return
+// countValues returns the number of values in the array at the start of b.
+func countValues(b []byte) int {
+	n := 0
+	inValue := false
+	for _, c := range b {
+		if c == '>' {
+			break
+		}
+
+		space := unicode.IsSpace(rune(c))
+		if !space && !inValue {
+			n++
+		}
+		inValue = !space
+	}
+
+	return n
+}
+
+// fillBuffer reads the input into the buffer, reusing it if the Decoder was reset.
+func (d *Decoder) fillBuffer() error {
+	r := d.rdr
+	max := d.options.limits.MaxBytes
+	if max > 0 {
+		// Read one byte more than the limit to tell whether the input exceeds it.
+		r = io.LimitReader(r, int64(max)+1)
+	}
+
+	if l, ok := d.rdr.(interface{ Len() int }); ok && cap(d.buf) < l.Len()+1 {
+		d.buf = make([]byte, 0, l.Len()+1)
+	}
+
+	d.buf = d.buf[:0]
+	for {
+		if len(d.buf) == cap(d.buf) {
+			d.buf = append(d.buf, 0)[:len(d.buf)]
+		}
+
+		n, err := r.Read(d.buf[len(d.buf):cap(d.buf)])
+		d.buf = d.buf[:len(d.buf)+n]
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if max > 0 && len(d.buf) > max {
+		d.bytesRead = len(d.buf)
+		return d.exceeds(LimitBytes, max, len(d.buf))
+	}
+
+	return nil
 }
 
 func (d *Decoder) scanNext() {
@@ -356,7 +681,7 @@ func (d *Decoder) scanNext() {
 		d.lastScanState = scanEnd
 		return
 	}
-	d.lastScanState = d.scan.step(d.scan, d.buf[d.bytesRead])
+	d.lastScanState = d.scan.step(&d.scan, d.buf[d.bytesRead])
 	d.lastByteRead = d.buf[d.bytesRead]
 	d.bytesRead++
 }
@@ -370,7 +695,12 @@ func (d *Decoder) scanWhile(state int) {
 	}
 }
 
+// peek returns the byte n bytes ahead, or the last byte if the input ends before it. It returns 0 if the input is empty.
 func (d *Decoder) peek(n int) byte {
+	if len(d.buf) == 0 {
+		return 0
+	}
+
 	var bytesRead = d.bytesRead + n
 	if bytesRead >= len(d.buf) {
 		bytesRead = len(d.buf) - 1
@@ -396,85 +726,336 @@ func (d *Decoder) skipAttribute() {
 	d.scanWhile(scanHash)
 }
 
-func (d *Decoder) getField(key string, typ reflect.Type) (int, bool) {
-	cachedTyp, isCached := d.typeCache[typ]
-	if isCached {
-		index, exists := cachedTyp[key]
-		if exists {
-			return index, true
+// maxInterned is the number of distinct strings a Decoder shares between the objects it decodes.
+const maxInterned = 1024
+
+// arenaSize is the size of the blocks that the strings of a Decoder are copied to. Strings longer than a quarter of it get
+// their own allocation, so little of a block is left unused.
+const arenaSize = 1024
+
+// intern returns b as a string, sharing the strings of values that repeat, like references and units.
+func (d *Decoder) intern(b []byte) string {
+	if s, ok := d.strings[string(b)]; ok {
+		return s
+	}
+
+	s := d.copyString(b)
+	if len(d.strings) < maxInterned {
+		if d.strings == nil {
+			d.strings = make(map[string]string, 16)
 		}
+		d.strings[s] = s
 	}
 
-	for i := 0; i < typ.NumField(); i++ {
-		tag := typ.Field(i).Tag.Get("gs2")
-		if strings.EqualFold(key, strings.Split(tag, ",")[0]) {
-			if isCached {
-				cachedTyp[key] = i
-			} else {
-				m := make(map[string]int)
-				m[key] = i
-				d.typeCache[typ] = m
-			}
+	return s
+}
+
+// copyString returns a copy of b. Short strings are copied to a block of arenaSize bytes shared with the following strings,
+// instead of being allocated one by one. The strings refer to the block, so bytes written to it are never changed, and a new
+// block is started when it is full.
+func (d *Decoder) copyString(b []byte) string {
+	if len(b) > arenaSize/4 {
+		return string(b)
+	}
+
+	if d.arena.Cap()-d.arena.Len() < len(b) {
+		d.arena = strings.Builder{}
+		d.arena.Grow(arenaSize)
+	}
+
+	n := d.arena.Len()
+	d.arena.Write(b)
+	return d.arena.String()[n:]
+}
+
+// token is the name or value being scanned. It refers to the input as long as its bytes are contiguous, and is copied to
+// scratch otherwise, e.g. when a value is broken by a newline.
+type token struct {
+	start, end int
+	scratch    []byte
+	copied     bool
+}
+
+func (t *token) reset() {
+	t.start, t.end, t.copied = 0, 0, false
+	t.scratch = t.scratch[:0]
+}
+
+// add adds the byte at offset i of buf.
+func (t *token) add(buf []byte, i int) {
+	switch {
+	case t.copied:
+		t.scratch = append(t.scratch, buf[i])
+	case t.start == t.end:
+		t.start, t.end = i, i+1
+	case t.end == i:
+		t.end++
+	default:
+		t.scratch = append(append(t.scratch[:0], buf[t.start:t.end]...), buf[i])
+		t.copied = true
+	}
+}
+
+func (t *token) len() int {
+	if t.copied {
+		return len(t.scratch)
+	}
+
+	return t.end - t.start
+}
+
+// bytes returns the token. It is only valid until the token is reset.
+func (t *token) bytes(buf []byte) []byte {
+	if t.copied {
+		return t.scratch
+	}
+
+	return buf[t.start:t.end]
+}
+
+// fields are the fields of a struct type by the names in their gs2 tags.
+type fields struct {
+	index map[string]int // Index of the first field with each name.
+	names []string       // Names by field index.
+}
+
+// fieldCache holds the fields of the types decoded by all Decoders.
+var fieldCache sync.Map // map[reflect.Type]*fields
+
+func cachedFields(typ reflect.Type) *fields {
+	if f, ok := fieldCache.Load(typ); ok {
+		return f.(*fields)
+	}
+
+	f := &fields{index: make(map[string]int), names: make([]string, typ.NumField())}
+	for i := range f.names {
+		f.names[i] = tagName(typ.Field(i))
+		if _, exists := f.index[f.names[i]]; !exists {
+			f.index[f.names[i]] = i
+		}
+	}
+
+	cached, _ := fieldCache.LoadOrStore(typ, f)
+	return cached.(*fields)
+}
 
+// lookup returns the index of the field named name, ignoring case.
+func (f *fields) lookup(name []byte) (int, bool) {
+	if i, ok := f.index[string(name)]; ok {
+		return i, true
+	}
+
+	for i, n := range f.names {
+		if bytes.EqualFold(name, []byte(n)) {
 			return i, true
 		}
 	}
+
 	return 0, false
 }
 
 func parseTriplet(val string) (Triplet, error) {
-	split := strings.Split(val, "/")
+	return parseTripletBytes([]byte(val))
+}
 
-	var v float64
-	var t time.Time
-	var q string
+// parseTripletBytes parses a triplet on the form value/time/quality, where all parts are optional. Parts after the third are
+// ignored.
+func parseTripletBytes(b []byte) (Triplet, error) {
+	var parts [3][]byte
+	for i := range parts {
+		end := bytes.IndexByte(b, '/')
+		if end < 0 {
+			parts[i] = b
+			break
+		}
+		parts[i], b = b[:end], b[end+1:]
+	}
+
+	var t Triplet
 	var err error
 
-	if len(split) > 0 && split[0] != "" {
-		v, err = strconv.ParseFloat(split[0], 64)
-		if err != nil {
+	if len(parts[0]) > 0 {
+		if t.Value, err = parseFloat(parts[0]); err != nil {
 			return Triplet{}, err
 		}
 	}
 
-	if len(split) > 1 && split[1] != "" {
-		t, err = parseTime(split[1])
-		if err != nil {
+	if len(parts[1]) > 0 {
+		if t.Time, err = parseTimeBytes(parts[1]); err != nil {
 			return Triplet{}, err
 		}
 	}
 
-	if len(split) > 2 && split[2] != "" {
-		q = split[2]
+	t.Quality = parseQualityBytes(parts[2])
+
+	return t, nil
+}
+
+// parseQualityBytes is ParseQuality for bytes, which only allocates for unknown quality codes.
+func parseQualityBytes(b []byte) Quality {
+	for _, q := range qualities {
+		if len(q) == len(b) && bytes.EqualFold(b, []byte(q)) {
+			return q
+		}
 	}
 
-	return Triplet{
-		Value:   v,
-		Time:    t,
-		Quality: q,
-	}, nil
+	return Quality(b)
+}
+
+// maxExactMantissa and maxExactExponent bound the decimals that parseFloat converts exactly: both the digits and the power
+// of ten are exact float64 values, so a single division is correctly rounded.
+const (
+	maxExactMantissa = 1<<53 - 1
+	maxExactExponent = 22
+)
+
+var exactPowersOfTen = [...]float64{1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11, 1e12, 1e13, 1e14, 1e15, 1e16,
+	1e17, 1e18, 1e19, 1e20, 1e21, 1e22}
+
+// parseFloat parses a float without converting b to a string. Plain decimals, like -12.345, are converted directly, and other
+// forms are left to strconv.ParseFloat, which also gives the errors.
+func parseFloat(b []byte) (float64, error) {
+	s := b
+	neg := len(s) > 0 && s[0] == '-'
+	if neg {
+		s = s[1:]
+	}
+
+	var mantissa uint64
+	digits, decimals := 0, -1
+	for i, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			mantissa = mantissa*10 + uint64(c-'0')
+			digits++
+			if decimals >= 0 {
+				decimals++
+			}
+			if mantissa > maxExactMantissa {
+				return strconv.ParseFloat(string(b), 64)
+			}
+		case c == '.' && decimals < 0 && i > 0:
+			decimals = 0
+		default:
+			return strconv.ParseFloat(string(b), 64)
+		}
+	}
+
+	if digits == 0 || decimals == 0 || decimals > maxExactExponent {
+		return strconv.ParseFloat(string(b), 64)
+	}
+
+	f := float64(mantissa)
+	if decimals > 0 {
+		f /= exactPowersOfTen[decimals]
+	}
+	if neg {
+		f = -f
+	}
+
+	return f, nil
 }
 
 func parseTime(s string) (time.Time, error) {
-	if s == "" {
+	return parseTimeBytes([]byte(s))
+}
+
+// parseTimeBytes parses a time on the form 2006-01-02.15:04:05 without converting b to a string. 24:00:00 is midnight at the
+// end of the day. Times that aren't on this exact form are left to time.ParseInLocation, which also gives the errors.
+func parseTimeBytes(b []byte) (time.Time, error) {
+	if len(b) == 0 {
 		return time.Time{}, nil
 	}
 
+	if len(b) == len(gs2TimeLayout) && b[4] == '-' && b[7] == '-' && b[10] == '.' && b[13] == ':' && b[16] == ':' {
+		year, ok1 := digits(b[0:4])
+		month, ok2 := digits(b[5:7])
+		day, ok3 := digits(b[8:10])
+		hour, ok4 := digits(b[11:13])
+		min, ok5 := digits(b[14:16])
+		sec, ok6 := digits(b[17:19])
+
+		midnight := hour == 24 && min == 0 && sec == 0
+		if ok1 && ok2 && ok3 && ok4 && ok5 && ok6 && month >= 1 && month <= 12 && day >= 1 && (hour < 24 || midnight) && min < 60 && sec < 60 {
+			if midnight {
+				hour = 0
+			}
+			t := time.Date(year, time.Month(month), day, hour, min, sec, 0, time.UTC)
+			// time.Date normalizes days past the end of the month, which are errors.
+			if t.Day() == day {
+				if midnight {
+					t = t.Add(24 * time.Hour)
+				}
+				return t, nil
+			}
+		}
+	}
+
+	return parseTimeString(string(b))
+}
+
+// digits parses b, which only has decimal digits.
+func digits(b []byte) (int, bool) {
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+
+	return n, true
+}
+
+// parseTimeString parses times that aren't on the exact form handled by parseTimeBytes.
+func parseTimeString(s string) (time.Time, error) {
+	// 24:00:00 is midnight at the end of the day.
 	var modifier time.Duration
-	if strings.Contains(s, "24:00:00") {
-		s = strings.Replace(s, "24:00:00", "00:00:00", 1)
+	if strings.HasSuffix(s, ".24:00:00") {
+		s = strings.TrimSuffix(s, "24:00:00") + "00:00:00"
 		modifier = 24 * time.Hour
 	}
 
 	t, err := time.ParseInLocation(gs2TimeLayout, s, time.UTC)
+	if err == nil && t.Nanosecond() != 0 {
+		// time.ParseInLocation accepts fractional seconds after the seconds, which GS2 times don't have.
+		return time.Time{}, fmt.Errorf("parsing time %q: fractional seconds are not supported", s)
+	}
 	return t.Add(modifier), err
 }
 
+// parseDuration parses a step on the form 0000-00-dd.hh:mm:ss. Days are 24 hours. Steps of months or years can't be represented
+// as a time.Duration, and are an error.
 func parseDuration(s string) (time.Duration, error) {
 	split := strings.Split(s, ".")
-	tp := strings.Split(split[1], ":")
+	if len(split) != 2 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
 
 	var duration time.Duration = 0
+
+	if date := strings.Split(split[0], "-"); len(date) == 3 {
+		for i, datePart := range date[:2] {
+			if n, err := strconv.Atoi(datePart); err != nil || n != 0 {
+				if err != nil {
+					return 0, err
+				}
+				return 0, fmt.Errorf("unsupported duration %q: steps of %s can't be represented", s, [...]string{"years", "months"}[i])
+			}
+		}
+
+		days, err := strconv.Atoi(date[2])
+		if err != nil {
+			return 0, err
+		}
+		duration += 24 * time.Hour * time.Duration(days)
+	}
+
+	tp := strings.Split(split[1], ":")
+	if len(tp) > 3 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
 	var durationUnits = [...]time.Duration{time.Hour, time.Minute, time.Second}
 
 	for i, timePart := range tp {
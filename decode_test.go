@@ -1,8 +1,14 @@
 package gs2
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -25,34 +31,336 @@ func TestDecoder_Decode(t *testing.T) {
 	}
 }
 
-// This is probably stupid since we're also reading the file and stuff. Maybe there is some other way to benchmark?
-func benchmarkDecoderDecode(fileName string, b *testing.B) {
-	for n := 0; n < b.N; n++ {
-		file, err := os.Open(fileName)
+func TestDecoder_BlockLine(t *testing.T) {
+	file, err := os.Open("testdata/timeseries.gs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDecoder(file)
+	if _, err := d.Decode(); err != nil {
+		t.Fatalf("unexpected error when decoding: %v", err)
+	}
+
+	for _, test := range []struct {
+		name  string
+		index int
+		line  int
+	}{
+		{"Start-message", 0, 1},
+		{"Time-series", 0, 10},
+		{"Time-series", 1, 22},
+		{"Time-series", 4, 82},
+		{"End-message", 0, 99},
+		{"Time-series", 5, 0},
+		{"Non-existing-block", 0, 0},
+	} {
+		if line := d.BlockLine(test.name, test.index); line != test.line {
+			t.Errorf("expected %s %d to start at line %d, but got %d", test.name, test.index, test.line, line)
+		}
+	}
+}
+
+func TestDecoder_SyntaxError(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		line  int
+	}{
+		{"##Start-message\n#Id=0\n#GMT-reference=x\n", 3},
+		{"##Start-message\n#Id=0\n\n##Time-series\n#Value=< 1 a 3 >\n", 5},
+		{"##Start-message#Id=0#Number-of-objects=1.5", 1},
+	} {
+		_, err := NewDecoder(strings.NewReader(test.input)).Decode()
+
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Fatalf("expected a SyntaxError when decoding %q, but got %v", test.input, err)
+		}
+
+		if syntaxErr.Line != test.line {
+			t.Errorf("expected error at line %d, but got %d", test.line, syntaxErr.Line)
+		}
+	}
+}
+
+// fuzzSeeds are inputs the fuzz targets start from.
+var fuzzSeeds = []string{
+	"",
+	"#",
+	"##",
+	"##Start-message\n#Step=0000-00-00.01:00:00",
+	"##Time-series\n#Value=< 1/2020-01-01.24:00:00/0 2//x >",
+	"##Time-series\n#Step=0000-00-00",
+	"##Time-series\n#Value=<",
+	"##Meter-reading\n#Value=1/24:00:00/",
+}
+
+func FuzzDecoder_Decode(f *testing.F) {
+	for _, name := range []string{"testdata/timeseries.gs2", "testdata/meterreading.gs2", "testdata/durations.gs2"} {
+		data, err := os.ReadFile(name)
 		if err != nil {
-			b.Fatal(err)
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		g, err := NewDecoder(bytes.NewReader(data)).Decode()
+		if (g == nil) == (err == nil) {
+			t.Fatalf("expected either a result or an error, but got %v and %v", g, err)
+		}
+	})
+}
+
+func FuzzParseTriplet(f *testing.F) {
+	for _, seed := range []string{"", "1", "1//", "1.5/2020-01-01.00:00:00/0", "/2020-01-01.24:00:00/", "a/b/c/d"} {
+		f.Add(seed)
+	}
+
+	// A parsed triplet is written by formatTriplet as it was parsed.
+	f.Fuzz(func(t *testing.T, s string) {
+		triplet, err := parseTriplet(s)
+		if err != nil {
+			return
+		}
+
+		formatted := formatTriplet(triplet, -1)
+		got, err := parseTriplet(formatted)
+		if err != nil {
+			t.Fatalf("parseTriplet(%q) = %v, but formatted as %q it fails: %v", s, triplet, formatted, err)
+		}
+		sameValue := math.Float64bits(got.Value) == math.Float64bits(triplet.Value) || math.IsNaN(got.Value) && math.IsNaN(triplet.Value)
+		if !sameValue || !got.Time.Equal(triplet.Time) || got.Quality != triplet.Quality {
+			t.Errorf("parseTriplet(%q) = %v, but formatted as %q it is %v", s, triplet, formatted, got)
+		}
+	})
+}
+
+func FuzzParseFloat(f *testing.F) {
+	for _, seed := range []string{"", "0", "-0", "1.5", "-12.345", ".5", "5.", "1e3", "+1", "0.1", "123456789012345678", "0.000000000000000000000001", "NaN"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		expected, expectedErr := strconv.ParseFloat(s, 64)
+		got, err := parseFloat([]byte(s))
+		if math.Float64bits(got) != math.Float64bits(expected) && !(math.IsNaN(got) && math.IsNaN(expected)) || !reflect.DeepEqual(err, expectedErr) {
+			t.Errorf("parseFloat(%q) = %v, %v, but strconv gives %v, %v", s, got, err, expected, expectedErr)
+		}
+	})
+}
+
+func FuzzParseTime(f *testing.F) {
+	for _, seed := range []string{"", "2020-01-01.00:00:00", "2020-02-29.24:00:00", "2019-02-29.12:00:00", "2020-13-01.00:00:00",
+		"2020-01-01.24:00:01", "2020-01-01.00:00:00.5", "0000-01-01.00:00:00", "2020-01-01T00:00:00"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		expected, expectedErr := parseTimeString(s)
+		if s == "" {
+			expected, expectedErr = time.Time{}, nil
+		}
+		got, err := parseTimeBytes([]byte(s))
+		if !got.Equal(expected) || !reflect.DeepEqual(err, expectedErr) {
+			t.Errorf("parseTimeBytes(%q) = %v, %v, but expected %v, %v", s, got, err, expected, expectedErr)
+		}
+	})
+}
+
+func TestDecoder_Reset(t *testing.T) {
+	dec := NewDecoder(nil)
+	for _, test := range decodeTestTable {
+		data, err := os.ReadFile(test.inputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dec.Reset(bytes.NewReader(data))
+		result, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("unexpected error decoding %s after a reset: %v", test.inputFile, err)
+		}
+		if !reflect.DeepEqual(*result, test.expected) {
+			t.Errorf("expected %+v for %s after a reset, but got %+v", test.expected, test.inputFile, *result)
+		}
+		if line := dec.BlockLine("End-message", 0); line == 0 || dec.BlockLine("End-message", 1) != 0 {
+			t.Errorf("expected the lines of one End-message in %s, but got %d", test.inputFile, line)
 		}
+	}
+}
+
+func TestParseTime_Midnight(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		expected time.Time
+		err      bool
+	}{
+		{"2020-03-27.24:00:00", getTime("2020-03-28T00:00:00Z"), false},
+		{"24:00:00", time.Time{}, true},
+		{"2024:00:00.00:00:00", time.Time{}, true},
+		{"2020-03-27.24:00:01", time.Time{}, true},
+		{"2020-03-27.12:00:00.5", time.Time{}, true},
+		{"2020-03-27.0:00:00,1", time.Time{}, true},
+	} {
+		tm, err := parseTime(test.s)
+		if (err != nil) != test.err || (!test.err && !tm.Equal(test.expected)) {
+			t.Errorf("parseTime(%q) = %v, %v", test.s, tm, err)
+		}
+	}
+}
+
+func TestDecoder_Peek(t *testing.T) {
+	d := NewDecoder(bytes.NewReader(nil))
+	if b := d.peek(0); b != 0 {
+		t.Errorf("expected 0 for empty input, but got %q", b)
+	}
+}
+
+// cancelAfter is a context that is cancelled after its Err method has been called n times.
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+
+	return nil
+}
+
+// longTimeSeries returns a GS2 object with a time series of n hourly values.
+func longTimeSeries(n int) *GS2 {
+	start := getTime("2020-01-01T00:00:00Z")
+	values := make([]Triplet, n)
+	for i := range values {
+		values[i] = Triplet{Value: 1}
+	}
+
+	return &GS2{
+		StartMessage: StartMessage{ID: "1", MessageType: MessageTypeSettlementData, Version: "1.2", Time: start, To: "to", From: "from"},
+		TimeSeries: []TimeSeries{{Reference: "mp1", Start: start, Stop: start.Add(time.Duration(n) * time.Hour), Step: time.Hour,
+			Unit: "kWh", Value: values, NoOfValues: n, Sum: float64(n)}},
+		EndMessage: EndMessage{ID: "1", NumberOfObjects: 3},
+	}
+}
+
+func TestDecoder_DecodeContext(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(longTimeSeries(5000)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewDecoder(bytes.NewReader(buf.Bytes())).DecodeContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, but got %v", err)
+	}
+
+	// Cancelled within the values of the time series, after the checks before each of the three blocks.
+	if _, err := NewDecoder(bytes.NewReader(buf.Bytes())).DecodeContext(&cancelAfter{context.Background(), 3}); err != context.Canceled {
+		t.Errorf("expected context.Canceled within the array, but got %v", err)
+	}
+
+	type key struct{}
+	var got interface{}
+	dec := NewDecoder(bytes.NewReader(buf.Bytes()), DecodeContextValidators(func(ctx context.Context, g *GS2) error {
+		got = ctx.Value(key{})
+		return nil
+	}))
+	if _, err := dec.DecodeContext(context.WithValue(context.Background(), key{}, "value")); err != nil || got != "value" {
+		t.Errorf("expected the context validator to get the context, but got %v, %v", got, err)
+	}
+}
 
-		if _, err := NewDecoder(file, DecodeValidators()).Decode(); err != nil {
+func TestParseDuration_Malformed(t *testing.T) {
+	for _, s := range []string{"", ".", "0000-00-00", "0000-00-00.", "0000-00-00.01:00:00:00", "0000-00-xx.01:00:00", "0001-00-00.00:00:00"} {
+		if d, err := parseDuration(s); err == nil {
+			t.Errorf("expected an error for %q, but got %v", s, d)
+		}
+	}
+}
+
+// benchmarkDecoderDecode decodes data, which is read up front, so only decoding is measured.
+func benchmarkDecoderDecode(data []byte, b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if _, err := NewDecoder(bytes.NewReader(data), DecodeValidators()).Decode(); err != nil {
 			b.Fatal(err)
 		}
 	}
 }
 
+func readFile(b *testing.B, name string) []byte {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return data
+}
+
+func encodeLarge(b *testing.B, g *GS2) []byte {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, EncodeValidators()).Encode(g); err != nil {
+		b.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
 func BenchmarkDecoder_SmallMeterReadingFile(b *testing.B) {
-	benchmarkDecoderDecode("testdata/meterreading.gs2", b)
+	benchmarkDecoderDecode(readFile(b, "testdata/meterreading.gs2"), b)
 }
 
 func BenchmarkDecoder_LargeMeterReadingFile(b *testing.B) {
-	benchmarkDecoderDecode("testdata/meterreading_large.gs2", b)
+	start := getTime("2020-01-01T00:00:00Z")
+	g := &GS2{StartMessage: StartMessage{ID: "1", MessageType: MessageTypeSettlementData, Version: "1.2", Time: start}}
+	for i := 0; i < 10000; i++ {
+		g.MeterReadings = append(g.MeterReadings, MeterReading{Reference: "mp" + strconv.Itoa(i%100), Meter: "m1", Unit: "kWh",
+			Time: start.Add(time.Duration(i) * time.Hour), Value: Triplet{Value: float64(i) + 0.25, Quality: QualityOK}})
+	}
+
+	benchmarkDecoderDecode(encodeLarge(b, g), b)
 }
 
 func BenchmarkDecoder_SmallTimeSeriesFile(b *testing.B) {
-	benchmarkDecoderDecode("testdata/timeseries.gs2", b)
+	benchmarkDecoderDecode(readFile(b, "testdata/timeseries.gs2"), b)
 }
 
 func BenchmarkDecoder_LargeTimeSeriesFile(b *testing.B) {
-	benchmarkDecoderDecode("testdata/timeseries_large.gs2", b)
+	g := longTimeSeries(1000)
+	for i := 0; i < 99; i++ {
+		g.TimeSeries = append(g.TimeSeries, g.TimeSeries[0])
+	}
+
+	benchmarkDecoderDecode(encodeLarge(b, g), b)
+}
+
+func BenchmarkDecoder_Reset(b *testing.B) {
+	data := readFile(b, "testdata/timeseries.gs2")
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	dec := NewDecoder(nil, DecodeValidators())
+	r := bytes.NewReader(data)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		r.Reset(data)
+		dec.Reset(r)
+		if _, err := dec.Decode(); err != nil {
+			b.Fatal(err)
+		}
+	}
 }
 
 var decodeTestTable = []struct {
@@ -1,6 +1,9 @@
 package gs2
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding"
 	"fmt"
 	"io"
 	"reflect"
@@ -9,17 +12,29 @@ import (
 	"time"
 )
 
-// Encoder encodes a GS2 object and writes to an io.Writer. NB: year, month and day is not supported in Step attribute. Only hour,
-//minute and seconds are used when encoding duration.
+// Encoder encodes a GS2 object and writes to an io.Writer. Steps are written as days, hours, minutes and seconds, never as
+// months or years.
 type Encoder struct {
 	options encoderOptions
+	ctx     context.Context
 	w       io.Writer
 	buf     []byte
+	loc     *time.Location
+
+	// messageType is the Message-type of the object being encoded, which decides the mandatory attributes.
+	messageType string
+
+	attr AttributeEncoder
+	gz   *gzip.Writer
 }
 
 type encoderOptions struct {
-	floatPrecision int
-	validators     []Validator
+	floatPrecision    int
+	validators        []Validator
+	contextValidators []ContextValidator
+	canonical         bool
+	gzip              bool
+	gzipLevel         int
 }
 
 var defaultEncoderOptions = encoderOptions{
@@ -48,6 +63,38 @@ func EncodeValidators(v ...Validator) EncoderOption {
 	}
 }
 
+// EncodeAppendValidators adds validators to be run before encoding an object, keeping the default ones.
+func EncodeAppendValidators(v ...Validator) EncoderOption {
+	return func(o *encoderOptions) {
+		o.validators = append(append([]Validator(nil), o.validators...), v...)
+	}
+}
+
+// EncodeContextValidators sets the validators to be run with the context after the other validators. There are none by default.
+func EncodeContextValidators(v ...ContextValidator) EncoderOption {
+	return func(o *encoderOptions) {
+		o.contextValidators = v
+	}
+}
+
+// EncodeCanonical makes the Encoder write GS2 objects in canonical form, see Format. Floats are written in their shortest form
+// regardless of EncodeFloatPrecision, and times are written in the time zone given by the GMT-reference of the StartMessage,
+// so decoding the output gives back the same object.
+func EncodeCanonical() EncoderOption {
+	return func(o *encoderOptions) {
+		o.canonical = true
+	}
+}
+
+// EncodeGzip makes the Encoder compress its output with gzip at the given level, e.g. gzip.DefaultCompression. Each GS2
+// object is written as a complete gzip stream.
+func EncodeGzip(level int) EncoderOption {
+	return func(o *encoderOptions) {
+		o.gzip = true
+		o.gzipLevel = level
+	}
+}
+
 // NewEncoder returna a new Encoder writing to w.
 func NewEncoder(w io.Writer, opt ...EncoderOption) *Encoder {
 	opts := defaultEncoderOptions
@@ -56,20 +103,52 @@ func NewEncoder(w io.Writer, opt ...EncoderOption) *Encoder {
 		o(&opts)
 	}
 
+	if opts.canonical {
+		opts.floatPrecision = -1
+	}
+
 	return &Encoder{
 		options: opts,
+		ctx:     context.Background(),
 		w:       w,
 	}
 }
 
 // Encode encodes and writes a GS2 object to an io.Writer.
 func (e *Encoder) Encode(g *GS2) error {
+	return e.EncodeContext(context.Background(), g)
+}
+
+// EncodeContext is like Encode, but stops with the error of ctx when ctx is done. Cancellation is checked between validators,
+// between blocks and within long arrays, and ctx is given to the validators set with EncodeContextValidators. Nothing is
+// written if ctx is done before the output is complete.
+func (e *Encoder) EncodeContext(ctx context.Context, g *GS2) error {
+	e.ctx = ctx
+	e.buf = e.buf[:0]
+
 	for _, validator := range e.options.validators {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := validator(g); err != nil {
 			return err
 		}
 	}
 
+	for _, validator := range e.options.contextValidators {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := validator(ctx, g); err != nil {
+			return err
+		}
+	}
+
+	if e.options.canonical {
+		e.loc = g.StartMessage.Location()
+	}
+	e.messageType = g.StartMessage.MessageType
+
 	return e.encode(reflect.ValueOf(g))
 }
 
@@ -82,18 +161,23 @@ func (e *Encoder) encode(v reflect.Value) error {
 			return fmt.Errorf("type %s does not have a gs2 tag defined", indirect.Type().Field(i).Type)
 		}
 
+		schema, _ := LookupSchema(blockName)
+
 		field := indirect.Field(i)
 		if field.Kind() == reflect.Slice {
 			for j := 0; j < field.Len(); j++ {
+				if err := e.ctx.Err(); err != nil {
+					return err
+				}
 				e.write([]byte("##" + blockName + "\n"))
-				if err := e.block(field.Index(j)); err != nil {
+				if err := e.block(field.Index(j), schema); err != nil {
 					return err
 				}
 				e.write([]byte("\n"))
 			}
 		} else {
 			e.write([]byte("##" + blockName + "\n"))
-			if err := e.block(field); err != nil {
+			if err := e.block(field, schema); err != nil {
 				return err
 			}
 
@@ -103,6 +187,10 @@ func (e *Encoder) encode(v reflect.Value) error {
 		}
 	}
 
+	if e.options.gzip {
+		return e.writeGzip()
+	}
+
 	if _, err := e.w.Write(e.buf); err != nil {
 		return err
 	}
@@ -110,7 +198,36 @@ func (e *Encoder) encode(v reflect.Value) error {
 	return nil
 }
 
-func (e *Encoder) block(v reflect.Value) error {
+// writeGzip writes the buffer compressed with gzip.
+func (e *Encoder) writeGzip() error {
+	if e.gz == nil {
+		gz, err := gzip.NewWriterLevel(e.w, e.options.gzipLevel)
+		if err != nil {
+			return err
+		}
+		e.gz = gz
+	} else {
+		e.gz.Reset(e.w)
+	}
+
+	if _, err := e.gz.Write(e.buf); err != nil {
+		return err
+	}
+
+	return e.gz.Close()
+}
+
+// block writes the attributes of v. Empty attributes are left out, except mandatory attributes where zero is a value, like
+// No-of-values. Attributes that aren't in schema are left out when empty if their tag has omitempty.
+func (e *Encoder) block(v reflect.Value, schema BlockSchema) error {
+	// Blocks with generated code encode their own attributes.
+	if useCodecs && v.CanAddr() {
+		if m, ok := v.Addr().Interface().(Marshaler); ok {
+			e.attr = AttributeEncoder{e: e, schema: schema}
+			return m.MarshalGS2(&e.attr)
+		}
+	}
+
 	indirect := reflect.Indirect(v)
 
 	for i := 0; i < indirect.NumField(); i++ {
@@ -128,6 +245,9 @@ func (e *Encoder) block(v reflect.Value) error {
 		}
 
 		omitempty := len(split) > 1 && split[1] == "omitempty"
+		if a, ok := schema.Attribute(attributeName); ok {
+			omitempty = !a.Required(e.messageType) || !a.Type.zeroIsValue()
+		}
 		if field.IsZero() && omitempty {
 			continue
 		}
@@ -147,6 +267,11 @@ func (e *Encoder) attribute(v reflect.Value) error {
 	if indirect.Kind() == reflect.Slice {
 		e.write([]byte("< "))
 		for j := 0; j < indirect.Len(); j++ {
+			if j > 0 && j%cancelCheckInterval == 0 {
+				if err := e.ctx.Err(); err != nil {
+					return err
+				}
+			}
 			if err := e.value(indirect.Index(j)); err != nil {
 				return err
 			}
@@ -169,6 +294,14 @@ func (e *Encoder) value(v reflect.Value) error {
 
 	switch indirect.Kind() {
 	case reflect.String:
+		if m, ok := indirect.Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			if err != nil {
+				return err
+			}
+			e.write(text)
+			return nil
+		}
 		e.write([]byte(indirect.String()))
 	case reflect.Int:
 		e.write([]byte(strconv.FormatInt(indirect.Int(), 10)))
@@ -184,11 +317,19 @@ func (e *Encoder) value(v reflect.Value) error {
 	case reflect.Struct:
 		switch indirect.Type() {
 		case reflect.TypeOf((*time.Time)(nil)).Elem():
-			e.write([]byte(indirect.Interface().(time.Time).Format(gs2TimeLayout)))
+			t := indirect.Interface().(time.Time)
+			if e.loc != nil {
+				t = t.In(e.loc)
+			}
+			e.write([]byte(formatTime(t)))
 		case reflect.TypeOf((*time.Duration)(nil)).Elem():
 			e.write([]byte(encodeDuration(indirect.Interface().(time.Duration))))
 		case reflect.TypeOf((*Triplet)(nil)).Elem():
-			e.write([]byte(e.encodeTriplet(indirect.Interface().(Triplet))))
+			t := indirect.Interface().(Triplet)
+			if e.loc != nil && !t.Time.IsZero() {
+				t.Time = t.Time.In(e.loc)
+			}
+			e.write([]byte(e.encodeTriplet(t)))
 		default:
 			return fmt.Errorf("type %s not supported", indirect.Type())
 		}
@@ -200,19 +341,32 @@ func (e *Encoder) value(v reflect.Value) error {
 }
 
 func (e *Encoder) encodeTriplet(t Triplet) string {
-	value := strconv.FormatFloat(t.Value, 'f', e.options.floatPrecision, 64)
+	return formatTriplet(t, e.options.floatPrecision)
+}
+
+func (e *Encoder) write(val []byte) {
+	e.buf = append(e.buf, val...)
+}
+
+func (e *Encoder) writeString(s string) {
+	e.buf = append(e.buf, s...)
+}
+
+func formatTriplet(t Triplet, precision int) string {
+	value := strconv.FormatFloat(t.Value, 'f', precision, 64)
 	var timePart string
 	if !reflect.ValueOf(t.Time).IsZero() {
-		timePart = t.Time.Format(gs2TimeLayout)
+		timePart = formatTime(t.Time)
 	}
 
-	return value + "/" + timePart + "/" + t.Quality
+	return value + "/" + timePart + "/" + string(t.Quality)
 }
 
-func (e *Encoder) write(val []byte) {
-	e.buf = append(e.buf, val...)
+func formatTime(t time.Time) string {
+	return t.Format(gs2TimeLayout)
 }
 
 func encodeDuration(d time.Duration) string {
-	return strings.Replace(time.Time{}.Add(d).Format(gs2TimeLayout), "0001-01-01", "0000-00-00", 1)
+	days := d / (24 * time.Hour)
+	return fmt.Sprintf("0000-00-%02d.", days) + time.Time{}.Add(d-days*24*time.Hour).Format("15:04:05")
 }
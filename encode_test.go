@@ -2,6 +2,9 @@ package gs2
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
 	"testing"
 	"time"
 )
@@ -19,6 +22,30 @@ func TestEncoder_Encode(t *testing.T) {
 	}
 }
 
+func TestEncoder_EncodeCanonical(t *testing.T) {
+	src, err := ioutil.ReadFile("testdata/durations.gs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := NewDecoder(bytes.NewReader(src)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var encoded, formatted bytes.Buffer
+	if err := NewEncoder(&encoded, EncodeCanonical(), EncodeFloatPrecision(2)).Encode(g); err != nil {
+		t.Fatalf("unexpected error when encoding: %v", err)
+	}
+	if err := Format(&formatted, src); err != nil {
+		t.Fatal(err)
+	}
+
+	if encoded.String() != formatted.String() {
+		t.Errorf("Expected:\n%s got:\n%s", formatted.String(), encoded.String())
+	}
+}
+
 var encodeTestTable = []struct {
 	g        GS2
 	expected string
@@ -231,3 +258,81 @@ var encodeTestTable = []struct {
 `,
 	},
 }
+
+func TestEncoder_EncodeContext(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, EncodeValidators())
+
+	// Cancelled within the values of the time series, after the check before the block.
+	if err := enc.EncodeContext(&cancelAfter{context.Background(), 1}, longTimeSeries(5000)); err != context.Canceled {
+		t.Errorf("expected context.Canceled, but got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be written, but got %d bytes", buf.Len())
+	}
+
+	// The Encoder can be used again after it was cancelled.
+	if err := enc.Encode(longTimeSeries(1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDecoder(&buf).Decode(); err != nil {
+		t.Errorf("expected the output to decode after a cancelled encode, but got %v", err)
+	}
+}
+
+func TestEncoder_EncodeGzip(t *testing.T) {
+	var plain, compressed bytes.Buffer
+	if err := NewEncoder(&plain, EncodeValidators()).Encode(longTimeSeries(100)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each object is a complete gzip stream, so the Encoder can be used again.
+	enc := NewEncoder(&compressed, EncodeValidators(), EncodeGzip(gzip.BestSpeed))
+	for i := 0; i < 2; i++ {
+		compressed.Reset()
+		if err := enc.Encode(longTimeSeries(100)); err != nil {
+			t.Fatal(err)
+		}
+
+		zr, err := gzip.NewReader(&compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, plain.Bytes()) {
+			t.Errorf("expected\n%s\nbut got\n%s", plain.String(), data)
+		}
+	}
+
+	if err := NewEncoder(&compressed, EncodeGzip(42)).Encode(longTimeSeries(1)); err == nil {
+		t.Error("expected an error for an invalid compression level")
+	}
+}
+
+func TestEncodeDuration(t *testing.T) {
+	for _, test := range []struct {
+		d        time.Duration
+		expected string
+	}{
+		{time.Hour, "0000-00-00.01:00:00"},
+		{15 * time.Minute, "0000-00-00.00:15:00"},
+		{24 * time.Hour, "0000-00-01.00:00:00"},
+		{49*time.Hour + 30*time.Second, "0000-00-02.01:00:30"},
+	} {
+		s := encodeDuration(test.d)
+		if s != test.expected {
+			t.Errorf("expected %v to encode as %q, but got %q", test.d, test.expected, s)
+		}
+
+		if d, err := parseDuration(s); err != nil || d != test.d {
+			t.Errorf("expected %q to decode as %v, but got %v, %v", s, test.d, d, err)
+		}
+	}
+
+	if _, err := parseDuration("0000-01-00.00:00:00"); err == nil {
+		t.Errorf("expected an error for a step of months")
+	}
+}
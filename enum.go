@@ -0,0 +1,154 @@
+package gs2
+
+import "strings"
+
+// DirectionOfFlow is the direction of the energy flow of a meter reading or time series. Values outside the GS2 vocabulary are
+// kept as they are, see Known.
+type DirectionOfFlow string
+
+// Directions of flow defined by GS2 1.2.
+const (
+	DirectionIn  DirectionOfFlow = "in"
+	DirectionOut DirectionOfFlow = "out"
+)
+
+// TypeOfValue tells how the values of a time series are measured. Values outside the GS2 vocabulary are kept as they are, see
+// Known.
+type TypeOfValue string
+
+// Types of value defined by GS2 1.2.
+const (
+	TypeInterval    TypeOfValue = "interval"    // Energy in each interval.
+	TypeAccumulated TypeOfValue = "accumulated" // Register value at the end of each interval.
+	TypeAverage     TypeOfValue = "average"     // Average over each interval.
+	TypeMomentary   TypeOfValue = "momentary"   // Value at the start of each interval.
+)
+
+// Quality is the quality code of a value triplet. Values outside the GS2 vocabulary are kept as they are, see Known.
+type Quality string
+
+// Quality codes defined by GS2 1.2. An empty quality means the value is OK.
+const (
+	QualityNone      Quality = ""
+	QualityOK        Quality = "0"
+	QualityCorrected Quality = "1"
+	QualityEstimated Quality = "2"
+	QualityMissing   Quality = "3"
+)
+
+var (
+	directionsOfFlow = []DirectionOfFlow{DirectionIn, DirectionOut}
+	typesOfValue     = []TypeOfValue{TypeInterval, TypeAccumulated, TypeAverage, TypeMomentary}
+	qualities        = []Quality{QualityNone, QualityOK, QualityCorrected, QualityEstimated, QualityMissing}
+)
+
+// ParseDirectionOfFlow returns the known direction of flow matching s regardless of case, or s as it is if it is unknown.
+func ParseDirectionOfFlow(s string) DirectionOfFlow {
+	for _, d := range directionsOfFlow {
+		if strings.EqualFold(s, string(d)) {
+			return d
+		}
+	}
+
+	return DirectionOfFlow(s)
+}
+
+// Known reports whether d is one of the directions of flow defined by GS2 1.2.
+func (d DirectionOfFlow) Known() bool {
+	for _, known := range directionsOfFlow {
+		if d == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d DirectionOfFlow) String() string {
+	return string(d)
+}
+
+// MarshalText returns d as it is, so unknown values are preserved.
+func (d DirectionOfFlow) MarshalText() ([]byte, error) {
+	return []byte(d), nil
+}
+
+// UnmarshalText sets d to the direction of flow in text, see ParseDirectionOfFlow.
+func (d *DirectionOfFlow) UnmarshalText(text []byte) error {
+	*d = ParseDirectionOfFlow(string(text))
+	return nil
+}
+
+// ParseTypeOfValue returns the known type of value matching s regardless of case, or s as it is if it is unknown.
+func ParseTypeOfValue(s string) TypeOfValue {
+	for _, t := range typesOfValue {
+		if strings.EqualFold(s, string(t)) {
+			return t
+		}
+	}
+
+	return TypeOfValue(s)
+}
+
+// Known reports whether t is one of the types of value defined by GS2 1.2.
+func (t TypeOfValue) Known() bool {
+	for _, known := range typesOfValue {
+		if t == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t TypeOfValue) String() string {
+	return string(t)
+}
+
+// MarshalText returns t as it is, so unknown values are preserved.
+func (t TypeOfValue) MarshalText() ([]byte, error) {
+	return []byte(t), nil
+}
+
+// UnmarshalText sets t to the type of value in text, see ParseTypeOfValue.
+func (t *TypeOfValue) UnmarshalText(text []byte) error {
+	*t = ParseTypeOfValue(string(text))
+	return nil
+}
+
+// ParseQuality returns the known quality code matching s regardless of case, or s as it is if it is unknown.
+func ParseQuality(s string) Quality {
+	for _, q := range qualities {
+		if strings.EqualFold(s, string(q)) {
+			return q
+		}
+	}
+
+	return Quality(s)
+}
+
+// Known reports whether q is one of the quality codes defined by GS2 1.2.
+func (q Quality) Known() bool {
+	for _, known := range qualities {
+		if q == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q Quality) String() string {
+	return string(q)
+}
+
+// MarshalText returns q as it is, so unknown values are preserved.
+func (q Quality) MarshalText() ([]byte, error) {
+	return []byte(q), nil
+}
+
+// UnmarshalText sets q to the quality code in text, see ParseQuality.
+func (q *Quality) UnmarshalText(text []byte) error {
+	*q = ParseQuality(string(text))
+	return nil
+}
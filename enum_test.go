@@ -0,0 +1,89 @@
+package gs2
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseDirectionOfFlow(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		expected DirectionOfFlow
+		known    bool
+	}{
+		{"in", DirectionIn, true},
+		{"OUT", DirectionOut, true},
+		{"Out", DirectionOut, true},
+		{"both", "both", false},
+		{"", "", false},
+	} {
+		d := ParseDirectionOfFlow(test.s)
+		if d != test.expected || d.Known() != test.known {
+			t.Errorf("%q: expected %q (known %v), but got %q (known %v)", test.s, test.expected, test.known, d, d.Known())
+		}
+	}
+}
+
+func TestParseTypeOfValue(t *testing.T) {
+	if v := ParseTypeOfValue("Interval"); v != TypeInterval || !v.Known() {
+		t.Errorf("expected %q, but got %q", TypeInterval, v)
+	}
+
+	if v := ParseTypeOfValue("vendor-specific"); v != "vendor-specific" || v.Known() {
+		t.Errorf("expected unknown value to be preserved, but got %q (known %v)", v, v.Known())
+	}
+}
+
+func TestParseQuality(t *testing.T) {
+	for _, q := range []Quality{QualityNone, QualityOK, QualityEstimated} {
+		if !ParseQuality(string(q)).Known() {
+			t.Errorf("expected %q to be known", q)
+		}
+	}
+
+	if q := ParseQuality("x"); q != "x" || q.Known() {
+		t.Errorf("expected unknown quality to be preserved, but got %q (known %v)", q, q.Known())
+	}
+}
+
+func TestEnum_RoundTrip(t *testing.T) {
+	src := `##Start-message
+##Time-series
+#Start=2020-01-01.00:00:00
+#Stop=2020-01-01.02:00:00
+#Step=0000-00-00.01:00:00
+#Type-of-value=INTERVAL
+#Direction-of-flow=Sideways
+#Value=< 1//0 2//X >
+#No-of-values=2
+#Sum=3
+##End-message
+#Number-of-objects=3
+`
+
+	g, err := NewDecoder(strings.NewReader(src)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := g.TimeSeries[0]
+	if ts.TypeOfValue != TypeInterval || ts.DirectionOfFlow != "Sideways" || ts.DirectionOfFlow.Known() || ts.Value[1].Quality != "X" {
+		t.Fatalf("unexpected values %q, %q, %q", ts.TypeOfValue, ts.DirectionOfFlow, ts.Value[1].Quality)
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(g); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"#Type-of-value=interval\n", "#Direction-of-flow=Sideways\n", "2//X"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected %q in encoded output:\n%s", s, buf.String())
+		}
+	}
+
+	if err := ValidateVocabulary(g); err == nil {
+		t.Errorf("expected unknown values to be invalid")
+	}
+}
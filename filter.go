@@ -0,0 +1,130 @@
+package gs2
+
+import (
+	"strings"
+	"time"
+)
+
+// Filter selects meter readings and time series in a GS2 object. Empty fields match everything, and a zero From or To leaves
+// the window open in that end.
+type Filter struct {
+	References      []string
+	Meters          []string
+	Channels        []string
+	Installations   []string
+	Units           []string
+	DirectionOfFlow DirectionOfFlow
+	From            time.Time // Inclusive start of the time window.
+	To              time.Time // Exclusive end of the time window.
+}
+
+// Filter returns a copy of g with the meter readings and time series matching f. Time series are trimmed to the values whose
+// intervals are completely within the time window, and series without values in the window are left out. For trimmed time
+// series Start, Stop, No-of-values and Sum are recomputed, and Number-of-objects is updated to the new number of objects.
+func (g *GS2) Filter(f Filter) *GS2 {
+	result := &GS2{
+		StartMessage: g.StartMessage,
+		EndMessage:   g.EndMessage,
+	}
+
+	for _, mr := range g.MeterReadings {
+		if f.matches(mr.Reference, mr.Meter, mr.Channel, mr.Installation, mr.Unit, mr.DirectionOfFlow) && f.contains(mr.Time, mr.Time) {
+			result.MeterReadings = append(result.MeterReadings, mr)
+		}
+	}
+
+	for _, ts := range g.TimeSeries {
+		if !f.matches(ts.Reference, ts.Meter, ts.Channel, ts.Installation, ts.Unit, ts.DirectionOfFlow) {
+			continue
+		}
+
+		if trimmed, ok := f.trim(ts); ok {
+			result.TimeSeries = append(result.TimeSeries, trimmed)
+		}
+	}
+
+	noOfObjects := len(result.MeterReadings) + len(result.TimeSeries) + 2
+	if result.StartMessage.NumberOfObjects != 0 {
+		result.StartMessage.NumberOfObjects = noOfObjects
+	}
+	result.EndMessage.NumberOfObjects = noOfObjects
+
+	return result
+}
+
+func (f Filter) matches(reference, meter, channel, installation, unit string, direction DirectionOfFlow) bool {
+	return matchesAny(f.References, reference) &&
+		matchesAny(f.Meters, meter) &&
+		matchesAny(f.Channels, channel) &&
+		matchesAny(f.Installations, installation) &&
+		matchesAny(f.Units, unit) &&
+		(f.DirectionOfFlow == "" || strings.EqualFold(string(f.DirectionOfFlow), string(direction)))
+}
+
+func matchesAny(list []string, s string) bool {
+	if len(list) == 0 {
+		return true
+	}
+
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contains reports whether the interval from start to stop is within the window of f.
+func (f Filter) contains(start, stop time.Time) bool {
+	if !f.From.IsZero() && start.Before(f.From) {
+		return false
+	}
+
+	if !f.To.IsZero() && (stop.After(f.To) || (stop.Equal(f.To) && start.Equal(stop))) {
+		return false
+	}
+
+	return true
+}
+
+// trim returns ts with only the values within the window of f, and false if no values are left.
+func (f Filter) trim(ts TimeSeries) (TimeSeries, bool) {
+	if f.From.IsZero() && f.To.IsZero() {
+		return ts, true
+	}
+
+	var values []Triplet
+	var sum float64
+	var start, stop time.Time
+	for i, v := range ts.Value {
+		valueStart := ts.ValueTime(i)
+		valueStop := valueStart.Add(ts.Step)
+		if !f.contains(valueStart, valueStop) {
+			continue
+		}
+
+		if len(values) == 0 {
+			start = valueStart
+		}
+		stop = valueStop
+		values = append(values, v)
+		sum += v.Value
+	}
+
+	if len(values) == 0 {
+		return TimeSeries{}, false
+	}
+
+	if len(values) == len(ts.Value) {
+		return ts, true
+	}
+
+	ts.Start = start
+	ts.Stop = stop
+	ts.Value = values
+	ts.NoOfValues = len(values)
+	ts.Sum = sum
+
+	return ts, true
+}
@@ -0,0 +1,88 @@
+package gs2
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGS2_Filter(t *testing.T) {
+	g := &GS2{
+		StartMessage: StartMessage{NumberOfObjects: 5},
+		MeterReadings: []MeterReading{
+			{Reference: "a", Meter: "meter1", Time: getTime("2020-01-01T00:00:00Z"), Value: Triplet{Value: 1}},
+			{Reference: "b", Meter: "meter2", Time: getTime("2020-01-01T00:00:00Z"), Value: Triplet{Value: 2}},
+		},
+		TimeSeries: []TimeSeries{
+			{
+				Reference:       "a",
+				Meter:           "meter1",
+				DirectionOfFlow: "out",
+				Start:           getTime("2020-01-01T00:00:00Z"),
+				Stop:            getTime("2020-01-01T04:00:00Z"),
+				Step:            time.Hour,
+				Value:           []Triplet{{Value: 1}, {Value: 2}, {Value: 3}, {Value: 4}},
+				NoOfValues:      4,
+				Sum:             10,
+			},
+		},
+		EndMessage: EndMessage{NumberOfObjects: 5},
+	}
+
+	for i, test := range []struct {
+		filter   Filter
+		expected *GS2
+	}{
+		{
+			Filter{},
+			g,
+		},
+		{
+			Filter{Meters: []string{"meter2"}},
+			&GS2{
+				StartMessage:  StartMessage{NumberOfObjects: 3},
+				MeterReadings: g.MeterReadings[1:],
+				EndMessage:    EndMessage{NumberOfObjects: 3},
+			},
+		},
+		{
+			Filter{References: []string{"a"}, DirectionOfFlow: "OUT", From: getTime("2020-01-01T01:00:00Z"), To: getTime("2020-01-01T03:00:00Z")},
+			&GS2{
+				StartMessage: StartMessage{NumberOfObjects: 3},
+				TimeSeries: []TimeSeries{
+					{
+						Reference:       "a",
+						Meter:           "meter1",
+						DirectionOfFlow: "out",
+						Start:           getTime("2020-01-01T01:00:00Z"),
+						Stop:            getTime("2020-01-01T03:00:00Z"),
+						Step:            time.Hour,
+						Value:           []Triplet{{Value: 2}, {Value: 3}},
+						NoOfValues:      2,
+						Sum:             5,
+					},
+				},
+				EndMessage: EndMessage{NumberOfObjects: 3},
+			},
+		},
+		{
+			Filter{From: getTime("2020-01-01T03:30:00Z")},
+			&GS2{
+				StartMessage: StartMessage{NumberOfObjects: 2},
+				EndMessage:   EndMessage{NumberOfObjects: 2},
+			},
+		},
+	} {
+		result := g.Filter(test.filter)
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("test %d: expected %+v, but got %+v", i, test.expected, result)
+		}
+
+		if err := ValidateNoOfObjects(result); err != nil {
+			t.Errorf("test %d: %v", i, err)
+		}
+		if err := ValidateTimeSeriesValues(result); err != nil {
+			t.Errorf("test %d: %v", i, err)
+		}
+	}
+}
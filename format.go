@@ -0,0 +1,309 @@
+package gs2
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format writes the canonical form of the GS2 document in src to dst. Unlike decoding and encoding, formatting keeps
+// blocks and attributes that are unknown to the GS2 type, so no information is lost.
+//
+// The canonical form is the one written by an Encoder with the EncodeCanonical option:
+//   - one attribute per line and an empty line between blocks
+//   - known attributes in the order of the GS2 type, followed by unknown attributes in their original order
+//   - block and attribute names spelled as in the GS2 type
+//   - numbers in their shortest form, triplets always written as value/time/quality
+//
+// Values that can't be parsed as their expected type are written as they are. Formatting is idempotent.
+func Format(dst io.Writer, src []byte) error {
+	blocks, err := tokenize(src)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for i, b := range blocks {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		formatBlock(&buf, b)
+	}
+
+	_, err = dst.Write(buf.Bytes())
+	return err
+}
+
+type rawBlock struct {
+	name       string
+	attributes []rawAttribute
+
+	end int // Offset of the end of the name in the source.
+}
+
+type rawAttribute struct {
+	name    string
+	value   string
+	isArray bool
+	array   []string
+
+	start, end int // Offsets of the value in the source, including the < and > of arrays.
+}
+
+// tokenize splits src into blocks and attributes using the same scanner as the Decoder.
+func tokenize(src []byte) ([]rawBlock, error) {
+	const (
+		none = iota
+		blockName
+		attributeName
+		value
+		arrayValue
+	)
+
+	var (
+		blocks  []rawBlock
+		token   []byte
+		target  = none
+		nameEnd int
+	)
+
+	flush := func() {
+		switch target {
+		case blockName:
+			blocks = append(blocks, rawBlock{name: string(token), end: nameEnd})
+		case value:
+			attrs := blocks[len(blocks)-1].attributes
+			attrs[len(attrs)-1].value = string(token)
+		case arrayValue:
+			if len(token) > 0 {
+				attrs := blocks[len(blocks)-1].attributes
+				attrs[len(attrs)-1].array = append(attrs[len(attrs)-1].array, string(token))
+			}
+		}
+		token = token[:0]
+	}
+
+	s := newScanner()
+	for i, b := range src {
+		switch s.step(s, b) {
+		case scanHash:
+			// A # directly following the # of an attribute name starts a block.
+			if target == attributeName {
+				if len(token) > 0 {
+					return nil, fmt.Errorf("attribute %q without value at offset %d", token, i)
+				}
+				target = blockName
+				continue
+			}
+			flush()
+			target = attributeName
+		case scanContinue:
+			switch target {
+			case blockName:
+				nameEnd = i + 1
+			case value:
+				attrs := blocks[len(blocks)-1].attributes
+				if len(token) == 0 {
+					attrs[len(attrs)-1].start = i
+				}
+				attrs[len(attrs)-1].end = i + 1
+			}
+			token = append(token, b)
+		case scanBeginValue:
+			if target != attributeName || len(blocks) == 0 {
+				return nil, fmt.Errorf("unexpected '=' at offset %d", i)
+			}
+			attrs := &blocks[len(blocks)-1].attributes
+			*attrs = append(*attrs, rawAttribute{name: string(token), start: i + 1, end: i + 1})
+			token = token[:0]
+			target = value
+		case scanArrayStart:
+			if target != value || len(bytes.TrimSpace(token)) > 0 {
+				return nil, fmt.Errorf("unexpected '<' at offset %d", i)
+			}
+			token = token[:0]
+			attrs := blocks[len(blocks)-1].attributes
+			attrs[len(attrs)-1].isArray = true
+			attrs[len(attrs)-1].start = i
+			target = arrayValue
+		case scanArraySeparator:
+			flush()
+		case scanArrayEnd:
+			flush()
+			attrs := blocks[len(blocks)-1].attributes
+			attrs[len(attrs)-1].end = i + 1
+			target = none
+		case scanSkipSpace:
+		case scanError:
+			return nil, fmt.Errorf("%v at offset %d", s.err, i)
+		}
+	}
+
+	switch {
+	case target == arrayValue:
+		return nil, fmt.Errorf("unexpected end of input in array")
+	case target == attributeName && len(token) > 0:
+		return nil, fmt.Errorf("attribute %q without value at end of input", token)
+	}
+	flush()
+
+	return blocks, nil
+}
+
+func formatBlock(buf *bytes.Buffer, b rawBlock) {
+	typ, name, known := lookupBlockType(b.name)
+	if !known {
+		name = b.name
+	}
+
+	buf.WriteString("##" + name + "\n")
+
+	type attribute struct {
+		rawAttribute
+		field int
+	}
+
+	var knownAttributes, unknownAttributes []attribute
+	for _, a := range b.attributes {
+		if !known {
+			unknownAttributes = append(unknownAttributes, attribute{a, -1})
+			continue
+		}
+
+		field, exists := lookupField(typ, a.name)
+		if !exists {
+			unknownAttributes = append(unknownAttributes, attribute{a, -1})
+			continue
+		}
+
+		a.name = tagName(typ.Field(field))
+		knownAttributes = append(knownAttributes, attribute{a, field})
+	}
+
+	// Insertion sort keeps attributes with the same field in their original order.
+	for i := 1; i < len(knownAttributes); i++ {
+		for j := i; j > 0 && knownAttributes[j-1].field > knownAttributes[j].field; j-- {
+			knownAttributes[j-1], knownAttributes[j] = knownAttributes[j], knownAttributes[j-1]
+		}
+	}
+
+	for _, a := range knownAttributes {
+		formatAttribute(buf, a.rawAttribute, typ.Field(a.field).Type)
+	}
+	for _, a := range unknownAttributes {
+		formatAttribute(buf, a.rawAttribute, nil)
+	}
+}
+
+func formatAttribute(buf *bytes.Buffer, a rawAttribute, typ reflect.Type) {
+	buf.WriteString("#" + a.name + "=")
+
+	if a.isArray {
+		var elem reflect.Type
+		if typ != nil && typ.Kind() == reflect.Slice {
+			elem = typ.Elem()
+		}
+
+		buf.WriteString("< ")
+		for _, v := range a.array {
+			buf.WriteString(formatValue(v, elem))
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(">\n")
+		return
+	}
+
+	buf.WriteString(formatValue(a.value, typ))
+	buf.WriteByte('\n')
+}
+
+// formatValue returns the canonical form of s as a value of type typ. If s is empty or can't be parsed as typ it is returned
+// as is.
+func formatValue(s string, typ reflect.Type) string {
+	if typ == nil || s == "" {
+		return s
+	}
+
+	switch typ {
+	case reflect.TypeOf(time.Time{}):
+		if t, err := parseTime(s); err == nil {
+			return formatTime(t)
+		}
+		return s
+	case reflect.TypeOf(time.Duration(0)):
+		if d, err := parseDuration(s); err == nil {
+			if encoded := encodeDuration(d); roundTrips(encoded, d) {
+				return encoded
+			}
+		}
+		return s
+	case reflect.TypeOf(Triplet{}):
+		if t, err := parseTriplet(s); err == nil {
+			return formatTriplet(t, -1)
+		}
+		return s
+	}
+
+	if u, ok := reflect.New(typ).Interface().(encoding.TextUnmarshaler); ok && typ.Kind() == reflect.String {
+		if err := u.UnmarshalText([]byte(s)); err == nil {
+			if text, err := u.(encoding.TextMarshaler).MarshalText(); err == nil {
+				return string(text)
+			}
+		}
+		return s
+	}
+
+	switch typ.Kind() {
+	case reflect.Int:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10)
+		}
+	case reflect.Float64:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	}
+
+	return s
+}
+
+func roundTrips(encoded string, d time.Duration) bool {
+	decoded, err := parseDuration(encoded)
+	return err == nil && decoded == d
+}
+
+// lookupBlockType returns the type and canonical name of the block with the given name in the GS2 type.
+func lookupBlockType(name string) (reflect.Type, string, bool) {
+	typ := reflect.TypeOf(GS2{})
+
+	field, exists := lookupField(typ, name)
+	if !exists {
+		return nil, "", false
+	}
+
+	blockType := typ.Field(field).Type
+	if blockType.Kind() == reflect.Slice {
+		blockType = blockType.Elem()
+	}
+
+	return blockType, tagName(typ.Field(field)), true
+}
+
+func lookupField(typ reflect.Type, name string) (int, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		if strings.EqualFold(name, tagName(typ.Field(i))) {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+func tagName(f reflect.StructField) string {
+	return strings.Split(f.Tag.Get("gs2"), ",")[0]
+}
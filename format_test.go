@@ -0,0 +1,149 @@
+package gs2
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+var formatTestFiles = []string{
+	"testdata/durations.gs2",
+	"testdata/meterreading.gs2",
+	"testdata/timeseries.gs2",
+	"testdata/timeseries_noNewlines.gs2",
+}
+
+func TestFormat_Idempotent(t *testing.T) {
+	for _, file := range formatTestFiles {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var first, second bytes.Buffer
+		if err := Format(&first, src); err != nil {
+			t.Fatalf("unexpected error when formatting %s: %v", file, err)
+		}
+		if err := Format(&second, first.Bytes()); err != nil {
+			t.Fatalf("unexpected error when formatting %s twice: %v", file, err)
+		}
+
+		if first.String() != second.String() {
+			t.Errorf("formatting %s is not idempotent. First:\n%s second:\n%s", file, first.String(), second.String())
+		}
+	}
+}
+
+func TestFormat_PreservesContent(t *testing.T) {
+	for _, file := range formatTestFiles {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := Format(&buf, src); err != nil {
+			t.Fatalf("unexpected error when formatting %s: %v", file, err)
+		}
+
+		expected, err := NewDecoder(bytes.NewReader(src)).Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := NewDecoder(&buf).Decode()
+		if err != nil {
+			t.Fatalf("unexpected error when decoding formatted %s: %v", file, err)
+		}
+
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("decoding formatted %s does not equal decoding the original", file)
+		}
+	}
+}
+
+func TestFormat_NewlinesDoNotMatter(t *testing.T) {
+	var withNewlines, withoutNewlines bytes.Buffer
+
+	for file, buf := range map[string]*bytes.Buffer{
+		"testdata/timeseries.gs2":            &withNewlines,
+		"testdata/timeseries_noNewlines.gs2": &withoutNewlines,
+	} {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Format(buf, src); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if withNewlines.String() != withoutNewlines.String() {
+		t.Errorf("Expected:\n%s got:\n%s", withNewlines.String(), withoutNewlines.String())
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for _, test := range formatTestTable {
+		var buf bytes.Buffer
+		if err := Format(&buf, []byte(test.input)); err != nil {
+			t.Fatalf("unexpected error when formatting: %v", err)
+		}
+
+		if buf.String() != test.expected {
+			t.Errorf("Expected:\n%s got:\n%s", test.expected, buf.String())
+		}
+	}
+}
+
+func TestFormat_Error(t *testing.T) {
+	for _, input := range []string{
+		"a",
+		"##Start-message#Id",
+		"##Time-series#Value=< 1// 2//",
+	} {
+		if err := Format(ioutil.Discard, []byte(input)); err == nil {
+			t.Errorf("expected error when formatting %q", input)
+		}
+	}
+}
+
+var formatTestTable = []struct {
+	input    string
+	expected string
+}{
+	{
+		"##start-message#GMT-reference=+1#Id=0#Unknown=some value##Time-series#sum=1.000#Value=<1 1/2020-01-01.24:00:00/x>#Step=0000-00-00.01:00##END-MESSAGE#Number-of-objects=3",
+		`##Start-message
+#Id=0
+#GMT-reference=1
+#Unknown=some value
+
+##Time-series
+#Step=0000-00-00.01:00:00
+#Value=< 1// 1/2020-01-02.00:00:00/x >
+#Sum=1
+
+##End-message
+#Number-of-objects=3
+`,
+	},
+	{
+		"##Start-message\r\n#Time=not a time\r\n#Number-of-objects=\r\n",
+		`##Start-message
+#Time=not a time
+#Number-of-objects=
+`,
+	},
+	{
+		"##Start-message\n#Time=\n##Time-series\n#Start=\n#Step=\n#Value=<  >\n",
+		`##Start-message
+#Time=
+
+##Time-series
+#Start=
+#Step=
+#Value=< >
+`,
+	},
+}
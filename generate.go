@@ -0,0 +1,390 @@
+package gs2
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Profile is the shape of the load of a meter over a day.
+type Profile string
+
+// Profiles of generated time series.
+const (
+	ProfileFlat        Profile = "flat"        // Constant load.
+	ProfileResidential Profile = "residential" // Base load with peaks in the morning and the evening.
+	ProfileCommercial  Profile = "commercial"  // High load in working hours on weekdays.
+	ProfileSolar       Profile = "solar"       // Production in daylight, with Direction-of-flow in.
+)
+
+// Profiles are the profiles of generated time series.
+var Profiles = []Profile{ProfileFlat, ProfileResidential, ProfileCommercial, ProfileSolar}
+
+// DSTChange is a change to or from daylight saving time.
+type DSTChange string
+
+// Daylight saving time changes a generated period can be moved to cross.
+const (
+	DSTNone   DSTChange = ""
+	DSTSpring DSTChange = "spring" // The change to daylight saving time, where the local day has one hour less.
+	DSTAutumn DSTChange = "autumn" // The change from daylight saving time, where the local day has one hour more.
+)
+
+// Generator generates GS2 files of settlement data with random values, e.g. for tests and benchmarks. A meter gets a time
+// series over the period, and optionally meter readings whose register values follow the time series. Files pass the
+// DefaultRules, strict rules included, unless Errors is set. The number of values is the number of whole steps in Location,
+// where steps of whole days are calendar days, but the values are steps in the time zone of the GMT-reference, as the
+// time-series-period rule expects. Over a change to or from daylight saving time, daily values thus end an hour after or
+// before local midnight. The same Generator and Seed give the same file.
+type Generator struct {
+	Seed     int64
+	Meters   int            // Number of meters. Less than 1 means 1.
+	Location *time.Location // Time zone of the period and the profiles. Nil means UTC.
+	Start    time.Time      // Start of the period. Zero means midnight at the start of 2024 in Location.
+	Stop     time.Time      // End of the period, shortened to whole steps. Zero means one day after Start.
+	Step     time.Duration  // Length of each value. Zero means one hour.
+	DST      DSTChange      // If set, the period is moved to start at midnight on the day of the next such change in Location.
+	Profiles []Profile      // Profiles of the meters, used in turn. Empty means ProfileFlat.
+
+	Gaps      float64 // Fraction of values that are missing, with value 0 and QualityMissing.
+	Qualities float64 // Fraction of values that are corrected or estimated.
+	Readings  int     // Meter readings of each meter, spread evenly over the period from Start to Stop.
+
+	TargetSize int // If set, meters are added until the encoded file is about this many bytes.
+	Errors     int // Number of time series with a wrong Sum or No-of-values, which fail the time-series-values rule.
+	// A wrong Sum also fails the qualities rule.
+}
+
+// maxLoad is the largest peak load of a meter, in kW.
+const maxLoad = 10
+
+// Generate returns a new file.
+func (gen *Generator) Generate() (*GS2, error) {
+	loc := gen.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	start := gen.Start
+	if start.IsZero() {
+		start = time.Date(2024, 1, 1, 0, 0, 0, 0, loc)
+	}
+	stop := gen.Stop
+	if stop.IsZero() {
+		stop = start.In(loc).AddDate(0, 0, 1)
+	}
+	if gen.DST != DSTNone {
+		var err error
+		if start, stop, err = dstPeriod(start, stop, loc, gen.DST); err != nil {
+			return nil, err
+		}
+	}
+
+	step := gen.Step
+	if step == 0 {
+		step = time.Hour
+	}
+
+	gmtReference, err := standardOffset(start, loc)
+	if err != nil {
+		return nil, err
+	}
+	fileLoc := gmtReferenceLocation(gmtReference)
+
+	switch {
+	case step < 0:
+		return nil, fmt.Errorf("gs2: step must be positive, but is %v", step)
+	case !stop.After(start):
+		return nil, fmt.Errorf("gs2: stop %s is not after start %s", stop.Format(time.RFC3339), start.Format(time.RFC3339))
+	case gen.Gaps < 0 || gen.Qualities < 0 || gen.Gaps+gen.Qualities > 1:
+		return nil, fmt.Errorf("gs2: fractions of gaps %v and qualities %v must be positive and at most 1 together", gen.Gaps, gen.Qualities)
+	case gen.Readings < 0 || gen.Errors < 0 || gen.TargetSize < 0:
+		return nil, errors.New("gs2: readings, errors and target size must not be negative")
+	}
+	for _, p := range gen.Profiles {
+		if !p.known() {
+			return nil, fmt.Errorf("gs2: unknown profile %q", p)
+		}
+	}
+
+	// A local day of 23 or 25 hours is one value, but the values are 24 hours in the GMT-reference, so Stop agrees with Start
+	// + n * Step in the file.
+	n := countSteps(start, stop, step, loc)
+	if n == 0 {
+		return nil, fmt.Errorf("gs2: step %v is longer than the period from %s to %s", step, start.Format(time.RFC3339), stop.Format(time.RFC3339))
+	}
+	if gen.Readings > n+1 {
+		return nil, fmt.Errorf("gs2: %d meter readings don't fit at the %d steps of the period", gen.Readings, n+1)
+	}
+
+	p := period{start: start.In(fileLoc), step: step, n: n, loc: loc, fileLoc: fileLoc}
+	p.stop = addSteps(p.start, step, n, fileLoc).In(fileLoc)
+
+	g := &GS2{
+		StartMessage: StartMessage{
+			ID:           fmt.Sprintf("generated-%d", gen.Seed),
+			MessageType:  MessageTypeSettlementData,
+			Version:      "1.2",
+			Time:         p.stop,
+			To:           "recipient",
+			From:         "sender",
+			GMTReference: gmtReference,
+		},
+		EndMessage: EndMessage{ID: fmt.Sprintf("generated-%d", gen.Seed)},
+	}
+
+	rnd := rand.New(rand.NewSource(gen.Seed))
+	meters := gen.Meters
+	if meters < 1 {
+		meters = 1
+	}
+	for i := 0; i < meters; i++ {
+		gen.addMeter(g, rnd, p, i)
+	}
+
+	if gen.TargetSize > 0 {
+		if err := gen.grow(g, rnd, p, meters); err != nil {
+			return nil, err
+		}
+	}
+
+	if gen.Errors > len(g.TimeSeries) {
+		return nil, fmt.Errorf("gs2: %d errors requested, but there are only %d time series", gen.Errors, len(g.TimeSeries))
+	}
+	for i, j := range rnd.Perm(len(g.TimeSeries))[:gen.Errors] {
+		ts := &g.TimeSeries[j]
+		if i%2 == 0 {
+			ts.Sum = round(ts.Sum + 1 + rnd.Float64()*100)
+		} else {
+			ts.NoOfValues++
+		}
+	}
+
+	g.EndMessage.NumberOfObjects = len(g.MeterReadings) + len(g.TimeSeries) + 2
+	return g, nil
+}
+
+// period is the period of a generated file.
+type period struct {
+	start, stop time.Time
+	step        time.Duration
+	n           int            // Number of values.
+	loc         *time.Location // Time zone of the profiles.
+	fileLoc     *time.Location // Time zone of the GMT-reference.
+}
+
+// addMeter adds a time series and the meter readings of the meter with index i to g.
+func (gen *Generator) addMeter(g *GS2, rnd *rand.Rand, p period, i int) {
+	profile := ProfileFlat
+	if len(gen.Profiles) > 0 {
+		profile = gen.Profiles[i%len(gen.Profiles)]
+	}
+	direction := DirectionOut
+	if profile == ProfileSolar {
+		direction = DirectionIn
+	}
+
+	ts := TimeSeries{
+		Reference:       fmt.Sprintf("70705750%010d", i),
+		Start:           p.start,
+		Stop:            p.stop,
+		Step:            p.step,
+		Unit:            "kWh",
+		TypeOfValue:     TypeInterval,
+		DirectionOfFlow: direction,
+		Value:           make([]Triplet, p.n),
+		NoOfValues:      p.n,
+		Meter:           fmt.Sprintf("meter%d", i),
+		Channel:         "1",
+	}
+
+	load := 1 + rnd.Float64()*(maxLoad-1)
+	var sum float64
+	for j := range ts.Value {
+		v := &ts.Value[j]
+		switch r := rnd.Float64(); {
+		case r < gen.Gaps:
+			v.Quality = QualityMissing
+			continue
+		case r < gen.Gaps+gen.Qualities/2:
+			v.Quality = QualityCorrected
+		case r < gen.Gaps+gen.Qualities:
+			v.Quality = QualityEstimated
+		}
+
+		from := addSteps(p.start, p.step, j, p.fileLoc)
+		to := addSteps(p.start, p.step, j+1, p.fileLoc)
+		v.Value = round(profile.energy(from, to, p.loc) * load * (0.8 + 0.4*rnd.Float64()))
+		sum += v.Value
+	}
+	ts.Sum = round(sum)
+
+	if gen.Readings > 0 {
+		register := round(rnd.Float64() * 100000)
+		next := 0
+		for k := 0; k < gen.Readings; k++ {
+			at := 0
+			if gen.Readings > 1 {
+				at = k * p.n / (gen.Readings - 1)
+			}
+			for ; next < at; next++ {
+				register += ts.Value[next].Value
+			}
+
+			g.MeterReadings = append(g.MeterReadings, MeterReading{
+				Reference:       ts.Reference,
+				Time:            addSteps(p.start, p.step, at, p.fileLoc).In(p.fileLoc),
+				Unit:            ts.Unit,
+				Value:           Triplet{Value: round(register)},
+				Meter:           ts.Meter,
+				Channel:         ts.Channel,
+				DirectionOfFlow: direction,
+			})
+		}
+	}
+
+	g.TimeSeries = append(g.TimeSeries, ts)
+}
+
+// grow adds meters to g until it is about the target size when encoded. The size of a meter is estimated from the meters
+// in g, and the meters are then added at once.
+func (gen *Generator) grow(g *GS2, rnd *rand.Rand, p period, meters int) error {
+	size, err := encodedSize(g)
+	if err != nil {
+		return err
+	}
+
+	empty := *g
+	empty.MeterReadings, empty.TimeSeries = nil, nil
+	overhead, err := encodedSize(&empty)
+	if err != nil {
+		return err
+	}
+
+	perMeter := (size - overhead) / meters
+	if perMeter < 1 {
+		perMeter = 1
+	}
+	extra := (gen.TargetSize - size + perMeter/2) / perMeter
+	for i := meters; i < meters+extra; i++ {
+		gen.addMeter(g, rnd, p, i)
+	}
+
+	return nil
+}
+
+// encodedSize returns the size of g encoded.
+func encodedSize(g *GS2) (int, error) {
+	var w countingWriter
+	g.EndMessage.NumberOfObjects = len(g.MeterReadings) + len(g.TimeSeries) + 2
+	if err := NewEncoder(&w).Encode(g); err != nil {
+		return 0, err
+	}
+
+	return int(w), nil
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter int
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+func (p Profile) known() bool {
+	for _, known := range Profiles {
+		if p == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// energy returns the energy in kWh from from to to of a meter with a peak load of 1 kW. The load is sampled at most every
+// hour in the local time of loc.
+func (p Profile) energy(from, to time.Time, loc *time.Location) float64 {
+	var energy float64
+	for t := from; t.Before(to); {
+		next := t.Add(time.Hour)
+		if next.After(to) {
+			next = to
+		}
+		energy += p.load(t.In(loc)) * next.Sub(t).Hours()
+		t = next
+	}
+
+	return energy
+}
+
+// load returns the load at local time t as a fraction of the peak load.
+func (p Profile) load(t time.Time) float64 {
+	hour := float64(t.Hour()) + float64(t.Minute())/60
+	peak := func(at, width float64) float64 {
+		return math.Exp(-(hour - at) * (hour - at) / (2 * width * width))
+	}
+
+	switch p {
+	case ProfileResidential:
+		return 0.2 + 0.5*peak(7.5, 1) + 0.8*peak(18.5, 1.5)
+	case ProfileCommercial:
+		if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday || hour < 7 || hour >= 17 {
+			return 0.2
+		}
+		return 1
+	case ProfileSolar:
+		return math.Max(0, math.Sin(math.Pi*(hour-6)/12))
+	default:
+		return 1
+	}
+}
+
+// dstPeriod returns the period from start to stop moved to start at midnight on the day of the next change of the given
+// kind in loc, keeping its number of days.
+func dstPeriod(start, stop time.Time, loc *time.Location, change DSTChange) (time.Time, time.Time, error) {
+	if change != DSTSpring && change != DSTAutumn {
+		return start, stop, fmt.Errorf("gs2: unknown daylight saving time change %q", change)
+	}
+
+	local := start.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	for i := 0; i < 366; i, day = i+1, day.AddDate(0, 0, 1) {
+		_, before := day.Zone()
+		_, after := day.AddDate(0, 0, 1).Zone()
+		if (change == DSTSpring && after > before) || (change == DSTAutumn && after < before) {
+			days := int(math.Ceil(stop.Sub(start).Hours() / 24))
+			if days < 1 {
+				days = 1
+			}
+			return day, day.AddDate(0, 0, days), nil
+		}
+	}
+
+	return start, stop, fmt.Errorf("gs2: no %s daylight saving time change in %s within a year of %s", change, loc, start.Format("2006-01-02"))
+}
+
+// standardOffset returns the offset of loc without daylight saving time in the year of t, in whole hours, for the
+// GMT-reference.
+func standardOffset(t time.Time, loc *time.Location) (int, error) {
+	year := t.In(loc).Year()
+	_, winter := time.Date(year, time.January, 1, 0, 0, 0, 0, loc).Zone()
+	_, summer := time.Date(year, time.July, 1, 0, 0, 0, 0, loc).Zone()
+	offset := winter
+	if summer < offset {
+		offset = summer
+	}
+
+	if offset%3600 != 0 {
+		return 0, fmt.Errorf("gs2: the offset of %s isn't whole hours, so it can't be a GMT-reference", loc)
+	}
+
+	return offset / 3600, nil
+}
+
+// round rounds v to whole Wh, as kWh.
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
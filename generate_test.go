@@ -0,0 +1,199 @@
+package gs2
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// checkGenerated encodes and decodes g, and returns the findings of all DefaultRules, strict rules included.
+func checkGenerated(t *testing.T, g *GS2) []*Finding {
+	t.Helper()
+
+	decoded, err := NewDecoder(bytes.NewReader(encodeTest(t, g)), DecodeValidators()).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := DefaultRules().Enable("vocabulary", "qualities")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rules.Check(decoded)
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	oslo, err := time.LoadLocation("Europe/Oslo")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		name     string
+		gen      Generator
+		readings int
+		series   int
+		values   int
+	}{
+		{"default", Generator{}, 0, 1, 24},
+		{"meters", Generator{Meters: 3, Readings: 2}, 6, 3, 24},
+		{"week of quarters", Generator{Location: oslo, Start: time.Date(2020, 3, 2, 0, 0, 0, 0, oslo),
+			Stop: time.Date(2020, 3, 9, 0, 0, 0, 0, oslo), Step: 15 * time.Minute}, 0, 1, 7 * 96},
+		{"days", Generator{Location: oslo, Start: time.Date(2020, 1, 1, 0, 0, 0, 0, oslo), Stop: time.Date(2021, 1, 1, 0, 0, 0, 0, oslo),
+			Step: 24 * time.Hour, Readings: 13}, 13, 1, 366},
+		{"spring", Generator{Location: oslo, Start: time.Date(2020, 1, 1, 0, 0, 0, 0, oslo), DST: DSTSpring, Readings: 3}, 3, 1, 23},
+		{"autumn", Generator{Location: oslo, Start: time.Date(2020, 1, 1, 0, 0, 0, 0, oslo), DST: DSTAutumn}, 0, 1, 25},
+		{"autumn days", Generator{Location: oslo, Start: time.Date(2020, 1, 1, 0, 0, 0, 0, oslo),
+			Stop: time.Date(2020, 1, 4, 0, 0, 0, 0, oslo), Step: 24 * time.Hour, DST: DSTAutumn}, 0, 1, 3},
+		{"spring days", Generator{Location: oslo, Start: time.Date(2024, 1, 1, 0, 0, 0, 0, oslo),
+			Stop: time.Date(2024, 1, 4, 0, 0, 0, 0, oslo), Step: 24 * time.Hour, DST: DSTSpring, Readings: 4}, 4, 1, 3},
+		{"spring day", Generator{Location: oslo, Start: time.Date(2020, 3, 29, 0, 0, 0, 0, oslo),
+			Stop: time.Date(2020, 3, 30, 0, 0, 0, 0, oslo), Step: 24 * time.Hour, Readings: 2}, 2, 1, 1},
+		{"profiles", Generator{Meters: 8, Profiles: Profiles, Readings: 5}, 40, 8, 24},
+		{"gaps and qualities", Generator{Meters: 4, Gaps: 0.2, Qualities: 0.3, Readings: 25}, 100, 4, 24},
+		{"all missing", Generator{Gaps: 1, Readings: 2}, 2, 1, 24},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := test.gen.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(g.MeterReadings) != test.readings || len(g.TimeSeries) != test.series {
+				t.Fatalf("expected %d meter readings and %d time series, but got %d and %d", test.readings, test.series,
+					len(g.MeterReadings), len(g.TimeSeries))
+			}
+			for _, ts := range g.TimeSeries {
+				if len(ts.Value) != test.values {
+					t.Errorf("%s: expected %d values, but got %d", ts.Reference, test.values, len(ts.Value))
+				}
+			}
+
+			for _, f := range checkGenerated(t, g) {
+				t.Error(f)
+			}
+		})
+	}
+}
+
+func TestGenerator_Generate_Seed(t *testing.T) {
+	gen := Generator{Seed: 42, Meters: 3, Profiles: Profiles, Gaps: 0.1, Qualities: 0.1, Readings: 3}
+
+	a, err := gen.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := gen.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Error("expected the same file from the same seed")
+	}
+
+	gen.Seed = 43
+	c, err := gen.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(a.TimeSeries, c.TimeSeries) {
+		t.Error("expected different values from different seeds")
+	}
+}
+
+func TestGenerator_Generate_Qualities(t *testing.T) {
+	g, err := (&Generator{Meters: 10, Gaps: 0.25, Qualities: 0.25}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[Quality]int{}
+	for _, ts := range g.TimeSeries {
+		for _, v := range ts.Value {
+			counts[v.Quality]++
+			if v.Quality == QualityMissing && v.Value != 0 {
+				t.Errorf("expected missing values to be 0, but got %v", v.Value)
+			}
+		}
+	}
+
+	for _, q := range []Quality{QualityNone, QualityCorrected, QualityEstimated, QualityMissing} {
+		if counts[q] == 0 {
+			t.Errorf("expected values with quality %q", q)
+		}
+	}
+}
+
+func TestGenerator_Generate_TargetSize(t *testing.T) {
+	for _, size := range []int{10000, 200000} {
+		g, err := (&Generator{Readings: 2, Profiles: Profiles, TargetSize: size}).Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data := encodeTest(t, g)
+		if len(data) < size*9/10 || len(data) > size*11/10 {
+			t.Errorf("expected about %d bytes, but got %d", size, len(data))
+		}
+		for _, f := range checkGenerated(t, g) {
+			t.Error(f)
+		}
+	}
+}
+
+func TestGenerator_Generate_Errors(t *testing.T) {
+	g, err := (&Generator{Meters: 5, Errors: 2}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := map[int]bool{}
+	for _, f := range checkGenerated(t, g) {
+		if f.Severity < SeverityError {
+			continue
+		}
+		if f.Rule != "time-series-values" && f.Rule != "qualities" {
+			t.Errorf("unexpected finding %v", f)
+		}
+		failed[f.Err.(*ObjectError).Index] = true
+	}
+	if len(failed) != 2 {
+		t.Errorf("expected 2 invalid time series, but got %d", len(failed))
+	}
+}
+
+func TestGenerator_Generate_Invalid(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	india, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		name string
+		gen  Generator
+	}{
+		{"stop before start", Generator{Start: start, Stop: start.Add(-time.Hour)}},
+		{"negative step", Generator{Step: -time.Hour}},
+		{"step longer than period", Generator{Step: 48 * time.Hour}},
+		{"gaps and qualities", Generator{Gaps: 0.6, Qualities: 0.6}},
+		{"unknown profile", Generator{Profiles: []Profile{"wind"}}},
+		{"too many readings", Generator{Readings: 26}},
+		{"too many errors", Generator{Meters: 2, Errors: 3}},
+		{"no daylight saving time", Generator{DST: DSTSpring}},
+		{"unknown change", Generator{Location: india, DST: "winter"}},
+		{"offset not whole hours", Generator{Location: india}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.gen.Generate(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/3lvia/gs2
 
-go 1.14
+go 1.18
@@ -2,6 +2,8 @@ package gs2
 
 import "time"
 
+//go:generate go run ./cmd/generate-codec -type StartMessage,EndMessage,MeterReading,TimeSeries
+
 // GS2 - versjon 1.2
 //http://hdl.handle.net/11250/2391930
 
@@ -30,6 +32,11 @@ type StartMessage struct {
 	Description     string    `gs2:"Description,omitempty"`
 }
 
+// Location returns the time zone given by GMTReference, which is the time zone of all times in the file.
+func (m StartMessage) Location() *time.Location {
+	return gmtReferenceLocation(m.GMTReference)
+}
+
 // EndMessage should always be the last object in any GS2-file-
 type EndMessage struct {
 	ID              string    `gs2:"Id,omitempty"`
@@ -49,48 +56,48 @@ type EndMessage struct {
 
 // MeterReading contains a single value that is a channel reading at a given point in time.
 type MeterReading struct {
-	Reference       string    `gs2:"Reference,omitempty"`
-	Time            time.Time `gs2:"Time,omitempty"`
-	Unit            string    `gs2:"Unit,omitempty"`
-	Value           Triplet   `gs2:"Value"`
-	Installation    string    `gs2:"Installation,omitempty"`
-	Plant           string    `gs2:"Plant,omitempty"`
-	MeterLocation   string    `gs2:"Meter-location,omitempty"`
-	NetOwner        string    `gs2:"Net-owner,omitempty"`
-	Supplier        string    `gs2:"Supplier,omitempty"`
-	Customer        string    `gs2:"Customer,omitempty"`
-	Meter           string    `gs2:"Meter,omitempty"`
-	Channel         string    `gs2:"Channel,omitempty"`
-	Description     string    `gs2:"Description,omitempty"`
-	DirectionOfFlow string    `gs2:"Direction-of-flow,omitempty"`
+	Reference       string          `gs2:"Reference,omitempty"`
+	Time            time.Time       `gs2:"Time,omitempty"`
+	Unit            string          `gs2:"Unit,omitempty"`
+	Value           Triplet         `gs2:"Value"`
+	Installation    string          `gs2:"Installation,omitempty"`
+	Plant           string          `gs2:"Plant,omitempty"`
+	MeterLocation   string          `gs2:"Meter-location,omitempty"`
+	NetOwner        string          `gs2:"Net-owner,omitempty"`
+	Supplier        string          `gs2:"Supplier,omitempty"`
+	Customer        string          `gs2:"Customer,omitempty"`
+	Meter           string          `gs2:"Meter,omitempty"`
+	Channel         string          `gs2:"Channel,omitempty"`
+	Description     string          `gs2:"Description,omitempty"`
+	DirectionOfFlow DirectionOfFlow `gs2:"Direction-of-flow,omitempty"`
 }
 
 // TimeSeries contains time series of metered values within the interval given by start and stop.
 type TimeSeries struct {
-	Reference       string        `gs2:"Reference,omitempty"`
-	Start           time.Time     `gs2:"Start,omitempty"`
-	Stop            time.Time     `gs2:"Stop,omitempty"`
-	Step            time.Duration `gs2:"Step,omitempty"`
-	Unit            string        `gs2:"Unit,omitempty"`
-	TypeOfValue     string        `gs2:"Type-of-value,omitempty"`
-	DirectionOfFlow string        `gs2:"Direction-of-flow,omitempty"`
-	Value           []Triplet     `gs2:"Value,omitempty"`
-	NoOfValues      int           `gs2:"No-of-values"`
-	Sum             float64       `gs2:"Sum"`
-	Installation    string        `gs2:"Installation,omitempty"`
-	Plant           string        `gs2:"Plant,omitempty"`
-	MeterLocation   string        `gs2:"Meter-location,omitempty"`
-	NetOwner        string        `gs2:"Net-owner,omitempty"`
-	Supplier        string        `gs2:"Supplier,omitempty"`
-	Customer        string        `gs2:"Customer,omitempty"`
-	Meter           string        `gs2:"Meter,omitempty"`
-	Channel         string        `gs2:"Channel,omitempty"`
-	Description     string        `gs2:"Description,omitempty"`
+	Reference       string          `gs2:"Reference,omitempty"`
+	Start           time.Time       `gs2:"Start,omitempty"`
+	Stop            time.Time       `gs2:"Stop,omitempty"`
+	Step            time.Duration   `gs2:"Step,omitempty"`
+	Unit            string          `gs2:"Unit,omitempty"`
+	TypeOfValue     TypeOfValue     `gs2:"Type-of-value,omitempty"`
+	DirectionOfFlow DirectionOfFlow `gs2:"Direction-of-flow,omitempty"`
+	Value           []Triplet       `gs2:"Value,omitempty"`
+	NoOfValues      int             `gs2:"No-of-values"`
+	Sum             float64         `gs2:"Sum"`
+	Installation    string          `gs2:"Installation,omitempty"`
+	Plant           string          `gs2:"Plant,omitempty"`
+	MeterLocation   string          `gs2:"Meter-location,omitempty"`
+	NetOwner        string          `gs2:"Net-owner,omitempty"`
+	Supplier        string          `gs2:"Supplier,omitempty"`
+	Customer        string          `gs2:"Customer,omitempty"`
+	Meter           string          `gs2:"Meter,omitempty"`
+	Channel         string          `gs2:"Channel,omitempty"`
+	Description     string          `gs2:"Description,omitempty"`
 }
 
 // Triplet represents a value triplet with value, time and quality.
 type Triplet struct {
 	Value   float64
 	Time    time.Time
-	Quality string
+	Quality Quality
 }
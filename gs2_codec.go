@@ -0,0 +1,311 @@
+// Code generated by generate-codec; DO NOT EDIT.
+
+package gs2
+
+// startMessageAttributes are the attribute names of StartMessage by field.
+var startMessageAttributes = []string{
+	"Id",
+	"Message-type",
+	"Version",
+	"Time",
+	"To",
+	"From",
+	"Reference-table",
+	"GMT-reference",
+	"Number-of-objects",
+	"Type-of-objects",
+	"Contains-objects",
+	"Requested-action",
+	"Description",
+}
+
+// UnmarshalGS2 decodes the attribute with the given name.
+func (s *StartMessage) UnmarshalGS2(name []byte, a *AttributeDecoder) (bool, error) {
+	switch LookupAttribute(name, startMessageAttributes) {
+	case 0:
+		return true, a.String(&s.ID)
+	case 1:
+		return true, a.String(&s.MessageType)
+	case 2:
+		return true, a.String(&s.Version)
+	case 3:
+		return true, a.Time(&s.Time)
+	case 4:
+		return true, a.String(&s.To)
+	case 5:
+		return true, a.String(&s.From)
+	case 6:
+		return true, a.String(&s.ReferenceTable)
+	case 7:
+		return true, a.Int(&s.GMTReference)
+	case 8:
+		return true, a.Int(&s.NumberOfObjects)
+	case 9:
+		return true, a.String(&s.TypeOfObjects)
+	case 10:
+		return true, a.String(&s.ContainsObjects)
+	case 11:
+		return true, a.String(&s.RequestedAction)
+	case 12:
+		return true, a.String(&s.Description)
+	}
+
+	return false, nil
+}
+
+// MarshalGS2 writes the attributes of s.
+func (s StartMessage) MarshalGS2(e *AttributeEncoder) error {
+	e.String("Id", s.ID, true)
+	e.String("Message-type", s.MessageType, true)
+	e.String("Version", s.Version, true)
+	e.Time("Time", s.Time, true)
+	e.String("To", s.To, true)
+	e.String("From", s.From, true)
+	e.String("Reference-table", s.ReferenceTable, true)
+	e.Int("GMT-reference", s.GMTReference, true)
+	e.Int("Number-of-objects", s.NumberOfObjects, true)
+	e.String("Type-of-objects", s.TypeOfObjects, true)
+	e.String("Contains-objects", s.ContainsObjects, true)
+	e.String("Requested-action", s.RequestedAction, true)
+	e.String("Description", s.Description, true)
+
+	return e.Err()
+}
+
+// endMessageAttributes are the attribute names of EndMessage by field.
+var endMessageAttributes = []string{
+	"Id",
+	"Message-type",
+	"Version",
+	"Time",
+	"To",
+	"From",
+	"Reference-table",
+	"GMT-reference",
+	"Number-of-objects",
+	"Type-of-objects",
+	"Contains-objects",
+	"Requested-action",
+	"Description",
+}
+
+// UnmarshalGS2 decodes the attribute with the given name.
+func (v *EndMessage) UnmarshalGS2(name []byte, a *AttributeDecoder) (bool, error) {
+	switch LookupAttribute(name, endMessageAttributes) {
+	case 0:
+		return true, a.String(&v.ID)
+	case 1:
+		return true, a.String(&v.MessageType)
+	case 2:
+		return true, a.String(&v.Version)
+	case 3:
+		return true, a.Time(&v.Time)
+	case 4:
+		return true, a.String(&v.To)
+	case 5:
+		return true, a.String(&v.From)
+	case 6:
+		return true, a.String(&v.ReferenceTable)
+	case 7:
+		return true, a.Int(&v.GMTReference)
+	case 8:
+		return true, a.Int(&v.NumberOfObjects)
+	case 9:
+		return true, a.String(&v.TypeOfObjects)
+	case 10:
+		return true, a.String(&v.ContainsObjects)
+	case 11:
+		return true, a.String(&v.RequestedAction)
+	case 12:
+		return true, a.String(&v.Description)
+	}
+
+	return false, nil
+}
+
+// MarshalGS2 writes the attributes of v.
+func (v EndMessage) MarshalGS2(e *AttributeEncoder) error {
+	e.String("Id", v.ID, true)
+	e.String("Message-type", v.MessageType, true)
+	e.String("Version", v.Version, true)
+	e.Time("Time", v.Time, true)
+	e.String("To", v.To, true)
+	e.String("From", v.From, true)
+	e.String("Reference-table", v.ReferenceTable, true)
+	e.Int("GMT-reference", v.GMTReference, true)
+	e.Int("Number-of-objects", v.NumberOfObjects, false)
+	e.String("Type-of-objects", v.TypeOfObjects, true)
+	e.String("Contains-objects", v.ContainsObjects, true)
+	e.String("Requested-action", v.RequestedAction, true)
+	e.String("Description", v.Description, true)
+
+	return e.Err()
+}
+
+// meterReadingAttributes are the attribute names of MeterReading by field.
+var meterReadingAttributes = []string{
+	"Reference",
+	"Time",
+	"Unit",
+	"Value",
+	"Installation",
+	"Plant",
+	"Meter-location",
+	"Net-owner",
+	"Supplier",
+	"Customer",
+	"Meter",
+	"Channel",
+	"Description",
+	"Direction-of-flow",
+}
+
+// UnmarshalGS2 decodes the attribute with the given name.
+func (m *MeterReading) UnmarshalGS2(name []byte, a *AttributeDecoder) (bool, error) {
+	switch LookupAttribute(name, meterReadingAttributes) {
+	case 0:
+		return true, a.String(&m.Reference)
+	case 1:
+		return true, a.Time(&m.Time)
+	case 2:
+		return true, a.String(&m.Unit)
+	case 3:
+		return true, a.Triplet(&m.Value)
+	case 4:
+		return true, a.String(&m.Installation)
+	case 5:
+		return true, a.String(&m.Plant)
+	case 6:
+		return true, a.String(&m.MeterLocation)
+	case 7:
+		return true, a.String(&m.NetOwner)
+	case 8:
+		return true, a.String(&m.Supplier)
+	case 9:
+		return true, a.String(&m.Customer)
+	case 10:
+		return true, a.String(&m.Meter)
+	case 11:
+		return true, a.String(&m.Channel)
+	case 12:
+		return true, a.String(&m.Description)
+	case 13:
+		return true, a.Text(&m.DirectionOfFlow)
+	}
+
+	return false, nil
+}
+
+// MarshalGS2 writes the attributes of m.
+func (m MeterReading) MarshalGS2(e *AttributeEncoder) error {
+	e.String("Reference", m.Reference, true)
+	e.Time("Time", m.Time, true)
+	e.String("Unit", m.Unit, true)
+	e.Triplet("Value", m.Value, false)
+	e.String("Installation", m.Installation, true)
+	e.String("Plant", m.Plant, true)
+	e.String("Meter-location", m.MeterLocation, true)
+	e.String("Net-owner", m.NetOwner, true)
+	e.String("Supplier", m.Supplier, true)
+	e.String("Customer", m.Customer, true)
+	e.String("Meter", m.Meter, true)
+	e.String("Channel", m.Channel, true)
+	e.String("Description", m.Description, true)
+	e.Text("Direction-of-flow", m.DirectionOfFlow, m.DirectionOfFlow == "", true)
+
+	return e.Err()
+}
+
+// timeSeriesAttributes are the attribute names of TimeSeries by field.
+var timeSeriesAttributes = []string{
+	"Reference",
+	"Start",
+	"Stop",
+	"Step",
+	"Unit",
+	"Type-of-value",
+	"Direction-of-flow",
+	"Value",
+	"No-of-values",
+	"Sum",
+	"Installation",
+	"Plant",
+	"Meter-location",
+	"Net-owner",
+	"Supplier",
+	"Customer",
+	"Meter",
+	"Channel",
+	"Description",
+}
+
+// UnmarshalGS2 decodes the attribute with the given name.
+func (t *TimeSeries) UnmarshalGS2(name []byte, a *AttributeDecoder) (bool, error) {
+	switch LookupAttribute(name, timeSeriesAttributes) {
+	case 0:
+		return true, a.String(&t.Reference)
+	case 1:
+		return true, a.Time(&t.Start)
+	case 2:
+		return true, a.Time(&t.Stop)
+	case 3:
+		return true, a.Duration(&t.Step)
+	case 4:
+		return true, a.String(&t.Unit)
+	case 5:
+		return true, a.Text(&t.TypeOfValue)
+	case 6:
+		return true, a.Text(&t.DirectionOfFlow)
+	case 7:
+		return true, a.Triplets(&t.Value)
+	case 8:
+		return true, a.Int(&t.NoOfValues)
+	case 9:
+		return true, a.Float(&t.Sum)
+	case 10:
+		return true, a.String(&t.Installation)
+	case 11:
+		return true, a.String(&t.Plant)
+	case 12:
+		return true, a.String(&t.MeterLocation)
+	case 13:
+		return true, a.String(&t.NetOwner)
+	case 14:
+		return true, a.String(&t.Supplier)
+	case 15:
+		return true, a.String(&t.Customer)
+	case 16:
+		return true, a.String(&t.Meter)
+	case 17:
+		return true, a.String(&t.Channel)
+	case 18:
+		return true, a.String(&t.Description)
+	}
+
+	return false, nil
+}
+
+// MarshalGS2 writes the attributes of t.
+func (t TimeSeries) MarshalGS2(e *AttributeEncoder) error {
+	e.String("Reference", t.Reference, true)
+	e.Time("Start", t.Start, true)
+	e.Time("Stop", t.Stop, true)
+	e.Duration("Step", t.Step, true)
+	e.String("Unit", t.Unit, true)
+	e.Text("Type-of-value", t.TypeOfValue, t.TypeOfValue == "", true)
+	e.Text("Direction-of-flow", t.DirectionOfFlow, t.DirectionOfFlow == "", true)
+	e.Triplets("Value", t.Value, true)
+	e.Int("No-of-values", t.NoOfValues, false)
+	e.Float("Sum", t.Sum, false)
+	e.String("Installation", t.Installation, true)
+	e.String("Plant", t.Plant, true)
+	e.String("Meter-location", t.MeterLocation, true)
+	e.String("Net-owner", t.NetOwner, true)
+	e.String("Supplier", t.Supplier, true)
+	e.String("Customer", t.Customer, true)
+	e.String("Meter", t.Meter, true)
+	e.String("Channel", t.Channel, true)
+	e.String("Description", t.Description, true)
+
+	return e.Err()
+}
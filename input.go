@@ -0,0 +1,249 @@
+package gs2
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Compression is the compression of a file, detected by its first bytes.
+type Compression string
+
+// Compressions detected by DetectCompression.
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionZip  Compression = "zip"
+)
+
+// magicSize is the number of bytes needed to detect the compression of a file.
+const magicSize = 4
+
+// DetectCompression returns the compression of a file starting with magic, which should be at least the first four bytes
+// of the file.
+func DetectCompression(magic []byte) Compression {
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return CompressionGzip
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")), bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		return CompressionZip
+	default:
+		return CompressionNone
+	}
+}
+
+// Input is a file with GS2 files: a GS2 file, a GS2 file compressed with gzip, or a zip archive of GS2 files, which may be
+// compressed with gzip themselves. Inputs are opened with OpenFile or NewInput, and must be closed.
+type Input struct {
+	Name        string
+	Compression Compression
+	Entries     []*Entry // The GS2 files, one unless the input is a zip archive. Directories in archives are left out.
+
+	closer io.Closer
+}
+
+// Entry is a GS2 file in an Input.
+type Entry struct {
+	Name string // Name of the input, or of the file in the zip archive.
+
+	open func() (io.ReadCloser, error)
+}
+
+// Open returns the content of e, decompressed. Entries of inputs read by NewInput from a stream, other than zip archives,
+// can only be opened once.
+func (e *Entry) Open() (io.ReadCloser, error) {
+	return e.open()
+}
+
+// OpenFile opens the named file as an Input, detecting its compression by its first bytes.
+func OpenFile(name string) (*Input, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	in, err := newFileInput(name, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return in, nil
+}
+
+func newFileInput(name string, f *os.File) (*Input, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, magicSize)
+	n, err := f.ReadAt(magic, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	in := &Input{Name: name, Compression: DetectCompression(magic[:n]), closer: f}
+	if in.Compression == CompressionZip {
+		return in, in.addZipEntries(f, fi.Size())
+	}
+
+	in.Entries = []*Entry{{Name: name, open: func() (io.ReadCloser, error) {
+		return decompress(in.Compression, io.NewSectionReader(f, 0, fi.Size()), ioutil.NopCloser(nil))
+	}}}
+	return in, nil
+}
+
+// NewInput reads an Input from r, detecting its compression by its first bytes. Zip archives are read into memory, since
+// their entries are listed at the end.
+func NewInput(name string, r io.Reader) (*Input, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(magicSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	in := &Input{Name: name, Compression: DetectCompression(magic)}
+	if in.Compression == CompressionZip {
+		data, err := ioutil.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		return in, in.addZipEntries(bytes.NewReader(data), int64(len(data)))
+	}
+
+	opened := false
+	in.Entries = []*Entry{{Name: name, open: func() (io.ReadCloser, error) {
+		if opened {
+			return nil, errors.New("gs2: input " + name + " can only be read once")
+		}
+		opened = true
+		return decompress(in.Compression, br, ioutil.NopCloser(nil))
+	}}}
+	return in, nil
+}
+
+// addZipEntries adds the files in the zip archive in r as entries.
+func (in *Input) addZipEntries(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+
+		f := f
+		in.Entries = append(in.Entries, &Entry{Name: f.Name, open: func() (io.ReadCloser, error) {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+
+			br := bufio.NewReader(rc)
+			magic, err := br.Peek(magicSize)
+			if err != nil && err != io.EOF {
+				rc.Close()
+				return nil, err
+			}
+
+			compression := DetectCompression(magic)
+			if compression == CompressionZip {
+				compression = CompressionNone
+			}
+			return decompress(compression, br, rc)
+		}})
+	}
+
+	return nil
+}
+
+// decompress returns r decompressed. Closing the result closes c.
+func decompress(compression Compression, r io.Reader, c io.Closer) (io.ReadCloser, error) {
+	if compression != CompressionGzip {
+		return readCloser{r, c}, nil
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return readCloser{zr, closers{zr, c}}, nil
+}
+
+// Close closes the file of in, if any.
+func (in *Input) Close() error {
+	if in.closer == nil {
+		return nil
+	}
+
+	return in.closer.Close()
+}
+
+// Result is the result of decoding an Entry.
+type Result struct {
+	Entry string // Name of the entry.
+	GS2   *GS2
+	Err   error
+}
+
+// Decode decodes the entries of in, and returns the result of each entry in order. An entry that can't be decoded doesn't
+// stop the others.
+func (in *Input) Decode(opt ...DecoderOption) []Result {
+	return in.DecodeContext(context.Background(), opt...)
+}
+
+// DecodeContext is like Decode, but stops with the error of ctx when ctx is done. The remaining entries get the error as
+// their result.
+func (in *Input) DecodeContext(ctx context.Context, opt ...DecoderOption) []Result {
+	results := make([]Result, len(in.Entries))
+	dec := NewDecoder(nil, opt...)
+	for i, e := range in.Entries {
+		results[i].Entry = e.Name
+		if results[i].Err = ctx.Err(); results[i].Err != nil {
+			continue
+		}
+
+		r, err := e.Open()
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		dec.Reset(r)
+		results[i].GS2, results[i].Err = dec.DecodeContext(ctx)
+		r.Close()
+	}
+
+	return results
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// closers closes all its closers, and returns the first error.
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var first error
+	for _, c := range cs {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
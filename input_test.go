@@ -0,0 +1,155 @@
+package gs2
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetectCompression(t *testing.T) {
+	tests := []struct {
+		magic    []byte
+		expected Compression
+	}{
+		{[]byte("##Start-message"), CompressionNone},
+		{[]byte{0x1f, 0x8b, 0x08, 0x00}, CompressionGzip},
+		{[]byte("PK\x03\x04"), CompressionZip},
+		{[]byte("PK\x05\x06"), CompressionZip},
+		{[]byte("PK"), CompressionNone},
+		{nil, CompressionNone},
+	}
+
+	for _, test := range tests {
+		if c := DetectCompression(test.magic); c != test.expected {
+			t.Errorf("%q: expected %s, but got %s", test.magic, test.expected, c)
+		}
+	}
+}
+
+// compressGzip returns data compressed with gzip.
+func compressGzip(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// compressZip returns a zip archive with the given files, in order.
+func compressZip(t *testing.T, files ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		w, err := zw.Create(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(files[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestOpenFile(t *testing.T) {
+	data, err := os.ReadFile("testdata/meterreading.gs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := "##Start-message\n#Id=1\n##Time-series\n#Step=x\n"
+	dir := t.TempDir()
+	tests := []struct {
+		name        string
+		data        []byte
+		compression Compression
+		entries     []string // The files in the archive, or the input if it isn't an archive.
+		valid       []bool
+	}{
+		{"file.gs2", data, CompressionNone, nil, []bool{true}},
+		{"file.gs2.gz", compressGzip(t, data), CompressionGzip, nil, []bool{true}},
+		{"files.zip", compressZip(t, "a.gs2", string(data), "dir/", "", "dir/b.gs2", invalid,
+			"c.gs2.gz", string(compressGzip(t, data))), CompressionZip, []string{"a.gs2", "dir/b.gs2", "c.gs2.gz"},
+			[]bool{true, false, true}},
+		{"empty.zip", compressZip(t), CompressionZip, nil, nil},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, test.name)
+		if err := os.WriteFile(path, test.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if test.compression != CompressionZip {
+			test.entries = []string{path}
+		}
+
+		open := map[string]func() (*Input, error){
+			"OpenFile": func() (*Input, error) { return OpenFile(path) },
+			"NewInput": func() (*Input, error) { return NewInput(path, bytes.NewReader(test.data)) },
+		}
+		for how, open := range open {
+			in, err := open()
+			if err != nil {
+				t.Fatalf("%s %s: %v", how, test.name, err)
+			}
+
+			if in.Compression != test.compression {
+				t.Errorf("%s %s: expected %s, but got %s", how, test.name, test.compression, in.Compression)
+			}
+
+			results := in.Decode()
+			var entries []string
+			for i, result := range results {
+				entries = append(entries, result.Entry)
+				if valid := result.Err == nil; valid != test.valid[i] {
+					t.Errorf("%s %s: %s: unexpected error %v", how, test.name, result.Entry, result.Err)
+				}
+				if result.Err == nil && !reflect.DeepEqual(result.GS2, expected) {
+					t.Errorf("%s %s: %s: expected %+v, but got %+v", how, test.name, result.Entry, expected, result.GS2)
+				}
+			}
+			if !reflect.DeepEqual(entries, test.entries) {
+				t.Errorf("%s %s: expected entries %q, but got %q", how, test.name, test.entries, entries)
+			}
+
+			if err := in.Close(); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+func TestNewInput_ReadOnce(t *testing.T) {
+	in, err := NewInput("stdin", bytes.NewReader([]byte("##Start-message\n#Id=1\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if results := in.Decode(DecodeValidators()); results[0].Err != nil {
+		t.Fatal(results[0].Err)
+	}
+	if results := in.Decode(DecodeValidators()); results[0].Err == nil {
+		t.Error("expected an error when reading a stream twice")
+	}
+}
@@ -0,0 +1,85 @@
+package gs2
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Limit names a limit on the input of the Decoder.
+type Limit string
+
+// Limits of the Decoder, see Limits.
+const (
+	LimitBytes       Limit = "bytes"
+	LimitObjects     Limit = "objects"
+	LimitValues      Limit = "values"
+	LimitNameLength  Limit = "name length"
+	LimitValueLength Limit = "value length"
+)
+
+// Limits bounds the input of the Decoder, so broken or hostile files can't exhaust memory. Zero means no limit.
+type Limits struct {
+	MaxBytes       int // Size of the input.
+	MaxObjects     int // Number of blocks, including the Start- and End-message and unknown blocks.
+	MaxValues      int // Number of values in a time series.
+	MaxNameLength  int // Length of block and attribute names.
+	MaxValueLength int // Length of attribute values, and of each value in an array.
+}
+
+// DecodeLimits makes the Decoder stop with a LimitError when the input exceeds l.
+func DecodeLimits(l Limits) DecoderOption {
+	return func(o *decoderOptions) {
+		o.limits = l
+	}
+}
+
+// LimitError is returned by the Decoder when the input exceeds one of its Limits.
+type LimitError struct {
+	Limit     Limit
+	Max       int
+	Offset    int    // Number of bytes read when the limit was exceeded.
+	Line      int    // Line where the limit was exceeded, starting at 1.
+	Object    string // Block being decoded, if any.
+	Index     int    // Index of the block among the blocks with the same name.
+	Attribute string // Attribute being decoded, if any.
+}
+
+func (e *LimitError) Error() string {
+	var where []string
+	if e.Object != "" {
+		where = append(where, fmt.Sprintf("%s %d", e.Object, e.Index))
+	}
+	if e.Attribute != "" {
+		where = append(where, e.Attribute)
+	}
+
+	location := fmt.Sprintf("line %d", e.Line)
+	if len(where) > 0 {
+		location += " (" + strings.Join(where, ", ") + ")"
+	}
+
+	return fmt.Sprintf("%s: %s exceeds limit of %d", location, e.Limit, e.Max)
+}
+
+// exceeds returns a LimitError if n is more than max, and max is set.
+func (d *Decoder) exceeds(limit Limit, max, n int) error {
+	if max <= 0 || n <= max {
+		return nil
+	}
+
+	// The limit was exceeded by the last byte read.
+	last := d.bytesRead - 1
+	if last < 0 {
+		last = 0
+	}
+
+	return &LimitError{
+		Limit:     limit,
+		Max:       max,
+		Offset:    d.bytesRead,
+		Line:      d.line(last),
+		Object:    d.object,
+		Index:     d.objectIndex,
+		Attribute: string(d.attributeName),
+	}
+}
@@ -0,0 +1,56 @@
+package gs2
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestDecodeLimits(t *testing.T) {
+	for _, test := range []struct {
+		limits   Limits
+		expected LimitError
+		message  string
+	}{
+		{Limits{MaxBytes: 100}, LimitError{Limit: LimitBytes, Max: 100, Line: 7}, "line 7: bytes exceeds limit of 100"},
+		{Limits{MaxObjects: 3}, LimitError{Limit: LimitObjects, Max: 3, Line: 35}, "line 35: objects exceeds limit of 3"},
+		{Limits{MaxValues: 10}, LimitError{Limit: LimitValues, Max: 10, Line: 18, Object: "Time-series", Attribute: "Value"},
+			"line 18 (Time-series 0, Value): values exceeds limit of 10"},
+		{Limits{MaxNameLength: 13}, LimitError{Limit: LimitNameLength, Max: 13, Line: 17, Object: "Time-series"},
+			"line 17 (Time-series 0): name length exceeds limit of 13"},
+		{Limits{MaxValueLength: 12}, LimitError{Limit: LimitValueLength, Max: 12, Line: 3, Object: "Start-message", Attribute: "Message-type"},
+			"line 3 (Start-message 0, Message-type): value length exceeds limit of 12"},
+	} {
+		file, err := os.Open("testdata/timeseries.gs2")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = NewDecoder(file, DecodeLimits(test.limits)).Decode()
+		file.Close()
+
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) {
+			t.Errorf("expected a LimitError for %+v, but got %v", test.limits, err)
+			continue
+		}
+
+		limitErr.Offset = 0
+		if *limitErr != test.expected || err.Error() != test.message {
+			t.Errorf("expected %+v (%s), but got %+v (%v)", test.expected, test.message, *limitErr, err)
+		}
+	}
+}
+
+func TestDecodeLimits_Within(t *testing.T) {
+	file, err := os.Open("testdata/timeseries.gs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	limits := Limits{MaxBytes: 1 << 20, MaxObjects: 10, MaxValues: 100, MaxNameLength: 32, MaxValueLength: 64}
+	if _, err := NewDecoder(file, DecodeLimits(limits)).Decode(); err != nil {
+		t.Errorf("expected no error within the limits, but got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package gs2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Defect is a kind of defect in a variant made by Mutate.
+type Defect string
+
+// Defects made by Mutate.
+const (
+	DefectSum               Defect = "wrong-sum"           // Sum of a time series is off.
+	DefectNoOfValues        Defect = "wrong-no-of-values"  // No-of-values of a time series is one too many.
+	DefectTruncatedValues   Defect = "truncated-values"    // The last value of a time series is left out.
+	DefectUnclosedValues    Defect = "unclosed-values"     // The > closing the values of a time series is left out.
+	DefectMissingEndMessage Defect = "missing-end-message" // The file ends before the End-message.
+	DefectHour24            Defect = "hour-24"             // Stop of a time series is 24:00:00 of the day it ends, not the day before.
+	DefectTimeOutOfRange    Defect = "time-out-of-range"   // The Time of the Start-message is 24:30:00.
+	DefectLatin1            Defect = "latin-1"             // A text attribute is in ISO 8859-1, not UTF-8.
+	DefectSpaceDelimiter    Defect = "space-delimiter"     // An attribute name is followed by a space instead of =.
+)
+
+// Variant is a defective variant of a file made by Mutate.
+type Variant struct {
+	Defect      Defect   `json:"defect"`
+	Rule        string   `json:"rule"`               // ID of the rule expected to find the defect, or CategorySyntax.
+	Severity    Severity `json:"severity"`           // Severity of the rule among the DefaultRules.
+	Disabled    bool     `json:"disabled,omitempty"` // The rule is strict, so it must be enabled to find the defect.
+	Description string   `json:"description"`
+	Data        []byte   `json:"-"`
+}
+
+// Mutate returns a variant of the GS2 file in data for each defect that applies to it, e.g. defects of time series only
+// apply to files with time series. The file must pass the enabled DefaultRules and the strict rules the variants are labelled
+// with, so each variant has no other defects than its own. The variants are made from the file as encoded by the Encoder, so
+// they differ from data in formatting too.
+func Mutate(data []byte) ([]Variant, error) {
+	g, err := NewDecoder(bytes.NewReader(data), DecodeValidators()).Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := DefaultRules().Enable("utf-8")
+	if err != nil {
+		return nil, err
+	}
+	if findings := rules.Check(g); len(findings) > 0 {
+		return nil, fmt.Errorf("gs2: only valid files can be mutated: %v", findings[0])
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(g); err != nil {
+		return nil, err
+	}
+	m := mutator{data: buf.Bytes()}
+
+	// The number of objects in the Start-message would still be right without the End-message, so it is removed too.
+	description := "End-message removed"
+	var clearStart func(g *GS2)
+	if g.StartMessage.NumberOfObjects != 0 {
+		description += ", and Number-of-objects of the Start-message"
+		clearStart = func(g *GS2) {
+			g.StartMessage.NumberOfObjects = 0
+		}
+	}
+	m.mutate(DefectMissingEndMessage, "no-of-objects", description, clearStart, func(data []byte) []byte {
+		return data[:bytes.LastIndex(data, []byte("##End-message"))]
+	})
+
+	if len(g.TimeSeries) > 0 {
+		ts := g.TimeSeries[0]
+		name := fmt.Sprintf("Time-series 0 (%s)", ts.Reference)
+
+		m.mutate(DefectSum, "time-series-values", "Sum of "+name+" is 1 more than the sum of its values", func(g *GS2) {
+			g.TimeSeries[0].Sum = round(g.TimeSeries[0].Sum + 1)
+		}, nil)
+
+		m.mutate(DefectNoOfValues, "time-series-values", fmt.Sprintf("No-of-values of %s is %d, but it has %d values", name,
+			ts.NoOfValues+1, len(ts.Value)), func(g *GS2) {
+			g.TimeSeries[0].NoOfValues++
+		}, nil)
+
+		if len(ts.Value) > 0 {
+			m.mutate(DefectTruncatedValues, "time-series-values", "last value of "+name+" removed, keeping No-of-values and Sum",
+				func(g *GS2) {
+					g.TimeSeries[0].Value = g.TimeSeries[0].Value[:len(ts.Value)-1]
+				}, nil)
+		}
+
+		m.mutate(DefectUnclosedValues, CategorySyntax, "> closing the values of "+name+" removed", nil, func(data []byte) []byte {
+			start := bytes.Index(data, []byte("##Time-series"))
+			end := start + bytes.Index(data[start:], []byte("#Value=<"))
+			end += bytes.IndexByte(data[end:], '>')
+			return append(append([]byte(nil), data[:end]...), data[end+1:]...)
+		})
+
+		// The Stop a day late is only found from the values if they don't have explicit times.
+		if stop := ts.Stop.In(g.StartMessage.Location()); stop.Hour() == 0 && stop.Minute() == 0 && stop.Second() == 0 &&
+			!explicitTimes(ts) {
+			written := stop.Format("2006-01-02") + ".24:00:00"
+			m.mutate(DefectHour24, "time-series-period", fmt.Sprintf("Stop of %s is %s, a day after %s", name, written,
+				stop.Format(gs2TimeLayout)), nil, func(data []byte) []byte {
+				return replaceAttribute(data, "##Time-series", "#Stop=", written)
+			})
+		}
+	}
+
+	day := g.StartMessage.Time.In(g.StartMessage.Location()).Format("2006-01-02")
+	m.mutate(DefectTimeOutOfRange, CategorySyntax, "Time of the Start-message is "+day+".24:30:00", nil, func(data []byte) []byte {
+		return replaceAttribute(data, "##Start-message", "#Time=", day+".24:30:00")
+	})
+
+	m.mutate(DefectLatin1, "utf-8", "Description of the Start-message is in ISO 8859-1", func(g *GS2) {
+		g.StartMessage.Description = "M\xe5lerverdier fra Tr\xf8ndelag"
+	}, nil)
+
+	m.mutate(DefectSpaceDelimiter, CategorySyntax, "Id of the Start-message is followed by a space instead of =", nil,
+		func(data []byte) []byte {
+			return bytes.Replace(data, []byte("#Id="), []byte("#Id "), 1)
+		})
+
+	return m.variants, m.err
+}
+
+// mutator makes the variants of an encoded file.
+type mutator struct {
+	data     []byte
+	variants []Variant
+	err      error
+}
+
+// mutate adds a variant with the defect made by decoding the file, changing it with change, encoding it without
+// validators, and then changing the encoded bytes with edit. Both change and edit may be nil.
+func (m *mutator) mutate(defect Defect, rule, description string, change func(g *GS2), edit func(data []byte) []byte) {
+	if m.err != nil {
+		return
+	}
+
+	data := m.data
+	if change != nil {
+		g, err := NewDecoder(bytes.NewReader(m.data), DecodeValidators()).Decode()
+		if err != nil {
+			m.err = err
+			return
+		}
+		change(g)
+
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf, EncodeValidators()).Encode(g); err != nil {
+			m.err = err
+			return
+		}
+		data = buf.Bytes()
+	}
+
+	if edit != nil {
+		data = edit(data)
+	}
+
+	v := Variant{Defect: defect, Rule: rule, Severity: SeverityError, Description: description, Data: data}
+	if r, ok := DefaultRules().Lookup(rule); ok {
+		v.Severity, v.Disabled = r.Severity, r.Disabled
+	}
+	m.variants = append(m.variants, v)
+}
+
+// replaceAttribute returns data with the value of the first attribute starting with prefix, e.g. #Stop=, after the first
+// block starting with block replaced with value.
+func replaceAttribute(data []byte, block, prefix, value string) []byte {
+	start := bytes.Index(data, []byte(block))
+	start += bytes.Index(data[start:], []byte(prefix)) + len(prefix)
+	end := start + bytes.IndexByte(data[start:], '\n')
+
+	result := append([]byte(nil), data[:start]...)
+	result = append(result, value...)
+	return append(result, data[end:]...)
+}
+
+// Caught reports whether the defect of v is found: by its rule among the DefaultRules, at any severity and enabled for the
+// check even if it is strict, or by the decoder if its rule is CategorySyntax. Severity and Disabled tell whether the
+// defect fails validation with the DefaultRules as they are.
+func (v *Variant) Caught() (bool, error) {
+	g, err := NewDecoder(bytes.NewReader(v.Data), DecodeValidators()).Decode()
+	var syntaxErr *SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return v.Rule == CategorySyntax, nil
+	case err != nil:
+		return false, err
+	}
+
+	// A variant expected to fail decoding isn't caught by a rule.
+	rules, err := DefaultRules().Only(v.Rule)
+	if err != nil {
+		return false, nil
+	}
+
+	return len(rules.Check(g)) > 0, nil
+}
+
+// explicitTimes reports whether any value of ts has an explicit time.
+func explicitTimes(ts TimeSeries) bool {
+	for _, v := range ts.Value {
+		if !v.Time.IsZero() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,121 @@
+package gs2
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMutate(t *testing.T) {
+	oslo, err := time.LoadLocation("Europe/Oslo")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	readings, err := (&Generator{Meters: 2, Readings: 3}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	readings.TimeSeries = nil
+	readings.EndMessage.NumberOfObjects = len(readings.MeterReadings) + 2
+
+	startObjects, err := (&Generator{}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	startObjects.StartMessage.NumberOfObjects = startObjects.EndMessage.NumberOfObjects
+
+	tests := []struct {
+		name    string
+		gen     Generator
+		g       *GS2
+		defects []Defect
+	}{
+		{"hours", Generator{Meters: 2, Readings: 2}, nil, []Defect{DefectMissingEndMessage, DefectSum, DefectNoOfValues,
+			DefectTruncatedValues, DefectUnclosedValues, DefectHour24, DefectTimeOutOfRange, DefectLatin1, DefectSpaceDelimiter}},
+		{"autumn", Generator{Location: oslo, DST: DSTAutumn, Profiles: Profiles, Gaps: 0.1, Qualities: 0.1}, nil,
+			[]Defect{DefectMissingEndMessage, DefectSum, DefectNoOfValues, DefectTruncatedValues, DefectUnclosedValues, DefectHour24,
+				DefectTimeOutOfRange, DefectLatin1, DefectSpaceDelimiter}},
+		{"not midnight", Generator{Stop: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}, nil, []Defect{DefectMissingEndMessage,
+			DefectSum, DefectNoOfValues, DefectTruncatedValues, DefectUnclosedValues, DefectTimeOutOfRange, DefectLatin1,
+			DefectSpaceDelimiter}},
+		{"meter readings", Generator{}, readings, []Defect{DefectMissingEndMessage, DefectTimeOutOfRange, DefectLatin1,
+			DefectSpaceDelimiter}},
+		{"objects in start", Generator{}, startObjects, []Defect{DefectMissingEndMessage, DefectSum, DefectNoOfValues,
+			DefectTruncatedValues, DefectUnclosedValues, DefectHour24, DefectTimeOutOfRange, DefectLatin1, DefectSpaceDelimiter}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := test.g
+			if g == nil {
+				if g, err = test.gen.Generate(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			variants, err := Mutate(encodeTest(t, g))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var defects []Defect
+			for _, v := range variants {
+				defects = append(defects, v.Defect)
+
+				caught, err := v.Caught()
+				if err != nil {
+					t.Fatalf("%s: %v", v.Defect, err)
+				}
+				if !caught {
+					t.Errorf("%s: expected %s to catch %q", v.Defect, v.Rule, v.Description)
+				}
+
+				severity, disabled := SeverityError, v.Defect == DefectLatin1
+				if v.Defect == DefectHour24 {
+					severity = SeverityWarning
+				}
+				if v.Severity != severity || v.Disabled != disabled {
+					t.Errorf("%s: expected severity %v and disabled %v, but got %v and %v", v.Defect, severity, disabled, v.Severity,
+						v.Disabled)
+				}
+			}
+			if !reflect.DeepEqual(defects, test.defects) {
+				t.Errorf("expected defects %v, but got %v", test.defects, defects)
+			}
+		})
+	}
+}
+
+func TestMutate_Invalid(t *testing.T) {
+	if _, err := Mutate([]byte("##Start-message\n#Id 1\n")); err == nil {
+		t.Error("expected error for a file that can't be decoded")
+	}
+
+	g, err := (&Generator{Errors: 1}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Mutate(encodeTest(t, g)); err == nil {
+		t.Error("expected error for an invalid file")
+	}
+}
+
+func TestVariant_Caught(t *testing.T) {
+	g, err := (&Generator{}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := encodeTest(t, g)
+
+	for _, v := range []Variant{
+		{Defect: DefectSum, Rule: "time-series-values", Data: data},
+		{Defect: DefectSum, Rule: CategorySyntax, Data: data},
+		{Defect: DefectSpaceDelimiter, Rule: "utf-8", Data: bytes.Replace(data, []byte("#Id="), []byte("#Id "), 1)},
+	} {
+		if caught, err := v.Caught(); caught || err != nil {
+			t.Errorf("%s: expected %s not to catch the defect, but got %v, %v", v.Defect, v.Rule, caught, err)
+		}
+	}
+}
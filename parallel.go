@@ -0,0 +1,147 @@
+package gs2
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// parallelChunkSize is the smallest part of the input decoded by one goroutine.
+var parallelChunkSize = 64 << 10
+
+// errBoundary is returned when a part of the input doesn't end where the next part starts, i.e. it was split at a ## that
+// the sequential Decoder wouldn't see as the start of a block.
+var errBoundary = errors.New("gs2: part doesn't end at a block boundary")
+
+// DecodeParallel makes the Decoder split large inputs at block boundaries and decode the parts on n goroutines. n less than
+// 1 uses GOMAXPROCS goroutines. The result, the lines of BlockLine and any error are the same as when decoding sequentially,
+// since the input is decoded again sequentially if decoding a part fails.
+func DecodeParallel(n int) DecoderOption {
+	return func(o *decoderOptions) {
+		if n < 1 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		o.workers = n
+	}
+}
+
+// decodeParallel decodes the buffer into v on the goroutines of the options, and reports whether it succeeded. v and the
+// state of d are unchanged if it didn't.
+func (d *Decoder) decodeParallel(v reflect.Value) bool {
+	chunkSize := len(d.buf) / (d.options.workers * 4)
+	if chunkSize < parallelChunkSize {
+		chunkSize = parallelChunkSize
+	}
+
+	bounds := blockBoundaries(d.buf, chunkSize)
+	if len(bounds) == 0 {
+		return false
+	}
+
+	// Part i starts at bounds[i-1], or at the start of the input, and ends at bounds[i], or after the end of the input.
+	ends := append(bounds, len(d.buf)+1)
+	parts := make([]*Decoder, len(ends))
+	results := make([]reflect.Value, len(ends))
+	errs := make([]error, len(ends))
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < d.options.workers && w < len(ends); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				start := 0
+				if i > 0 {
+					start = ends[i-1]
+				}
+
+				parts[i] = &Decoder{
+					options:      d.options,
+					ctx:          d.ctx,
+					buf:          d.buf,
+					scan:         scanner{step: stateBeginScan},
+					blockOffsets: make(map[string][]int),
+				}
+				results[i] = reflect.New(reflect.Indirect(v).Type())
+				errs[i] = parts[i].decodeRange(results[i], start, ends[i])
+			}
+		}()
+	}
+
+	for i := range ends {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	objects := 0
+	for i, err := range errs {
+		if err != nil {
+			return false
+		}
+		objects += parts[i].objects
+	}
+	if max := d.options.limits.MaxObjects; max > 0 && objects > max {
+		return false
+	}
+
+	// Blocks of single fields replace the previous ones, like when decoding sequentially.
+	indirect := reflect.Indirect(v)
+	names := cachedFields(indirect.Type()).names
+	for i, part := range parts {
+		result := reflect.Indirect(results[i])
+		for f := 0; f < indirect.NumField(); f++ {
+			if len(part.blockOffsets[names[f]]) == 0 {
+				continue
+			}
+
+			if field := indirect.Field(f); field.Kind() == reflect.Slice {
+				field.Set(reflect.AppendSlice(field, result.Field(f)))
+			} else {
+				field.Set(result.Field(f))
+			}
+		}
+
+		for name, offsets := range part.blockOffsets {
+			d.blockOffsets[name] = append(d.blockOffsets[name], offsets...)
+		}
+	}
+
+	d.objects = objects
+	d.bytesRead = len(d.buf)
+	return true
+}
+
+// blockBoundaries returns the offsets after the ## starting a block where the input can be split in parts of at least
+// size bytes. ## in arrays and ### are skipped. The Decoder checks that the parts end at the offsets, see decodeRange.
+func blockBoundaries(buf []byte, size int) []int {
+	var bounds []int
+
+	last := 0
+	inArray := false
+	for i := 1; i < len(buf); i++ {
+		switch buf[i] {
+		case '<':
+			inArray = true
+		case '>':
+			inArray = false
+		case '#':
+			if inArray || buf[i-1] != '#' || (i > 1 && buf[i-2] == '#') || (i+1 < len(buf) && buf[i+1] == '#') {
+				continue
+			}
+			if i+1-last >= size && len(buf)-(i+1) >= size {
+				bounds = append(bounds, i+1)
+				last = i + 1
+			}
+		}
+	}
+
+	return bounds
+}
+
+// inState reports whether the scanner is in the given state.
+func (s *scanner) inState(step func(*scanner, byte) int) bool {
+	return reflect.ValueOf(s.step).Pointer() == reflect.ValueOf(step).Pointer()
+}
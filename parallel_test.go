@@ -0,0 +1,108 @@
+package gs2
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// decodeBoth decodes data sequentially and in parallel, split at every block, and fails if the results differ.
+func decodeBoth(t *testing.T, data []byte) {
+	t.Helper()
+
+	defer func(size int) { parallelChunkSize = size }(parallelChunkSize)
+	parallelChunkSize = 1
+
+	seq := NewDecoder(bytes.NewReader(data))
+	expected, expectedErr := seq.Decode()
+
+	par := NewDecoder(bytes.NewReader(data), DecodeParallel(4))
+	g, err := par.Decode()
+
+	if !reflect.DeepEqual(g, expected) || !reflect.DeepEqual(err, expectedErr) {
+		t.Fatalf("expected %+v, %v, but got %+v, %v", expected, expectedErr, g, err)
+	}
+
+	for name, offsets := range seq.blockOffsets {
+		for i := range offsets {
+			if line, expectedLine := par.BlockLine(name, i), seq.BlockLine(name, i); line != expectedLine {
+				t.Errorf("expected %s %d at line %d, but got %d", name, i, expectedLine, line)
+			}
+		}
+	}
+}
+
+func TestDecodeParallel(t *testing.T) {
+	for _, name := range []string{"testdata/timeseries.gs2", "testdata/timeseries_noNewlines.gs2", "testdata/meterreading.gs2",
+		"testdata/durations.gs2"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decodeBoth(t, data)
+
+		// Errors in a later block are reported at the same line.
+		decodeBoth(t, append(append([]byte(nil), data...), "\n##Time-series\n#Step=x\n"...))
+	}
+
+	for _, data := range []string{
+		"##Start-message\n#Id=1\n##Unknown\n#A=< ## >\n##End-message\n#Id=1\n#Number-of-objects=3\n",
+		"##Start-message\n#Id=1\n###Time-series\n#Reference=a\n",
+		"##Start-message\n#Id=1\n##Start-message\n##End-message\n#Number-of-objects=0",
+		"##Start-message\n#Id##Meter-reading\n#Reference=a",
+	} {
+		decodeBoth(t, []byte(data))
+	}
+}
+
+func TestDecodeParallel_Limits(t *testing.T) {
+	data, err := os.ReadFile("testdata/meterreading.gs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(size int) { parallelChunkSize = size }(parallelChunkSize)
+	parallelChunkSize = 1
+
+	// Each part is within the limit, but the file isn't.
+	_, err = NewDecoder(bytes.NewReader(data), DecodeParallel(4), DecodeLimits(Limits{MaxObjects: 3})).Decode()
+	if limitErr, ok := err.(*LimitError); !ok || limitErr.Limit != LimitObjects {
+		t.Errorf("expected the objects limit to be exceeded, but got %v", err)
+	}
+}
+
+func FuzzDecodeParallel(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Add([]byte("##Start-message\n#Id=1\n##Time-series\n#Value=< 1 2 >\n##End-message\n#Id=1"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decodeBoth(t, data)
+	})
+}
+
+func BenchmarkDecoder_Parallel(b *testing.B) {
+	g := longTimeSeries(1000)
+	for i := 0; i < 200; i++ {
+		g.TimeSeries = append(g.TimeSeries, g.TimeSeries[0])
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, EncodeValidators()).Encode(g); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 4} {
+		b.Run(strconv.Itoa(workers), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				if _, err := NewDecoder(bytes.NewReader(buf.Bytes()), DecodeValidators(), DecodeParallel(workers)).Decode(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
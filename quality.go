@@ -0,0 +1,303 @@
+package gs2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QualityMeaning is the meaning of a quality code.
+type QualityMeaning string
+
+// Meanings of quality codes. Unknown codes have the meaning MeaningUnknown.
+const (
+	MeaningUnknown   QualityMeaning = ""
+	MeaningMeasured  QualityMeaning = "measured"
+	MeaningCorrected QualityMeaning = "corrected"
+	MeaningEstimated QualityMeaning = "estimated"
+	MeaningMissing   QualityMeaning = "missing"
+)
+
+// defaultRanks are the ranks of codes where the rank isn't given. Higher ranks are worse.
+var defaultRanks = map[QualityMeaning]int{
+	MeaningMeasured:  0,
+	MeaningCorrected: 10,
+	MeaningEstimated: 20,
+	MeaningMissing:   30,
+}
+
+// unknownRank is the rank of unknown codes, which are worse than every known code.
+const unknownRank = math.MaxInt32
+
+// QualityCode describes a quality code.
+type QualityCode struct {
+	Code        Quality        `json:"code"`
+	Meaning     QualityMeaning `json:"meaning"`
+	Rank        int            `json:"rank"` // Ordering for worst of aggregation. Higher is worse.
+	Description string         `json:"description,omitempty"`
+}
+
+// QualityRegistry maps quality codes to their meaning.
+type QualityRegistry struct {
+	codes map[Quality]QualityCode
+}
+
+// DefaultQualityRegistry contains the quality codes defined by GS2 1.2. It is used by the quality helpers of TimeSeries.
+var DefaultQualityRegistry = NewQualityRegistry(
+	QualityCode{Code: QualityNone, Meaning: MeaningMeasured, Rank: 0, Description: "OK"},
+	QualityCode{Code: QualityOK, Meaning: MeaningMeasured, Rank: 0, Description: "OK"},
+	QualityCode{Code: QualityCorrected, Meaning: MeaningCorrected, Rank: 10, Description: "Corrected"},
+	QualityCode{Code: QualityEstimated, Meaning: MeaningEstimated, Rank: 20, Description: "Estimated"},
+	QualityCode{Code: QualityMissing, Meaning: MeaningMissing, Rank: 30, Description: "Missing"},
+)
+
+// NewQualityRegistry returns a registry with the given codes.
+func NewQualityRegistry(codes ...QualityCode) *QualityRegistry {
+	r := &QualityRegistry{codes: map[Quality]QualityCode{}}
+	for _, c := range codes {
+		r.codes[c.Code] = c
+	}
+
+	return r
+}
+
+// With returns a copy of r with the given codes added, replacing codes already in r.
+func (r *QualityRegistry) With(codes ...QualityCode) *QualityRegistry {
+	result := NewQualityRegistry(r.Codes()...)
+	for _, c := range codes {
+		result.codes[c.Code] = c
+	}
+
+	return result
+}
+
+// Codes returns the codes in r, ordered by rank and code.
+func (r *QualityRegistry) Codes() []QualityCode {
+	codes := make([]QualityCode, 0, len(r.codes))
+	for _, c := range r.codes {
+		codes = append(codes, c)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if codes[i].Rank != codes[j].Rank {
+			return codes[i].Rank < codes[j].Rank
+		}
+		return codes[i].Code < codes[j].Code
+	})
+
+	return codes
+}
+
+// Lookup returns the description of q, and false if q is unknown.
+func (r *QualityRegistry) Lookup(q Quality) (QualityCode, bool) {
+	c, ok := r.codes[q]
+	return c, ok
+}
+
+// Meaning returns the meaning of q, or MeaningUnknown if q is unknown.
+func (r *QualityRegistry) Meaning(q Quality) QualityMeaning {
+	return r.codes[q].Meaning
+}
+
+// Rank returns the rank of q. Unknown codes rank worse than all known codes.
+func (r *QualityRegistry) Rank(q Quality) int {
+	if c, ok := r.codes[q]; ok {
+		return c.Rank
+	}
+
+	return unknownRank
+}
+
+// Worst returns the code with the worst rank among qualities. Of codes with the same rank, the first is returned.
+func (r *QualityRegistry) Worst(qualities ...Quality) Quality {
+	var worst Quality
+	rank := -1
+	for _, q := range qualities {
+		if qr := r.Rank(q); qr > rank {
+			worst, rank = q, qr
+		}
+	}
+
+	return worst
+}
+
+// Missing reports whether q means that the value is missing.
+func (r *QualityRegistry) Missing(q Quality) bool {
+	return r.Meaning(q) == MeaningMissing
+}
+
+// MissingCount returns the number of values in ts that are missing.
+func (r *QualityRegistry) MissingCount(ts TimeSeries) int {
+	var n int
+	for _, v := range ts.Value {
+		if r.Missing(v.Quality) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Sum returns the sum of the values in ts that aren't missing.
+func (r *QualityRegistry) Sum(ts TimeSeries) float64 {
+	var sum float64
+	for _, v := range ts.Value {
+		if !r.Missing(v.Quality) {
+			sum += v.Value
+		}
+	}
+
+	return sum
+}
+
+// WorstQuality returns the worst quality of the values in ts.
+func (r *QualityRegistry) WorstQuality(ts TimeSeries) Quality {
+	qualities := make([]Quality, len(ts.Value))
+	for i, v := range ts.Value {
+		qualities[i] = v.Quality
+	}
+
+	return r.Worst(qualities...)
+}
+
+// MissingCount returns the number of values in ts that are missing according to DefaultQualityRegistry.
+func (ts *TimeSeries) MissingCount() int {
+	return DefaultQualityRegistry.MissingCount(*ts)
+}
+
+// WorstQuality returns the worst quality of the values in ts according to DefaultQualityRegistry.
+func (ts *TimeSeries) WorstQuality() Quality {
+	return DefaultQualityRegistry.WorstQuality(*ts)
+}
+
+// QualityConfig holds the quality codes of each sender. Senders are identified by the From attribute of the Start-message.
+type QualityConfig struct {
+	Default *QualityRegistry            // Codes for senders without their own codes.
+	Senders map[string]*QualityRegistry // Codes by sender.
+}
+
+// Registry returns the registry for sender.
+func (c *QualityConfig) Registry(sender string) *QualityRegistry {
+	if r, ok := c.Senders[sender]; ok {
+		return r
+	}
+
+	if c.Default != nil {
+		return c.Default
+	}
+
+	return DefaultQualityRegistry
+}
+
+type qualityCodeConfig struct {
+	Code        Quality        `json:"code"`
+	Meaning     QualityMeaning `json:"meaning"`
+	Rank        *int           `json:"rank"`
+	Description string         `json:"description"`
+}
+
+type qualityConfigFile struct {
+	Qualities []qualityCodeConfig            `json:"qualities"`
+	Senders   map[string][]qualityCodeConfig `json:"senders"`
+}
+
+// LoadQualityConfig reads a quality configuration in JSON from r. The codes in "qualities" are added to the codes of GS2 1.2,
+// and the codes of each sender in "senders" are added to those again:
+//
+//	{
+//	  "qualities": [{"code": "x", "meaning": "missing"}],
+//	  "senders": {"supplier": [{"code": "E", "meaning": "estimated", "rank": 25}]}
+//	}
+//
+// Meanings are measured, corrected, estimated or missing. The rank defaults to 0, 10, 20 and 30 for the meanings.
+func LoadQualityConfig(r io.Reader) (*QualityConfig, error) {
+	var file qualityConfigFile
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, err
+	}
+
+	codes, err := qualityCodes(file.Qualities)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &QualityConfig{
+		Default: DefaultQualityRegistry.With(codes...),
+		Senders: map[string]*QualityRegistry{},
+	}
+
+	for sender, senderCodes := range file.Senders {
+		codes, err := qualityCodes(senderCodes)
+		if err != nil {
+			return nil, fmt.Errorf("sender %q: %w", sender, err)
+		}
+		config.Senders[sender] = config.Default.With(codes...)
+	}
+
+	return config, nil
+}
+
+func qualityCodes(configs []qualityCodeConfig) ([]QualityCode, error) {
+	var codes []QualityCode
+	for _, c := range configs {
+		rank, ok := defaultRanks[c.Meaning]
+		if !ok {
+			return nil, fmt.Errorf("quality %q: unknown meaning %q", c.Code, c.Meaning)
+		}
+		if c.Rank != nil {
+			rank = *c.Rank
+		}
+
+		codes = append(codes, QualityCode{Code: c.Code, Meaning: c.Meaning, Rank: rank, Description: c.Description})
+	}
+
+	return codes, nil
+}
+
+// ValidateQualities validates the qualities of g against DefaultQualityRegistry, see ValidateQualitiesWith.
+func ValidateQualities(g *GS2) error {
+	return validateQualities(g, DefaultQualityRegistry)
+}
+
+// ValidateQualitiesWith returns a Validator checking that the quality codes of all values are known to the registry of the
+// sender of the file, and that the Sum of each time series equals the sum of the values that aren't missing.
+func ValidateQualitiesWith(c *QualityConfig) Validator {
+	return func(g *GS2) error {
+		return validateQualities(g, c.Registry(g.StartMessage.From))
+	}
+}
+
+func validateQualities(g *GS2, r *QualityRegistry) error {
+	var errs ValidationErrors
+
+	for i, mr := range g.MeterReadings {
+		if _, ok := r.Lookup(mr.Value.Quality); !ok {
+			errs = append(errs, &ObjectError{Object: "Meter-reading", Index: i, Reference: mr.Reference,
+				Err: fmt.Errorf("unknown quality %q", mr.Value.Quality)})
+		}
+	}
+
+	for i, ts := range g.TimeSeries {
+		problems := unknownQualities(ts, func(q Quality) bool {
+			_, ok := r.Lookup(q)
+			return ok
+		})
+
+		if sum := r.Sum(ts); math.Abs(sum-ts.Sum) > delta {
+			problems = append(problems, fmt.Sprintf("sum of values that aren't missing is %s, but Sum is %s",
+				strconv.FormatFloat(sum, 'f', -1, 64), strconv.FormatFloat(ts.Sum, 'f', -1, 64)))
+		}
+
+		if len(problems) > 0 {
+			errs = append(errs, timeSeriesError(i, ts, errors.New(strings.Join(problems, ", "))))
+		}
+	}
+
+	return errs.err()
+}
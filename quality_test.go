@@ -0,0 +1,97 @@
+package gs2
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQualityRegistry_Worst(t *testing.T) {
+	r := DefaultQualityRegistry
+
+	for _, test := range []struct {
+		qualities []Quality
+		expected  Quality
+	}{
+		{[]Quality{QualityOK, QualityNone}, QualityOK},
+		{[]Quality{QualityOK, QualityEstimated, QualityCorrected}, QualityEstimated},
+		{[]Quality{QualityMissing, QualityEstimated}, QualityMissing},
+		{[]Quality{QualityMissing, "vendor"}, "vendor"},
+	} {
+		if worst := r.Worst(test.qualities...); worst != test.expected {
+			t.Errorf("expected worst of %q to be %q, but got %q", test.qualities, test.expected, worst)
+		}
+	}
+}
+
+func TestTimeSeries_MissingCount(t *testing.T) {
+	ts := TimeSeries{Value: []Triplet{{Value: 1}, {Value: 0, Quality: QualityMissing}, {Value: 2, Quality: QualityEstimated}, {Quality: QualityMissing}}}
+
+	if n := ts.MissingCount(); n != 2 {
+		t.Errorf("expected 2 missing values, but got %d", n)
+	}
+
+	if q := ts.WorstQuality(); q != QualityMissing {
+		t.Errorf("expected worst quality %q, but got %q", QualityMissing, q)
+	}
+}
+
+func TestLoadQualityConfig(t *testing.T) {
+	config, err := LoadQualityConfig(strings.NewReader(`{
+		"qualities": [{"code": "x", "meaning": "missing"}],
+		"senders": {"supplier": [{"code": "E", "meaning": "estimated", "rank": 25}, {"code": "x", "meaning": "estimated"}]}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m := config.Registry("other").Meaning("x"); m != MeaningMissing {
+		t.Errorf("expected x to be missing for other senders, but got %q", m)
+	}
+
+	supplier := config.Registry("supplier")
+	if m := supplier.Meaning("x"); m != MeaningEstimated {
+		t.Errorf("expected x to be estimated for supplier, but got %q", m)
+	}
+	if worst := supplier.Worst("E", QualityEstimated, QualityOK); worst != "E" {
+		t.Errorf("expected E to be worst, but got %q", worst)
+	}
+	if m := supplier.Meaning(QualityMissing); m != MeaningMissing {
+		t.Errorf("expected GS2 codes to be kept, but got %q", m)
+	}
+
+	for _, src := range []string{`{"qualities": [{"code": "x", "meaning": "broken"}]}`, `{"codes": []}`, `{`} {
+		if _, err := LoadQualityConfig(strings.NewReader(src)); err == nil {
+			t.Errorf("expected error for %s", src)
+		}
+	}
+}
+
+func TestValidateQualitiesWith(t *testing.T) {
+	config, err := LoadQualityConfig(strings.NewReader(`{"senders": {"supplier": [{"code": "x", "meaning": "missing"}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &GS2{
+		StartMessage: StartMessage{From: "supplier"},
+		TimeSeries: []TimeSeries{
+			{Value: []Triplet{{Value: 1}, {Value: 99, Quality: "x"}}, NoOfValues: 2, Sum: 1},
+		},
+	}
+
+	validate := ValidateQualitiesWith(config)
+	if err := validate(g); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	g.TimeSeries[0].Sum = 100
+	if err := validate(g); err == nil {
+		t.Errorf("expected Sum including missing values to be invalid")
+	}
+
+	g.TimeSeries[0].Sum = 1
+	g.StartMessage.From = "other"
+	if err := validate(g); err == nil || !strings.Contains(err.Error(), `unknown quality "x" in values 1`) {
+		t.Errorf("expected x to be unknown for other senders, but got %v", err)
+	}
+}
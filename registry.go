@@ -0,0 +1,347 @@
+package gs2
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// MeteringPoint is a metering point as registered in a Registry. Empty attributes aren't checked, neither in the registration
+// nor in the checked objects.
+type MeteringPoint struct {
+	Reference     string
+	Meter         string
+	Installation  string
+	MeterLocation string
+	Channels      []string      // Channels of the meter.
+	Unit          string        // Expected unit of values.
+	Step          time.Duration // Expected step of time series.
+	ActiveFrom    time.Time     // First time the metering point is active.
+	ActiveTo      time.Time     // First time the metering point is no longer active, or zero if it still is.
+}
+
+// Active reports whether p is active from start until stop.
+func (p MeteringPoint) Active(start, stop time.Time) bool {
+	if start.Before(p.ActiveFrom) {
+		return false
+	}
+
+	return p.ActiveTo.IsZero() || !stop.After(p.ActiveTo)
+}
+
+// Registry looks up metering points.
+type Registry interface {
+	// Lookup returns the registrations of the metering point with the given reference, e.g. one for each meter installed
+	// over time. It returns no registrations and no error for unknown references.
+	Lookup(ctx context.Context, reference string) ([]MeteringPoint, error)
+}
+
+// FileRegistry is a Registry holding metering points read from a file.
+type FileRegistry struct {
+	points map[string][]MeteringPoint
+}
+
+// NewFileRegistry returns a registry with the given metering points.
+func NewFileRegistry(points ...MeteringPoint) *FileRegistry {
+	r := &FileRegistry{points: map[string][]MeteringPoint{}}
+	for _, p := range points {
+		r.points[p.Reference] = append(r.points[p.Reference], p)
+	}
+
+	return r
+}
+
+// Lookup returns the registrations of the metering point with the given reference.
+func (r *FileRegistry) Lookup(_ context.Context, reference string) ([]MeteringPoint, error) {
+	return r.points[reference], nil
+}
+
+type meteringPointConfig struct {
+	Reference     string   `json:"reference"`
+	Meter         string   `json:"meter"`
+	Installation  string   `json:"installation"`
+	MeterLocation string   `json:"meterLocation"`
+	Channels      []string `json:"channels"`
+	Unit          string   `json:"unit"`
+	Step          string   `json:"step"`
+	ActiveFrom    string   `json:"activeFrom"`
+	ActiveTo      string   `json:"activeTo"`
+}
+
+func (c meteringPointConfig) meteringPoint() (MeteringPoint, error) {
+	if c.Reference == "" {
+		return MeteringPoint{}, errors.New("missing reference")
+	}
+
+	p := MeteringPoint{
+		Reference:     c.Reference,
+		Meter:         c.Meter,
+		Installation:  c.Installation,
+		MeterLocation: c.MeterLocation,
+		Channels:      c.Channels,
+		Unit:          c.Unit,
+	}
+
+	var err error
+	if c.Step != "" {
+		if p.Step, err = time.ParseDuration(c.Step); err != nil {
+			if p.Step, err = parseDuration(c.Step); err != nil {
+				return MeteringPoint{}, fmt.Errorf("reference %q: invalid step %q", c.Reference, c.Step)
+			}
+		}
+	}
+
+	if p.ActiveFrom, err = parseRegistryTime(c.ActiveFrom); err != nil {
+		return MeteringPoint{}, fmt.Errorf("reference %q: %w", c.Reference, err)
+	}
+	if p.ActiveTo, err = parseRegistryTime(c.ActiveTo); err != nil {
+		return MeteringPoint{}, fmt.Errorf("reference %q: %w", c.Reference, err)
+	}
+
+	return p, nil
+}
+
+// parseRegistryTime parses a date, 2006-01-02, or a time in RFC 3339. Dates are in UTC.
+func parseRegistryTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+
+	return t, nil
+}
+
+// LoadRegistryJSON reads metering points in JSON from r:
+//
+//	[
+//	  {"reference": "mp1", "meter": "m1", "channels": ["1", "2"], "unit": "kWh", "step": "1h", "activeFrom": "2020-01-01"}
+//	]
+//
+// Steps are Go durations, e.g. 15m, or GS2 steps, e.g. 0000-00-00.00:15:00. Active dates are dates or RFC 3339 times, and
+// activeTo is the first time the metering point is no longer active.
+func LoadRegistryJSON(r io.Reader) (*FileRegistry, error) {
+	var configs []meteringPointConfig
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&configs); err != nil {
+		return nil, err
+	}
+
+	var points []MeteringPoint
+	for _, c := range configs {
+		p, err := c.meteringPoint()
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+
+	return NewFileRegistry(points...), nil
+}
+
+// LoadRegistryCSV reads metering points in CSV from r. The first record names the columns, which are the keys of
+// LoadRegistryJSON in any order. Only reference is required, and channels are separated by |:
+//
+//	reference,meter,channels,unit,step,activeFrom,activeTo
+//	mp1,m1,1|2,kWh,1h,2020-01-01,
+func LoadRegistryCSV(r io.Reader) (*FileRegistry, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]func(*meteringPointConfig, string){
+		"reference":     func(c *meteringPointConfig, v string) { c.Reference = v },
+		"meter":         func(c *meteringPointConfig, v string) { c.Meter = v },
+		"installation":  func(c *meteringPointConfig, v string) { c.Installation = v },
+		"meterLocation": func(c *meteringPointConfig, v string) { c.MeterLocation = v },
+		"channels": func(c *meteringPointConfig, v string) {
+			if v != "" {
+				c.Channels = strings.Split(v, "|")
+			}
+		},
+		"unit":       func(c *meteringPointConfig, v string) { c.Unit = v },
+		"step":       func(c *meteringPointConfig, v string) { c.Step = v },
+		"activeFrom": func(c *meteringPointConfig, v string) { c.ActiveFrom = v },
+		"activeTo":   func(c *meteringPointConfig, v string) { c.ActiveTo = v },
+	}
+
+	setters := make([]func(*meteringPointConfig, string), len(header))
+	for i, name := range header {
+		set, ok := columns[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		setters[i] = set
+	}
+
+	var points []MeteringPoint
+	for n := 2; ; n++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var c meteringPointConfig
+		for i, v := range record {
+			setters[i](&c, strings.TrimSpace(v))
+		}
+
+		p, err := c.meteringPoint()
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+		points = append(points, p)
+	}
+
+	return NewFileRegistry(points...), nil
+}
+
+// ValidateRegistry returns a Validator checking the metering point of each time series and meter reading against r. The
+// Reference must be registered, and Meter, Installation, Meter-location and Channel must match the registration that is
+// active in the period of the object. Unit and, for time series, Step must be the ones expected by the registration. Missing
+// attributes are left to ValidateMandatory.
+//
+// Errors from r stop the validation and are returned as they are.
+func ValidateRegistry(ctx context.Context, r Registry) Validator {
+	v := ValidateRegistryContext(r)
+	return func(g *GS2) error {
+		return v(ctx, g)
+	}
+}
+
+// ValidateRegistryContext is like ValidateRegistry, but takes the context of each validation, see DecodeContextValidators.
+func ValidateRegistryContext(r Registry) ContextValidator {
+	return func(ctx context.Context, g *GS2) error {
+		var errs ValidationErrors
+		loc := g.StartMessage.Location()
+
+		for i, ts := range g.TimeSeries {
+			p, problems, err := registryProblems(ctx, r, registered{ts.Reference, ts.Meter, ts.Installation, ts.MeterLocation,
+				ts.Channel, ts.Unit}, ts.Start, ts.Stop, loc)
+			if err != nil {
+				return err
+			}
+
+			if p != nil && ts.Step != 0 && p.Step != 0 && ts.Step != p.Step {
+				problems = append(problems, fmt.Sprintf("Step is %s, but %s is expected", encodeDuration(ts.Step), encodeDuration(p.Step)))
+			}
+
+			if len(problems) > 0 {
+				errs = append(errs, timeSeriesError(i, ts, errors.New(strings.Join(problems, ", "))))
+			}
+		}
+
+		for i, mr := range g.MeterReadings {
+			_, problems, err := registryProblems(ctx, r, registered{mr.Reference, mr.Meter, mr.Installation, mr.MeterLocation,
+				mr.Channel, mr.Unit}, mr.Time, mr.Time, loc)
+			if err != nil {
+				return err
+			}
+
+			if len(problems) > 0 {
+				errs = append(errs, &ObjectError{Object: "Meter-reading", Index: i, Reference: mr.Reference,
+					Err: errors.New(strings.Join(problems, ", "))})
+			}
+		}
+
+		return errs.err()
+	}
+}
+
+// registered are the attributes of an object that are checked against a registry.
+type registered struct {
+	reference, meter, installation, meterLocation, channel, unit string
+}
+
+// registryProblems returns the registration of o that is active from start until stop, and describes how o differs from
+// it. The registration is nil if there is none.
+func registryProblems(ctx context.Context, r Registry, o registered, start, stop time.Time, loc *time.Location) (*MeteringPoint, []string, error) {
+	if o.reference == "" {
+		return nil, nil, nil
+	}
+
+	points, err := r.Lookup(ctx, o.reference)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(points) == 0 {
+		return nil, []string{"unknown Reference"}, nil
+	}
+
+	p, ok := selectPoint(points, o.meter, start, stop)
+	if !ok {
+		period := start.In(loc).Format(time.RFC3339)
+		if !stop.Equal(start) {
+			period += " to " + stop.In(loc).Format(time.RFC3339)
+		}
+		return nil, []string{fmt.Sprintf("metering point isn't active at %s", period)}, nil
+	}
+
+	var problems []string
+	for _, a := range []struct{ name, value, expected string }{
+		{"Meter", o.meter, p.Meter},
+		{"Installation", o.installation, p.Installation},
+		{"Meter-location", o.meterLocation, p.MeterLocation},
+		{"Unit", o.unit, p.Unit},
+	} {
+		if a.value != "" && a.expected != "" && a.value != a.expected {
+			problems = append(problems, fmt.Sprintf("%s is %q, but %q is expected", a.name, a.value, a.expected))
+		}
+	}
+
+	if o.channel != "" && len(p.Channels) > 0 && !containsString(p.Channels, o.channel) {
+		problems = append(problems, fmt.Sprintf("unknown Channel %q", o.channel))
+	}
+
+	return &p, problems, nil
+}
+
+// selectPoint returns the registration that is active from start until stop, preferring the one with the given meter.
+func selectPoint(points []MeteringPoint, meter string, start, stop time.Time) (MeteringPoint, bool) {
+	var active []MeteringPoint
+	for _, p := range points {
+		if p.Active(start, stop) {
+			active = append(active, p)
+		}
+	}
+
+	for _, p := range active {
+		if p.Meter == meter {
+			return p, true
+		}
+	}
+
+	if len(active) > 0 {
+		return active[0], true
+	}
+
+	return MeteringPoint{}, false
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,106 @@
+package gs2
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadRegistryCSV(t *testing.T) {
+	r, err := LoadRegistryCSV(strings.NewReader("reference,meter,channels,unit,step,activeFrom,activeTo\n" +
+		"mp1,m1,1|2,kWh,1h,2020-01-01,2020-06-01T00:00:00+02:00\n" +
+		"mp1,m2,,kWh,0000-00-00.00:15:00,2020-06-01,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	points, _ := r.Lookup(context.Background(), "mp1")
+	if len(points) != 2 {
+		t.Fatalf("expected 2 registrations, but got %d", len(points))
+	}
+
+	first := points[0]
+	if first.Meter != "m1" || len(first.Channels) != 2 || first.Step != time.Hour || !first.ActiveTo.Equal(getTime("2020-05-31T22:00:00Z")) {
+		t.Errorf("unexpected registration %+v", first)
+	}
+	if points[1].Step != 15*time.Minute || !points[1].ActiveTo.IsZero() {
+		t.Errorf("unexpected registration %+v", points[1])
+	}
+
+	for _, csv := range []string{
+		"reference,serial\nmp1,1\n",
+		"reference,step\nmp1,hourly\n",
+		"meter\nm1\n",
+	} {
+		if _, err := LoadRegistryCSV(strings.NewReader(csv)); err == nil {
+			t.Errorf("expected an error for %q", csv)
+		}
+	}
+}
+
+func TestLoadRegistryJSON(t *testing.T) {
+	r, err := LoadRegistryJSON(strings.NewReader(`[{"reference": "mp1", "meter": "m1", "channels": ["1"], "activeFrom": "2020-01-01"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if points, _ := r.Lookup(context.Background(), "mp1"); len(points) != 1 || points[0].Channels[0] != "1" {
+		t.Errorf("unexpected registrations %+v", points)
+	}
+}
+
+func TestValidateRegistry(t *testing.T) {
+	r := NewFileRegistry(
+		MeteringPoint{Reference: "mp1", Meter: "m1", Channels: []string{"1"}, Unit: "kWh", Step: time.Hour,
+			ActiveFrom: getTime("2020-01-01T00:00:00Z"), ActiveTo: getTime("2020-06-01T00:00:00Z")},
+		MeteringPoint{Reference: "mp1", Meter: "m2", Unit: "kWh", ActiveFrom: getTime("2020-06-01T00:00:00Z")},
+	)
+
+	g := &GS2{
+		TimeSeries: []TimeSeries{
+			{Reference: "mp1", Meter: "m1", Channel: "1", Unit: "kWh", Step: time.Hour,
+				Start: getTime("2020-03-01T00:00:00Z"), Stop: getTime("2020-03-02T00:00:00Z")},
+			{Reference: "mp1", Meter: "m1", Channel: "2", Unit: "MWh", Step: 15 * time.Minute,
+				Start: getTime("2020-03-01T00:00:00Z"), Stop: getTime("2020-03-02T00:00:00Z")},
+			{Reference: "unknown"},
+		},
+		MeterReadings: []MeterReading{
+			{Reference: "mp1", Meter: "m2", Unit: "kWh", Time: getTime("2020-07-01T00:00:00Z")},
+			{Reference: "mp1", Meter: "m1", Time: getTime("2019-07-01T00:00:00Z")},
+			{Reference: "mp1", Meter: "m1", Time: getTime("2020-07-01T00:00:00Z")},
+		},
+	}
+
+	errs := Errors(ValidateRegistry(context.Background(), r)(g))
+	expected := []string{
+		`Time-series 1 (mp1): Unit is "MWh", but "kWh" is expected, unknown Channel "2", Step is 0000-00-00.00:15:00, but 0000-00-00.01:00:00 is expected`,
+		`Time-series 2 (unknown): unknown Reference`,
+		`Meter-reading 1 (mp1): metering point isn't active at 2019-07-01T00:00:00Z`,
+		`Meter-reading 2 (mp1): Meter is "m1", but "m2" is expected`,
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, but got %d: %v", len(expected), len(errs), errs)
+	}
+
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("expected %q, but got %q", expected[i], err.Error())
+		}
+	}
+}
+
+type failingRegistry struct{}
+
+func (failingRegistry) Lookup(context.Context, string) ([]MeteringPoint, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestValidateRegistry_LookupError(t *testing.T) {
+	g := &GS2{TimeSeries: []TimeSeries{{Reference: "mp1"}}}
+
+	if err := ValidateRegistry(context.Background(), failingRegistry{})(g); err == nil || err.Error() != "unavailable" {
+		t.Errorf("expected the lookup error, but got %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package gs2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrAmbiguous is wrapped by the errors from Repair for attributes that can't be recomputed because the data disagrees with
+// itself.
+var ErrAmbiguous = errors.New("ambiguous")
+
+// Change describes an attribute changed by Repair.
+type Change struct {
+	Object    string // Name of the block, e.g. Time-series.
+	Index     int    // Index of the object among the blocks with the same name.
+	Reference string // Reference of the object, if any.
+	Attribute string // Name of the attribute, e.g. Sum.
+	Old       string // Value before the repair. Empty if the attribute was missing.
+	New       string // Value after the repair.
+}
+
+func (c Change) String() string {
+	old := c.Old
+	if old == "" {
+		old = "(missing)"
+	}
+
+	if c.Reference != "" {
+		return fmt.Sprintf("%s %d (%s): %s %s -> %s", c.Object, c.Index, c.Reference, c.Attribute, old, c.New)
+	}
+
+	return fmt.Sprintf("%s %d: %s %s -> %s", c.Object, c.Index, c.Attribute, old, c.New)
+}
+
+// Repair recomputes the attributes of g derived from its content: Sum, No-of-values and a missing Stop of time series, and
+// Number-of-objects of the Start-message and End-message. It returns the changes made, in the order of the objects in g.
+//
+// A time series is left unchanged when its attributes can't be recomputed without guessing, e.g. when the number of
+// values disagrees with both No-of-values and the period given by Start, Stop and Step, so values may be missing. Such time
+// series are reported as ObjectErrors wrapping ErrAmbiguous, returned as ValidationErrors.
+func Repair(g *GS2) ([]Change, error) {
+	var changes []Change
+	var errs ValidationErrors
+
+	for i := range g.TimeSeries {
+		tsChanges, err := repairTimeSeries(i, &g.TimeSeries[i])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		changes = append(changes, tsChanges...)
+	}
+
+	noOfObjects := len(g.MeterReadings) + len(g.TimeSeries) + 2
+	if g.StartMessage.NumberOfObjects != 0 && g.StartMessage.NumberOfObjects != noOfObjects {
+		changes = append(changes, Change{
+			Object:    "Start-message",
+			Attribute: "Number-of-objects",
+			Old:       strconv.Itoa(g.StartMessage.NumberOfObjects),
+			New:       strconv.Itoa(noOfObjects),
+		})
+		g.StartMessage.NumberOfObjects = noOfObjects
+	}
+	if g.EndMessage.NumberOfObjects != noOfObjects {
+		old := ""
+		if g.EndMessage.NumberOfObjects != 0 {
+			old = strconv.Itoa(g.EndMessage.NumberOfObjects)
+		}
+		changes = append(changes, Change{
+			Object:    "End-message",
+			Attribute: "Number-of-objects",
+			Old:       old,
+			New:       strconv.Itoa(noOfObjects),
+		})
+		g.EndMessage.NumberOfObjects = noOfObjects
+	}
+
+	return changes, errs.err()
+}
+
+// ApplyChanges returns src with the values of the attributes changed by Repair replaced by their values in g, which is the
+// file decoded from src and repaired. Everything else in src is kept as it is, including formatting and blocks and
+// attributes unknown to the GS2 type. Attributes that were missing are added at the end of their block.
+func ApplyChanges(src []byte, g *GS2, changes []Change) ([]byte, error) {
+	blocks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+
+	for _, c := range changes {
+		value, err := attributeValue(g, c)
+		if err != nil {
+			return nil, err
+		}
+
+		b, ok := findBlock(blocks, c.Object, c.Index)
+		switch {
+		case !ok && c.Object == "End-message":
+			newline := "\n"
+			if len(src) > 0 && src[len(src)-1] != '\n' {
+				newline = "\n\n"
+			}
+			edits = append(edits, edit{len(src), len(src), newline + "##End-message\n#" + c.Attribute + "=" + value + "\n"})
+			continue
+		case !ok:
+			return nil, fmt.Errorf("gs2: %s %d not found", c.Object, c.Index)
+		}
+
+		if a, ok := findAttribute(b, c.Attribute); ok {
+			edits = append(edits, edit{a.start, a.end, value})
+			continue
+		}
+
+		end := b.end
+		if len(b.attributes) > 0 {
+			end = b.attributes[len(b.attributes)-1].end
+		}
+		var newline string
+		switch {
+		case bytes.HasPrefix(src[end:], []byte("\r\n")):
+			newline = "\r\n"
+		case bytes.HasPrefix(src[end:], []byte("\n")):
+			newline = "\n"
+		}
+		edits = append(edits, edit{end, end, newline + "#" + c.Attribute + "=" + value})
+	}
+
+	// Edits are applied from the end, so the offsets of the others stay valid.
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+
+	result := append([]byte(nil), src...)
+	for _, e := range edits {
+		result = append(result[:e.start], append([]byte(e.text), result[e.end:]...)...)
+	}
+
+	return result, nil
+}
+
+// findBlock returns the block with the given name and index among the blocks with the same name, as counted by the
+// Decoder.
+func findBlock(blocks []rawBlock, name string, index int) (rawBlock, bool) {
+	for _, b := range blocks {
+		if _, blockName, known := lookupBlockType(b.name); known && blockName == name {
+			if index == 0 {
+				return b, true
+			}
+			index--
+		}
+	}
+
+	return rawBlock{}, false
+}
+
+// findAttribute returns the last attribute of b with the given name regardless of case, which is the one decoded.
+func findAttribute(b rawBlock, name string) (rawAttribute, bool) {
+	for i := len(b.attributes) - 1; i >= 0; i-- {
+		if strings.EqualFold(b.attributes[i].name, name) {
+			return b.attributes[i], true
+		}
+	}
+
+	return rawAttribute{}, false
+}
+
+// attributeValue returns the value of the attribute changed by c in g, as written in GS2 files.
+func attributeValue(g *GS2, c Change) (string, error) {
+	v := reflect.ValueOf(g).Elem()
+	field, ok := lookupField(v.Type(), c.Object)
+	if !ok {
+		return "", fmt.Errorf("gs2: unknown block %s", c.Object)
+	}
+
+	block := v.Field(field)
+	if block.Kind() == reflect.Slice {
+		block = block.Index(c.Index)
+	}
+
+	if field, ok = lookupField(block.Type(), c.Attribute); !ok {
+		return "", fmt.Errorf("gs2: unknown attribute %s of %s", c.Attribute, c.Object)
+	}
+
+	switch value := block.Field(field).Interface().(type) {
+	case time.Time:
+		return formatTime(value.In(g.StartMessage.Location())), nil
+	case int:
+		return strconv.Itoa(value), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("gs2: unsupported type %T of %s", value, c.Attribute)
+	}
+}
+
+// repairTimeSeries repairs ts, or returns an error without changing ts if the repair would be a guess.
+func repairTimeSeries(i int, ts *TimeSeries) ([]Change, error) {
+	n := len(ts.Value)
+
+	if ts.Stop.IsZero() && n > 0 {
+		switch {
+		case ts.Start.IsZero() || ts.Step <= 0:
+			return nil, ambiguousError(i, *ts, "Stop is missing, and can't be computed without Start and Step")
+		case ts.NoOfValues != n:
+			return nil, ambiguousError(i, *ts, fmt.Sprintf("Stop is missing, and No-of-values %d doesn't match the %d values", ts.NoOfValues, n))
+		}
+	}
+
+	if ts.NoOfValues != n {
+		if ts.Start.IsZero() || ts.Stop.IsZero() || ts.Step <= 0 {
+			return nil, ambiguousError(i, *ts, fmt.Sprintf("No-of-values %d doesn't match the %d values, and there is no period to tell which is right", ts.NoOfValues, n))
+		}
+		if expected, ok := periodValues(*ts); !ok || expected != n {
+			return nil, ambiguousError(i, *ts, fmt.Sprintf("No-of-values %d doesn't match the %d values, and neither matches the period from %s to %s",
+				ts.NoOfValues, n, ts.Start.Format(time.RFC3339), ts.Stop.Format(time.RFC3339)))
+		}
+	}
+
+	var changes []Change
+	change := func(attribute, old, new string) {
+		changes = append(changes, Change{Object: "Time-series", Index: i, Reference: ts.Reference, Attribute: attribute, Old: old, New: new})
+	}
+
+	if ts.Stop.IsZero() && n > 0 {
+		ts.Stop = ts.ValueTime(n - 1).Add(ts.Step)
+		change("Stop", "", ts.Stop.Format(time.RFC3339))
+	}
+
+	if ts.NoOfValues != n {
+		change("No-of-values", strconv.Itoa(ts.NoOfValues), strconv.Itoa(n))
+		ts.NoOfValues = n
+	}
+
+	if sum := roundedSum(ts.Value); math.Abs(sum-ts.Sum) > delta {
+		change("Sum", strconv.FormatFloat(ts.Sum, 'f', -1, 64), strconv.FormatFloat(sum, 'f', -1, 64))
+		ts.Sum = sum
+	}
+
+	return changes, nil
+}
+
+// periodValues returns the number of values between Start and Stop of ts, and false if Step doesn't divide the period.
+func periodValues(ts TimeSeries) (int, bool) {
+	period := ts.Stop.Sub(ts.Start)
+	if period < 0 || period%ts.Step != 0 {
+		return 0, false
+	}
+
+	return int(period / ts.Step), true
+}
+
+// roundedSum returns the sum of values, rounded to the largest number of decimals in the values to remove floating point
+// noise from the summation.
+func roundedSum(values []Triplet) float64 {
+	var sum float64
+	decimals := 0
+	for _, v := range values {
+		sum += v.Value
+		s := strconv.FormatFloat(v.Value, 'f', -1, 64)
+		if i := strings.IndexByte(s, '.'); i >= 0 && len(s)-i-1 > decimals {
+			decimals = len(s) - i - 1
+		}
+	}
+
+	scale := math.Pow10(decimals)
+	if rounded := math.Round(sum*scale) / scale; !math.IsInf(rounded, 0) && !math.IsNaN(rounded) {
+		return rounded
+	}
+
+	return sum
+}
+
+func ambiguousError(i int, ts TimeSeries, reason string) *ObjectError {
+	return timeSeriesError(i, ts, fmt.Errorf("%w: %s", ErrAmbiguous, reason))
+}
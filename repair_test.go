@@ -0,0 +1,156 @@
+package gs2
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRepair(t *testing.T) {
+	start := getTime("2020-01-01T00:00:00Z")
+	stop := getTime("2020-01-01T03:00:00Z")
+	values := []Triplet{{Value: 0.1}, {Value: 0.2}, {Value: 0.3}}
+
+	for i, test := range []struct {
+		ts       TimeSeries
+		expected TimeSeries
+		changes  []string
+	}{
+		{
+			TimeSeries{Start: start, Stop: stop, Step: time.Hour, Value: values, NoOfValues: 3, Sum: 0.6},
+			TimeSeries{Start: start, Stop: stop, Step: time.Hour, Value: values, NoOfValues: 3, Sum: 0.6},
+			nil,
+		},
+		{
+			TimeSeries{Reference: "a", Start: start, Stop: stop, Step: time.Hour, Value: values, NoOfValues: 3, Sum: 1},
+			TimeSeries{Reference: "a", Start: start, Stop: stop, Step: time.Hour, Value: values, NoOfValues: 3, Sum: 0.6},
+			[]string{"Time-series 0 (a): Sum 1 -> 0.6"},
+		},
+		{
+			TimeSeries{Start: start, Stop: stop, Step: time.Hour, Value: values, NoOfValues: 4, Sum: 0.6},
+			TimeSeries{Start: start, Stop: stop, Step: time.Hour, Value: values, NoOfValues: 3, Sum: 0.6},
+			[]string{"Time-series 0: No-of-values 4 -> 3"},
+		},
+		{
+			TimeSeries{Start: start, Step: time.Hour, Value: values, NoOfValues: 3},
+			TimeSeries{Start: start, Stop: stop, Step: time.Hour, Value: values, NoOfValues: 3, Sum: 0.6},
+			[]string{"Time-series 0: Stop (missing) -> 2020-01-01T03:00:00Z", "Time-series 0: Sum 0 -> 0.6"},
+		},
+	} {
+		g := &GS2{TimeSeries: []TimeSeries{test.ts}, EndMessage: EndMessage{NumberOfObjects: 3}}
+
+		changes, err := Repair(g)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+
+		var got []string
+		for _, c := range changes {
+			got = append(got, c.String())
+		}
+
+		if !reflect.DeepEqual(got, test.changes) {
+			t.Errorf("%d: expected changes %q, but got %q", i, test.changes, got)
+		}
+
+		if !reflect.DeepEqual(g.TimeSeries[0], test.expected) {
+			t.Errorf("%d: expected %+v, but got %+v", i, test.expected, g.TimeSeries[0])
+		}
+
+		if err := ValidateTimeSeriesValues(g); err != nil {
+			t.Errorf("%d: repaired time series is invalid: %v", i, err)
+		}
+	}
+}
+
+func TestRepair_Ambiguous(t *testing.T) {
+	start := getTime("2020-01-01T00:00:00Z")
+	values := []Triplet{{Value: 1}, {Value: 2}}
+
+	for i, ts := range []TimeSeries{
+		{Start: start, Stop: getTime("2020-01-01T03:00:00Z"), Step: time.Hour, Value: values, NoOfValues: 3, Sum: 6},
+		{Start: start, Step: time.Hour, Value: values, NoOfValues: 3, Sum: 6},
+		{Start: start, Value: values, NoOfValues: 2, Sum: 4},
+		{Value: values, NoOfValues: 3, Sum: 4},
+	} {
+		g := &GS2{TimeSeries: []TimeSeries{ts}, EndMessage: EndMessage{NumberOfObjects: 3}}
+
+		changes, err := Repair(g)
+		if errs := Errors(err); len(errs) != 1 || !errors.Is(errs[0], ErrAmbiguous) {
+			t.Errorf("%d: expected ErrAmbiguous, but got %v", i, err)
+		}
+
+		if len(changes) != 0 || !reflect.DeepEqual(g.TimeSeries[0], ts) {
+			t.Errorf("%d: expected time series to be unchanged, but got %v", i, changes)
+		}
+	}
+}
+
+func TestRepair_NumberOfObjects(t *testing.T) {
+	g := &GS2{
+		StartMessage:  StartMessage{NumberOfObjects: 2},
+		MeterReadings: []MeterReading{{}, {}},
+	}
+
+	changes, err := Repair(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes) != 2 || g.StartMessage.NumberOfObjects != 4 || g.EndMessage.NumberOfObjects != 4 {
+		t.Errorf("unexpected repair %v", changes)
+	}
+
+	if err := ValidateNoOfObjects(g); err != nil {
+		t.Errorf("repaired object is invalid: %v", err)
+	}
+}
+
+func TestApplyChanges(t *testing.T) {
+	data, err := os.ReadFile("testdata/timeseries.gs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(data)
+	withoutStop := strings.Replace(src, "#Stop=2020-03-28.00:00:00\n", "", 1)
+	withoutEnd := src[:strings.Index(src, "##End-message")]
+
+	tests := []struct {
+		name     string
+		broken   string
+		expected string
+	}{
+		{"unchanged", src, src},
+		{"sum", strings.Replace(src, "#Sum=27", "#Sum=999", 1), src},
+		{"no-of-values", strings.Replace(src, "#No-of-values=24\n#Sum=12", "#No-of-values=23\n#Sum=12", 1), src},
+		{"stop", withoutStop, strings.Replace(withoutStop, "#Sum=27\n", "#Sum=27\n#Stop=2020-03-28.00:00:00\n", 1)},
+		{"number of objects", strings.Replace(src, "#Number-of-objects=7", "#Number-of-objects=1", 1), src},
+		{"end-message", withoutEnd, withoutEnd + "\n##End-message\n#Number-of-objects=7\n"},
+		{"no newlines", strings.Replace(strings.ReplaceAll(src, "\n", ""), "#Sum=27", "#Sum=1", 1), strings.ReplaceAll(src, "\n", "")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := NewDecoder(strings.NewReader(test.broken), DecodeValidators()).Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			changes, err := Repair(g)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			repaired, err := ApplyChanges([]byte(test.broken), g, changes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(repaired) != test.expected {
+				t.Errorf("expected\n%s\nbut got\n%s", test.expected, repaired)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package gs2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Severity is the severity of the problems found by a rule.
+type Severity int
+
+// Severities, from least to most severe.
+const (
+	SeverityInfo Severity = iota + 1
+	SeverityWarning
+	SeverityError
+)
+
+var severityNames = map[Severity]string{
+	SeverityInfo:    "info",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText returns the name of s.
+func (s Severity) MarshalText() ([]byte, error) {
+	if _, ok := severityNames[s]; !ok {
+		return nil, fmt.Errorf("invalid severity %d", int(s))
+	}
+
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText sets s to the severity named by text: info, warning or error.
+func (s *Severity) UnmarshalText(text []byte) error {
+	for severity, name := range severityNames {
+		if name == string(text) {
+			*s = severity
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid severity %q", text)
+}
+
+// Rule is a named Validator with a severity.
+type Rule struct {
+	ID               string
+	Severity         Severity // SeverityError if zero.
+	Description      string
+	Validator        Validator
+	ContextValidator ContextValidator // Run instead of Validator if set.
+	Disabled         bool             // Disabled rules are not run.
+}
+
+// validate runs the validator of r.
+func (r *Rule) validate(ctx context.Context, g *GS2) error {
+	if r.ContextValidator != nil {
+		return r.ContextValidator(ctx, g)
+	}
+
+	return r.Validator(g)
+}
+
+// RuleSet is a list of rules. The methods changing a RuleSet return a changed copy.
+type RuleSet []Rule
+
+// DefaultRules returns the rules for the validators in this package. Strict rules, which reject values that are common in
+// practice, are disabled.
+func DefaultRules() RuleSet {
+	return RuleSet{
+		{"mandatory", SeverityError, "Mandatory attributes of the message type have values", ValidateMandatory, nil, true},
+		{"no-of-objects", SeverityError, "Number-of-objects matches the number of objects in the file", ValidateNoOfObjects, nil, false},
+		{"time-series-values", SeverityError, "No-of-values and Sum match the values of each time series", ValidateTimeSeriesValues, nil, false},
+		{"time-series-period", SeverityWarning, "Start + No-of-values * Step equals Stop for each time series", ValidateTimeSeriesPeriod, nil, false},
+		{"value-times", SeverityError, "Explicit times of values increase strictly in each time series", ValidateValueTimes, nil, false},
+		{"meter-reading-order", SeverityError, "Meter readings of each meter and channel increase in time and register value", ValidateMeterReadingOrder, nil, false},
+		{"vocabulary", SeverityError, "Direction-of-flow, Type-of-value and qualities are known GS2 1.2 values", ValidateVocabulary, nil, true},
+		{"qualities", SeverityError, "Quality codes are known for the sender, and Sum excludes missing values", ValidateQualities, nil, true},
+		{"utf-8", SeverityError, "Text attributes are valid UTF-8", ValidateUTF8, nil, true},
+	}
+}
+
+// Lookup returns the rule with the given ID, and false if there is none.
+func (rs RuleSet) Lookup(id string) (Rule, bool) {
+	for _, r := range rs {
+		if r.ID == id {
+			return r, true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// Enabled returns the rules in rs that are enabled.
+func (rs RuleSet) Enabled() RuleSet {
+	var enabled RuleSet
+	for _, r := range rs {
+		if !r.Disabled {
+			enabled = append(enabled, r)
+		}
+	}
+
+	return enabled
+}
+
+// Enable returns rs with the rules with the given IDs enabled.
+func (rs RuleSet) Enable(ids ...string) (RuleSet, error) {
+	return rs.update(ids, func(r *Rule) { r.Disabled = false })
+}
+
+// Disable returns rs with the rules with the given IDs disabled.
+func (rs RuleSet) Disable(ids ...string) (RuleSet, error) {
+	return rs.update(ids, func(r *Rule) { r.Disabled = true })
+}
+
+// Only returns rs with only the rules with the given IDs enabled.
+func (rs RuleSet) Only(ids ...string) (RuleSet, error) {
+	all := make([]string, len(rs))
+	for i, r := range rs {
+		all[i] = r.ID
+	}
+
+	disabled, _ := rs.Disable(all...)
+	return disabled.Enable(ids...)
+}
+
+// WithSeverity returns rs with the severity of the rule with the given ID changed.
+func (rs RuleSet) WithSeverity(id string, s Severity) (RuleSet, error) {
+	return rs.update([]string{id}, func(r *Rule) { r.Severity = s })
+}
+
+// With returns rs with the given rules added, replacing rules with the same ID. Rules without a severity get SeverityError.
+func (rs RuleSet) With(rules ...Rule) RuleSet {
+	result := append(RuleSet(nil), rs...)
+	for _, rule := range rules {
+		if rule.Severity == 0 {
+			rule.Severity = SeverityError
+		}
+
+		replaced := false
+		for i := range result {
+			if result[i].ID == rule.ID {
+				result[i] = rule
+				replaced = true
+			}
+		}
+		if !replaced {
+			result = append(result, rule)
+		}
+	}
+
+	return result
+}
+
+func (rs RuleSet) update(ids []string, f func(*Rule)) (RuleSet, error) {
+	result := append(RuleSet(nil), rs...)
+	for _, id := range ids {
+		found := false
+		for i := range result {
+			if result[i].ID == id {
+				f(&result[i])
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown rule %q", id)
+		}
+	}
+
+	return result, nil
+}
+
+// Finding is a problem found by a rule.
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Err      error
+}
+
+func (f *Finding) Error() string {
+	return fmt.Sprintf("%s: %s: %v", f.Severity, f.Rule, f.Err)
+}
+
+func (f *Finding) Unwrap() error {
+	return f.Err
+}
+
+// Check runs the enabled rules in rs on g, and returns a finding for each error returned by the validators.
+func (rs RuleSet) Check(g *GS2) []*Finding {
+	return rs.CheckContext(context.Background(), g)
+}
+
+// CheckContext is like Check, but gives ctx to the rules with a ContextValidator.
+func (rs RuleSet) CheckContext(ctx context.Context, g *GS2) []*Finding {
+	var findings []*Finding
+	for _, r := range rs.Enabled() {
+		severity := r.Severity
+		if severity == 0 {
+			severity = SeverityError
+		}
+
+		for _, err := range Errors(r.validate(ctx, g)) {
+			findings = append(findings, &Finding{Rule: r.ID, Severity: severity, Err: err})
+		}
+	}
+
+	return findings
+}
+
+// Validator returns a Validator running the enabled rules in rs. Only findings with SeverityError make it fail, and they are
+// returned as ValidationErrors.
+func (rs RuleSet) Validator() Validator {
+	v := rs.ContextValidator()
+	return func(g *GS2) error {
+		return v(context.Background(), g)
+	}
+}
+
+// ContextValidator is like Validator, but gives the context to the rules with a ContextValidator.
+func (rs RuleSet) ContextValidator() ContextValidator {
+	return func(ctx context.Context, g *GS2) error {
+		var errs ValidationErrors
+		for _, f := range rs.CheckContext(ctx, g) {
+			if f.Severity >= SeverityError {
+				errs = append(errs, f)
+			}
+		}
+
+		return errs.err()
+	}
+}
+
+// RuleProfile enables and disables rules, and changes their severity.
+type RuleProfile struct {
+	Enable   []string            `json:"enable,omitempty"`
+	Disable  []string            `json:"disable,omitempty"`
+	Severity map[string]Severity `json:"severity,omitempty"`
+}
+
+// Apply returns rs changed by p. Rules are first enabled, then disabled.
+func (p RuleProfile) Apply(rs RuleSet) (RuleSet, error) {
+	rs, err := rs.Enable(p.Enable...)
+	if err != nil {
+		return nil, err
+	}
+
+	if rs, err = rs.Disable(p.Disable...); err != nil {
+		return nil, err
+	}
+
+	for id, severity := range p.Severity {
+		if rs, err = rs.WithSeverity(id, severity); err != nil {
+			return nil, err
+		}
+	}
+
+	return rs, nil
+}
+
+// RuleConfig holds rule profiles per counterpart, e.g. the sender of a file.
+type RuleConfig struct {
+	Default      RuleProfile            `json:"default"`      // Profile for all counterparts.
+	Counterparts map[string]RuleProfile `json:"counterparts"` // Profiles applied after the default profile.
+}
+
+// LoadRuleConfig reads a rule configuration in JSON from r:
+//
+//	{
+//	  "default": {"disable": ["time-series-period"]},
+//	  "counterparts": {
+//	    "supplier": {"enable": ["vocabulary"], "severity": {"meter-reading-order": "warning"}}
+//	  }
+//	}
+func LoadRuleConfig(r io.Reader) (*RuleConfig, error) {
+	var c RuleConfig
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Apply returns rs changed by the default profile and the profile of counterpart.
+func (c *RuleConfig) Apply(rs RuleSet, counterpart string) (RuleSet, error) {
+	rs, err := c.Default.Apply(rs)
+	if err != nil {
+		return nil, fmt.Errorf("default profile: %w", err)
+	}
+
+	if p, ok := c.Counterparts[counterpart]; ok {
+		if rs, err = p.Apply(rs); err != nil {
+			return nil, fmt.Errorf("profile for %q: %w", counterpart, err)
+		}
+	}
+
+	return rs, nil
+}
+
+// Verify returns an error if a profile in c refers to rules that are not in rs.
+func (c *RuleConfig) Verify(rs RuleSet) error {
+	if _, err := c.Apply(rs, ""); err != nil {
+		return err
+	}
+
+	for counterpart := range c.Counterparts {
+		if _, err := c.Apply(rs, counterpart); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
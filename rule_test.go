@@ -0,0 +1,187 @@
+package gs2
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSeverity_UnmarshalText(t *testing.T) {
+	for _, s := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		text, err := s.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var parsed Severity
+		if err := parsed.UnmarshalText(text); err != nil || parsed != s {
+			t.Errorf("expected %q to parse as %v, but got %v (%v)", text, s, parsed, err)
+		}
+	}
+
+	var s Severity
+	if err := s.UnmarshalText([]byte("fatal")); err == nil {
+		t.Error("expected an error for unknown severity")
+	}
+}
+
+func enabledIDs(rs RuleSet) []string {
+	var ids []string
+	for _, r := range rs.Enabled() {
+		ids = append(ids, r.ID)
+	}
+
+	return ids
+}
+
+func TestRuleSet_Only(t *testing.T) {
+	rules, err := DefaultRules().Only("vocabulary", "no-of-objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := enabledIDs(rules); !reflect.DeepEqual(ids, []string{"no-of-objects", "vocabulary"}) {
+		t.Errorf("unexpected enabled rules %q", ids)
+	}
+
+	if _, err := DefaultRules().Disable("unknown"); err == nil {
+		t.Error("expected an error for unknown rule")
+	}
+
+	if r, _ := DefaultRules().Lookup("vocabulary"); !r.Disabled {
+		t.Error("expected strict rules to be disabled by default")
+	}
+}
+
+func TestRuleSet_Validator(t *testing.T) {
+	g := &GS2{TimeSeries: []TimeSeries{{Reference: "a", Value: []Triplet{{Value: 1}}, NoOfValues: 2, Sum: 1}}}
+
+	rules, err := DefaultRules().Only("time-series-values")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := rules.Check(g)
+	if len(findings) != 1 || findings[0].Rule != "time-series-values" || findings[0].Severity != SeverityError {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+
+	var objectErr *ObjectError
+	if errs := Errors(rules.Validator()(g)); len(errs) != 1 || !errors.As(errs[0], &objectErr) || objectErr.Reference != "a" {
+		t.Errorf("expected validator to fail with an ObjectError, but got %v", errs)
+	}
+
+	warning, err := rules.WithSeverity("time-series-values", SeverityWarning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := warning.Validator()(g); err != nil {
+		t.Errorf("expected warnings not to fail, but got %v", err)
+	}
+}
+
+func TestRuleSet_CheckContext(t *testing.T) {
+	g := &GS2{TimeSeries: []TimeSeries{{Reference: "unknown"}}}
+	rules := RuleSet{{ID: "metering-points", Severity: SeverityError, ContextValidator: ValidateRegistryContext(NewFileRegistry())}}
+
+	if findings := rules.CheckContext(context.Background(), g); len(findings) != 1 || findings[0].Rule != "metering-points" {
+		t.Errorf("unexpected findings %v", findings)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	failing := RuleSet{{ID: "lookup", Severity: SeverityError, ContextValidator: func(ctx context.Context, g *GS2) error {
+		return ctx.Err()
+	}}}
+	if errs := Errors(failing.ContextValidator()(ctx, g)); len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("expected the rule to get the context, but got %v", errs)
+	}
+}
+
+func TestRuleSet_ZeroSeverity(t *testing.T) {
+	failing := func(g *GS2) error { return errors.New("failed") }
+
+	rules := RuleSet(nil).With(Rule{ID: "x", Validator: failing})
+	if rules[0].Severity != SeverityError {
+		t.Errorf("expected With to default to SeverityError, but got %v", rules[0].Severity)
+	}
+	if err := rules.Validator()(&GS2{}); err == nil {
+		t.Error("expected the rule to fail the validator")
+	}
+
+	findings := RuleSet{{ID: "x", Validator: failing}}.Check(&GS2{})
+	if len(findings) != 1 || findings[0].Severity != SeverityError {
+		t.Fatalf("expected a finding with SeverityError, but got %v", findings)
+	}
+	if _, err := findings[0].Severity.MarshalText(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadRuleConfig(t *testing.T) {
+	config, err := LoadRuleConfig(strings.NewReader(`{
+		"default": {"disable": ["time-series-period"]},
+		"counterparts": {
+			"supplier": {"enable": ["vocabulary"], "severity": {"meter-reading-order": "warning"}}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := config.Verify(DefaultRules()); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := config.Apply(DefaultRules(), "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := enabledIDs(other); !reflect.DeepEqual(ids, []string{"no-of-objects", "time-series-values", "value-times", "meter-reading-order"}) {
+		t.Errorf("unexpected enabled rules for other %q", ids)
+	}
+
+	supplier, err := config.Apply(DefaultRules(), "supplier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := supplier.Lookup("vocabulary"); r.Disabled {
+		t.Error("expected vocabulary to be enabled for supplier")
+	}
+	if r, _ := supplier.Lookup("meter-reading-order"); r.Severity != SeverityWarning {
+		t.Errorf("expected meter-reading-order to be a warning for supplier, but got %v", r.Severity)
+	}
+
+	bad, err := LoadRuleConfig(strings.NewReader(`{"counterparts": {"x": {"enable": ["unknown"]}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bad.Verify(DefaultRules()); err == nil {
+		t.Error("expected an error for unknown rule in profile")
+	}
+}
+
+func TestDecodeAppendValidators(t *testing.T) {
+	file, err := os.Open("testdata/timeseries.gs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var called bool
+	dec := NewDecoder(file, DecodeAppendValidators(func(*GS2) error {
+		called = true
+		return nil
+	}))
+
+	if _, err := dec.Decode(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !called || len(dec.options.validators) != len(defaultDecoderOptions.validators)+1 {
+		t.Errorf("expected the validator to be run together with the defaults")
+	}
+}
@@ -25,6 +25,32 @@ func TestScanner(t *testing.T) {
 	}
 }
 
+func FuzzScanner(f *testing.F) {
+	for _, test := range scanTestTable {
+		f.Add([]byte(test.data))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s := newScanner()
+		failed := false
+		for _, b := range data {
+			state := s.step(s, b)
+			if state < scanError || state > scanSkipSpace {
+				t.Fatalf("unknown state %d", state)
+			}
+			if failed && state != scanError {
+				t.Fatalf("expected the scanner to stay failed, but got state %d", state)
+			}
+			if state == scanError {
+				failed = true
+				if s.err == nil {
+					t.Fatal("expected an error with scanError")
+				}
+			}
+		}
+	})
+}
+
 var scanTestTable = []struct {
 	data     string
 	expected []int
@@ -0,0 +1,266 @@
+package gs2
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// AttributeType is the type of the value of an attribute.
+type AttributeType string
+
+// Types of attribute values.
+const (
+	AttributeString   AttributeType = "string"
+	AttributeInteger  AttributeType = "integer"
+	AttributeDecimal  AttributeType = "decimal"
+	AttributeTime     AttributeType = "time"     // yyyy-mm-dd.hh:mm:ss
+	AttributeDuration AttributeType = "duration" // 0000-00-dd.hh:mm:ss
+	AttributeTriplet  AttributeType = "triplet"  // value/time/quality
+	AttributeTriplets AttributeType = "triplets" // < value/time/quality ... >
+)
+
+// zeroIsValue reports whether the zero value of t is a value, e.g. 0, rather than the absence of a value, e.g. an empty
+// string.
+func (t AttributeType) zeroIsValue() bool {
+	return t == AttributeInteger || t == AttributeDecimal || t == AttributeTriplet
+}
+
+// MessageTypeSettlementData is the Message-type of files with settlement data.
+const MessageTypeSettlementData = "Settlement-data"
+
+// Attribute describes an attribute of a block.
+type Attribute struct {
+	Name        string        `json:"name"`
+	Type        AttributeType `json:"type"`
+	Mandatory   bool          `json:"mandatory,omitempty"`   // Mandatory in all message types.
+	MandatoryIn []string      `json:"mandatoryIn,omitempty"` // Message types where the attribute is mandatory.
+	Values      []string      `json:"values,omitempty"`      // Allowed values, or empty if any value is allowed.
+	Description string        `json:"description,omitempty"`
+}
+
+// Required reports whether a is mandatory in files with the given Message-type.
+func (a Attribute) Required(messageType string) bool {
+	if a.Mandatory {
+		return true
+	}
+
+	for _, t := range a.MandatoryIn {
+		if t == messageType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Allowed reports whether value is an allowed value of a.
+func (a Attribute) Allowed(value string) bool {
+	if len(a.Values) == 0 {
+		return true
+	}
+
+	for _, v := range a.Values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BlockSchema describes a block and its attributes.
+type BlockSchema struct {
+	Name       string      `json:"name"`
+	Attributes []Attribute `json:"attributes"`
+}
+
+// Attribute returns the attribute with the given name, and false if the block has no such attribute.
+func (b BlockSchema) Attribute(name string) (Attribute, bool) {
+	for _, a := range b.Attributes {
+		if a.Name == name {
+			return a, true
+		}
+	}
+
+	return Attribute{}, false
+}
+
+// Required returns the attributes of b that are mandatory in files with the given Message-type.
+func (b BlockSchema) Required(messageType string) []Attribute {
+	var required []Attribute
+	for _, a := range b.Attributes {
+		if a.Required(messageType) {
+			required = append(required, a)
+		}
+	}
+
+	return required
+}
+
+// headerAttributes returns the attributes of the Start- and End-message, with the named attributes mandatory.
+func headerAttributes(mandatory ...string) []Attribute {
+	attributes := []Attribute{
+		{Name: "Id", Type: AttributeString, Description: "Identifier of the file"},
+		{Name: "Message-type", Type: AttributeString, Description: "Type of the file, e.g. Settlement-data"},
+		{Name: "Version", Type: AttributeString, Description: "Version of GS2"},
+		{Name: "Time", Type: AttributeTime, Description: "When the file was created"},
+		{Name: "To", Type: AttributeString, Description: "Receiver"},
+		{Name: "From", Type: AttributeString, Description: "Sender"},
+		{Name: "Reference-table", Type: AttributeString},
+		{Name: "GMT-reference", Type: AttributeInteger, Description: "Offset in hours from UTC of all times in the file"},
+		{Name: "Number-of-objects", Type: AttributeInteger, Description: "Number of blocks in the file, including the Start- and End-message"},
+		{Name: "Type-of-objects", Type: AttributeString},
+		{Name: "Contains-objects", Type: AttributeString},
+		{Name: "Requested-action", Type: AttributeString},
+		{Name: "Description", Type: AttributeString},
+	}
+
+	for i := range attributes {
+		for _, name := range mandatory {
+			if attributes[i].Name == name {
+				attributes[i].Mandatory = true
+			}
+		}
+	}
+
+	return attributes
+}
+
+func meteringPointAttributes() []Attribute {
+	return []Attribute{
+		{Name: "Installation", Type: AttributeString},
+		{Name: "Plant", Type: AttributeString},
+		{Name: "Meter-location", Type: AttributeString},
+		{Name: "Net-owner", Type: AttributeString},
+		{Name: "Supplier", Type: AttributeString},
+		{Name: "Customer", Type: AttributeString},
+		{Name: "Meter", Type: AttributeString},
+		{Name: "Channel", Type: AttributeString},
+		{Name: "Description", Type: AttributeString},
+	}
+}
+
+func directionOfFlowValues() []string {
+	values := make([]string, len(directionsOfFlow))
+	for i, d := range directionsOfFlow {
+		values[i] = string(d)
+	}
+
+	return values
+}
+
+func typeOfValueValues() []string {
+	values := make([]string, len(typesOfValue))
+	for i, t := range typesOfValue {
+		values[i] = string(t)
+	}
+
+	return values
+}
+
+// schemas are the blocks in the order of the GS2 type.
+var schemas = []BlockSchema{
+	{Name: "Start-message", Attributes: headerAttributes("Id", "Message-type", "Version", "Time", "To", "From")},
+	{Name: "Meter-reading", Attributes: append([]Attribute{
+		{Name: "Reference", Type: AttributeString, Mandatory: true, Description: "Metering point"},
+		{Name: "Time", Type: AttributeTime, Mandatory: true, Description: "Time of the reading"},
+		{Name: "Unit", Type: AttributeString, Mandatory: true},
+		{Name: "Value", Type: AttributeTriplet, Mandatory: true, Description: "Register value"},
+	}, append(meteringPointAttributes(),
+		Attribute{Name: "Direction-of-flow", Type: AttributeString, MandatoryIn: []string{MessageTypeSettlementData}, Values: directionOfFlowValues()},
+	)...)},
+	{Name: "Time-series", Attributes: append([]Attribute{
+		{Name: "Reference", Type: AttributeString, Mandatory: true, Description: "Metering point"},
+		{Name: "Start", Type: AttributeTime, Mandatory: true, Description: "Start of the first interval"},
+		{Name: "Stop", Type: AttributeTime, Mandatory: true, Description: "End of the last interval"},
+		{Name: "Step", Type: AttributeDuration, Mandatory: true, Description: "Length of each interval"},
+		{Name: "Unit", Type: AttributeString, Mandatory: true},
+		{Name: "Type-of-value", Type: AttributeString, MandatoryIn: []string{MessageTypeSettlementData}, Values: typeOfValueValues()},
+		{Name: "Direction-of-flow", Type: AttributeString, MandatoryIn: []string{MessageTypeSettlementData}, Values: directionOfFlowValues()},
+		{Name: "Value", Type: AttributeTriplets, Mandatory: true},
+		{Name: "No-of-values", Type: AttributeInteger, Mandatory: true},
+		{Name: "Sum", Type: AttributeDecimal, Mandatory: true, Description: "Sum of the values"},
+	}, meteringPointAttributes()...)},
+	{Name: "End-message", Attributes: headerAttributes("Id", "Number-of-objects")},
+}
+
+// Schemas returns the blocks of GS2 1.2 and their attributes. Attributes are optional unless they are mandatory in all
+// message types or in the message type given by the Message-type of the Start-message.
+func Schemas() []BlockSchema {
+	result := make([]BlockSchema, len(schemas))
+	for i, s := range schemas {
+		result[i] = BlockSchema{Name: s.Name, Attributes: append([]Attribute(nil), s.Attributes...)}
+	}
+
+	return result
+}
+
+// LookupSchema returns the schema of the block with the given name, and false if there is none.
+func LookupSchema(block string) (BlockSchema, bool) {
+	for _, s := range schemas {
+		if s.Name == block {
+			return BlockSchema{Name: s.Name, Attributes: append([]Attribute(nil), s.Attributes...)}, true
+		}
+	}
+
+	return BlockSchema{}, false
+}
+
+// ValidateMandatory validates that the mandatory attributes of each block, according to the Message-type of the
+// Start-message, have values. Zero is a value, so missing integers, decimals and single triplets can't be detected after
+// decoding.
+func ValidateMandatory(g *GS2) error {
+	var errs ValidationErrors
+	messageType := g.StartMessage.MessageType
+
+	v := reflect.ValueOf(g).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		block := strings.Split(v.Type().Field(i).Tag.Get("gs2"), ",")[0]
+		schema, ok := LookupSchema(block)
+		if !ok {
+			continue
+		}
+
+		field := v.Field(i)
+		if field.Kind() != reflect.Slice {
+			if err := missingAttributes(field, schema, messageType); err != nil {
+				errs = append(errs, &ObjectError{Object: block, Err: err})
+			}
+			continue
+		}
+
+		for j := 0; j < field.Len(); j++ {
+			if err := missingAttributes(field.Index(j), schema, messageType); err != nil {
+				var reference string
+				if r := field.Index(j).FieldByName("Reference"); r.IsValid() {
+					reference = r.String()
+				}
+				errs = append(errs, &ObjectError{Object: block, Index: j, Reference: reference, Err: err})
+			}
+		}
+	}
+
+	return errs.err()
+}
+
+func missingAttributes(v reflect.Value, schema BlockSchema, messageType string) error {
+	var missing []string
+	for i := 0; i < v.NumField(); i++ {
+		name := strings.Split(v.Type().Field(i).Tag.Get("gs2"), ",")[0]
+		a, ok := schema.Attribute(name)
+		if !ok || !a.Required(messageType) || a.Type.zeroIsValue() {
+			continue
+		}
+
+		if v.Field(i).IsZero() || (v.Field(i).Kind() == reflect.Slice && v.Field(i).Len() == 0) {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return errors.New("missing mandatory " + strings.Join(missing, ", "))
+}
@@ -0,0 +1,151 @@
+package gs2
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// attributeType returns the attribute type of values of Go type t.
+func attributeType(t reflect.Type) AttributeType {
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return AttributeTime
+	case reflect.TypeOf(time.Duration(0)):
+		return AttributeDuration
+	case reflect.TypeOf(Triplet{}):
+		return AttributeTriplet
+	case reflect.TypeOf([]Triplet{}):
+		return AttributeTriplets
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return AttributeString
+	case reflect.Int:
+		return AttributeInteger
+	case reflect.Float64:
+		return AttributeDecimal
+	}
+
+	return ""
+}
+
+func TestSchemas_MatchTypes(t *testing.T) {
+	g := reflect.TypeOf(GS2{})
+	if len(Schemas()) != g.NumField() {
+		t.Fatalf("expected a schema for each of the %d blocks, but got %d", g.NumField(), len(Schemas()))
+	}
+
+	for i := 0; i < g.NumField(); i++ {
+		block := strings.Split(g.Field(i).Tag.Get("gs2"), ",")[0]
+		schema, ok := LookupSchema(block)
+		if !ok {
+			t.Errorf("no schema for %s", block)
+			continue
+		}
+
+		typ := g.Field(i).Type
+		if typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+		if typ.NumField() != len(schema.Attributes) {
+			t.Errorf("expected %d attributes in the schema of %s, but got %d", typ.NumField(), block, len(schema.Attributes))
+		}
+
+		for j := 0; j < typ.NumField(); j++ {
+			name := strings.Split(typ.Field(j).Tag.Get("gs2"), ",")[0]
+			a, ok := schema.Attribute(name)
+			if !ok {
+				t.Errorf("no attribute %s in the schema of %s", name, block)
+				continue
+			}
+
+			if expected := attributeType(typ.Field(j).Type); a.Type != expected {
+				t.Errorf("expected %s %s to be %s, but got %s", block, name, expected, a.Type)
+			}
+		}
+	}
+}
+
+func TestAttribute_Required(t *testing.T) {
+	schema, _ := LookupSchema("Time-series")
+
+	for _, test := range []struct {
+		attribute   string
+		messageType string
+		expected    bool
+	}{
+		{"Reference", "", true},
+		{"Type-of-value", MessageTypeSettlementData, true},
+		{"Type-of-value", "Other", false},
+		{"Meter", MessageTypeSettlementData, false},
+	} {
+		a, _ := schema.Attribute(test.attribute)
+		if a.Required(test.messageType) != test.expected {
+			t.Errorf("expected %s to be mandatory in %q: %v", test.attribute, test.messageType, test.expected)
+		}
+	}
+
+	if a, _ := schema.Attribute("Direction-of-flow"); !a.Allowed("in") || a.Allowed("up") {
+		t.Errorf("unexpected allowed values %q", a.Values)
+	}
+}
+
+func TestValidateMandatory(t *testing.T) {
+	g := &GS2{
+		StartMessage: StartMessage{ID: "0", MessageType: MessageTypeSettlementData, Version: "1.2", Time: getTime("2020-04-04T20:00:00Z"), From: "a"},
+		TimeSeries: []TimeSeries{
+			{Reference: "ok", Start: getTime("2020-04-03T00:00:00Z"), Stop: getTime("2020-04-03T01:00:00Z"), Step: time.Hour,
+				Unit: "kWh", TypeOfValue: TypeInterval, DirectionOfFlow: DirectionIn, Value: []Triplet{{Value: 0}}, NoOfValues: 1},
+			{Reference: "missing", Start: getTime("2020-04-03T00:00:00Z"), Stop: getTime("2020-04-03T01:00:00Z"), Step: time.Hour,
+				Value: []Triplet{{Value: 0}}, NoOfValues: 1},
+		},
+		EndMessage: EndMessage{ID: "0", NumberOfObjects: 4},
+	}
+
+	errs := Errors(ValidateMandatory(g))
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, but got %d: %v", len(errs), errs)
+	}
+
+	for i, expected := range []string{
+		"Start-message 0: missing mandatory To",
+		"Time-series 1 (missing): missing mandatory Unit, Type-of-value, Direction-of-flow",
+	} {
+		var objectErr *ObjectError
+		if !errors.As(errs[i], &objectErr) || errs[i].Error() != expected {
+			t.Errorf("expected %q, but got %v", expected, errs[i])
+		}
+	}
+
+	g.StartMessage.MessageType = "Other"
+	g.StartMessage.To = "b"
+	g.TimeSeries[1].Unit = "kWh"
+	if err := ValidateMandatory(g); err != nil {
+		t.Errorf("expected no errors for other message types, but got %v", err)
+	}
+}
+
+func TestEncoder_MandatoryZeroValues(t *testing.T) {
+	g := &GS2{
+		StartMessage:  StartMessage{ID: "0", MessageType: MessageTypeSettlementData},
+		MeterReadings: []MeterReading{{Reference: "a"}},
+		EndMessage:    EndMessage{ID: "0", NumberOfObjects: 3},
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(g); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "##Start-message\n#Id=0\n#Message-type=Settlement-data\n\n" +
+		"##Meter-reading\n#Reference=a\n#Value=0//\n\n" +
+		"##End-message\n#Id=0\n#Number-of-objects=3\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s got:\n%s", expected, buf.String())
+	}
+}
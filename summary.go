@@ -0,0 +1,196 @@
+package gs2
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Summary gives an overview of the content of a GS2 object.
+type Summary struct {
+	Header     Header          `json:"header"`
+	Objects    map[string]int  `json:"objects"`
+	Meters     []string        `json:"meters"`
+	References []string        `json:"references"`
+	Period     Period          `json:"period"`
+	Steps      map[string]int  `json:"steps"`
+	Units      map[string]int  `json:"units"`
+	Energy     []EnergyTotal   `json:"energy"`
+	Qualities  map[string]int  `json:"qualities"`
+	Series     []SeriesSummary `json:"series"`
+}
+
+// Header contains the fields of the StartMessage describing the file.
+type Header struct {
+	ID           string    `json:"id"`
+	MessageType  string    `json:"messageType"`
+	Version      string    `json:"version"`
+	Time         time.Time `json:"time"`
+	From         string    `json:"from"`
+	To           string    `json:"to"`
+	GMTReference int       `json:"gmtReference"`
+}
+
+// Period is the time range covered by the time series and meter readings, in UTC and in the local time given by the
+// GMT-reference of the StartMessage.
+type Period struct {
+	Start      time.Time `json:"start"`
+	Stop       time.Time `json:"stop"`
+	LocalStart time.Time `json:"localStart"`
+	LocalStop  time.Time `json:"localStop"`
+}
+
+// EnergyTotal is the sum of all time series values with the same direction of flow and unit.
+type EnergyTotal struct {
+	DirectionOfFlow DirectionOfFlow `json:"directionOfFlow"`
+	Unit            string          `json:"unit"`
+	Total           float64         `json:"total"`
+}
+
+// SeriesSummary contains statistics for the values of a single time series.
+type SeriesSummary struct {
+	Reference string    `json:"reference"`
+	Meter     string    `json:"meter"`
+	Values    int       `json:"values"`
+	Min       float64   `json:"min"`
+	Max       float64   `json:"max"`
+	Mean      float64   `json:"mean"`
+	Peak      time.Time `json:"peak"` // Start of the interval with the largest value.
+}
+
+// Summary returns an overview of g.
+func (g *GS2) Summary() Summary {
+	s := Summary{
+		Header: Header{
+			ID:           g.StartMessage.ID,
+			MessageType:  g.StartMessage.MessageType,
+			Version:      g.StartMessage.Version,
+			Time:         g.StartMessage.Time,
+			From:         g.StartMessage.From,
+			To:           g.StartMessage.To,
+			GMTReference: g.StartMessage.GMTReference,
+		},
+		Objects: map[string]int{
+			"Start-message": 1,
+			"Meter-reading": len(g.MeterReadings),
+			"Time-series":   len(g.TimeSeries),
+			"End-message":   1,
+		},
+		Meters:     []string{},
+		References: []string{},
+		Steps:      make(map[string]int),
+		Units:      make(map[string]int),
+		Energy:     []EnergyTotal{},
+		Qualities:  make(map[string]int),
+		Series:     []SeriesSummary{},
+	}
+
+	meters := make(map[string]bool)
+	references := make(map[string]bool)
+	addIdentity := func(meter, reference string) {
+		if meter != "" {
+			meters[meter] = true
+		}
+		if reference != "" {
+			references[reference] = true
+		}
+	}
+
+	var period Period
+	extend := func(start, stop time.Time) {
+		if start.IsZero() || stop.IsZero() {
+			return
+		}
+		if period.Start.IsZero() || start.Before(period.Start) {
+			period.Start = start
+		}
+		if period.Stop.IsZero() || stop.After(period.Stop) {
+			period.Stop = stop
+		}
+	}
+
+	for _, mr := range g.MeterReadings {
+		addIdentity(mr.Meter, mr.Reference)
+		extend(mr.Time, mr.Time)
+		s.Units[mr.Unit]++
+		s.Qualities[string(mr.Value.Quality)]++
+	}
+
+	energy := make(map[[2]string]float64)
+	for _, ts := range g.TimeSeries {
+		addIdentity(ts.Meter, ts.Reference)
+		extend(ts.Start, ts.Stop)
+		s.Steps[ts.Step.String()]++
+		s.Units[ts.Unit]++
+
+		series := SeriesSummary{Reference: ts.Reference, Meter: ts.Meter, Values: len(ts.Value)}
+		if len(ts.Value) > 0 {
+			series.Min, series.Max = math.Inf(1), math.Inf(-1)
+		}
+
+		var sum float64
+		for i, v := range ts.Value {
+			s.Qualities[string(v.Quality)]++
+			sum += v.Value
+
+			if v.Value < series.Min {
+				series.Min = v.Value
+			}
+			if v.Value > series.Max {
+				series.Max = v.Value
+				series.Peak = ts.ValueTime(i)
+			}
+		}
+
+		if len(ts.Value) > 0 {
+			series.Mean = sum / float64(len(ts.Value))
+		}
+
+		energy[[2]string{string(ts.DirectionOfFlow), ts.Unit}] += sum
+		s.Series = append(s.Series, series)
+	}
+
+	for key, total := range energy {
+		s.Energy = append(s.Energy, EnergyTotal{DirectionOfFlow: DirectionOfFlow(key[0]), Unit: key[1], Total: total})
+	}
+	sort.Slice(s.Energy, func(i, j int) bool {
+		if s.Energy[i].DirectionOfFlow != s.Energy[j].DirectionOfFlow {
+			return s.Energy[i].DirectionOfFlow < s.Energy[j].DirectionOfFlow
+		}
+		return s.Energy[i].Unit < s.Energy[j].Unit
+	})
+
+	s.Meters = sortedKeys(meters)
+	s.References = sortedKeys(references)
+
+	local := g.StartMessage.Location()
+	if !period.Start.IsZero() {
+		period.Start = period.Start.UTC()
+		period.Stop = period.Stop.UTC()
+		period.LocalStart = period.Start.In(local)
+		period.LocalStop = period.Stop.In(local)
+	}
+	s.Period = period
+
+	return s
+}
+
+// ValueTime returns the start of the interval of the i'th value. Explicit times in the value triplets take precedence over
+// Start and Step.
+func (ts *TimeSeries) ValueTime(i int) time.Time {
+	if t := ts.Value[i].Time; !t.IsZero() {
+		return t
+	}
+
+	return ts.Start.Add(time.Duration(i) * ts.Step)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,80 @@
+package gs2
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGS2_Summary(t *testing.T) {
+	file, err := os.Open("testdata/durations.gs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := NewDecoder(file).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := g.Summary()
+
+	if s.Header.From != "Sender" || s.Header.GMTReference != 2 {
+		t.Errorf("unexpected header %+v", s.Header)
+	}
+
+	expectedObjects := map[string]int{"Start-message": 1, "Meter-reading": 0, "Time-series": 4, "End-message": 1}
+	if !reflect.DeepEqual(s.Objects, expectedObjects) {
+		t.Errorf("expected objects %v, but got %v", expectedObjects, s.Objects)
+	}
+
+	expectedReferences := []string{"meterpoint1", "meterpoint2", "meterpoint3", "meterpoint4"}
+	if !reflect.DeepEqual(s.References, expectedReferences) {
+		t.Errorf("expected references %v, but got %v", expectedReferences, s.References)
+	}
+
+	if !s.Period.Start.Equal(getTime("2020-03-26T22:00:00Z")) || !s.Period.Stop.Equal(getTime("2020-03-27T08:00:00Z")) {
+		t.Errorf("unexpected period %+v", s.Period)
+	}
+	if s.Period.LocalStart.Format(gs2TimeLayout) != "2020-03-27.00:00:00" {
+		t.Errorf("expected local start in GMT+2, but got %v", s.Period.LocalStart)
+	}
+
+	expectedSteps := map[string]int{"1h0m0s": 1, "2h0m0s": 1, "15m0s": 1, "1s": 1}
+	if !reflect.DeepEqual(s.Steps, expectedSteps) {
+		t.Errorf("expected steps %v, but got %v", expectedSteps, s.Steps)
+	}
+
+	if len(s.Energy) != 1 || s.Energy[0].Total < 22081.614 || s.Energy[0].Total > 22081.616 {
+		t.Errorf("unexpected energy totals %+v", s.Energy)
+	}
+
+	expectedSeries := SeriesSummary{
+		Reference: "meterpoint1",
+		Values:    10,
+		Min:       1,
+		Max:       10,
+		Mean:      5.5,
+		Peak:      getTime("2020-03-26T22:00:00Z").Add(9 * time.Hour),
+	}
+	if !reflect.DeepEqual(s.Series[0], expectedSeries) {
+		t.Errorf("expected series summary %+v, but got %+v", expectedSeries, s.Series[0])
+	}
+}
+
+func TestTimeSeries_ValueTime(t *testing.T) {
+	explicit := getTime("2020-01-01T12:00:00Z")
+	ts := TimeSeries{
+		Start: getTime("2020-01-01T00:00:00Z"),
+		Step:  time.Hour,
+		Value: []Triplet{{}, {}, {Time: explicit}},
+	}
+
+	if vt := ts.ValueTime(1); !vt.Equal(getTime("2020-01-01T01:00:00Z")) {
+		t.Errorf("expected time from Start and Step, but got %v", vt)
+	}
+	if vt := ts.ValueTime(2); !vt.Equal(explicit) {
+		t.Errorf("expected explicit time, but got %v", vt)
+	}
+}
@@ -1,13 +1,79 @@
 package gs2
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // Validator is a function taking in a refenrece to a GS" object and returns an error if its not valid.
 type Validator func(*GS2) error
 
+// ContextValidator is a Validator that also takes a context, for validators doing I/O like registry lookups. It should stop
+// and return the error of ctx when ctx is done.
+type ContextValidator func(ctx context.Context, g *GS2) error
+
+// ObjectError is returned by validators when a single object in a GS2 object is invalid.
+type ObjectError struct {
+	Object    string // Name of the block, e.g. Time-series.
+	Index     int    // Index of the object among the blocks with the same name.
+	Reference string // Reference of the object, if any.
+	Values    []int  // Positions of the offending values in the object, if any.
+	Err       error
+}
+
+func (e *ObjectError) Error() string {
+	if e.Reference != "" {
+		return fmt.Sprintf("%s %d (%s): %v", e.Object, e.Index, e.Reference, e.Err)
+	}
+
+	return fmt.Sprintf("%s %d: %v", e.Object, e.Index, e.Err)
+}
+
+func (e *ObjectError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors is returned by validators reporting more than one error. Use Errors to get the errors it contains.
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Errors returns the errors in err. If err is a ValidationErrors the errors it contains are returned.
+func Errors(err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	if errs, ok := err.(ValidationErrors); ok {
+		return errs
+	}
+
+	return []error{err}
+}
+
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
+
 // ValidateNoOfObjects validates that the reported number of objects are equal to the actual number of objects in the decoded object.
 func ValidateNoOfObjects(g *GS2) error {
 	startNoOfObjects := g.StartMessage.NumberOfObjects
@@ -38,9 +104,11 @@ const delta = 0.000001
 // ValidateTimeSeriesValues validates the number ov values are consistent and that sum og values in a time series block are equal to the
 // sum attribute.
 func ValidateTimeSeriesValues(g *GS2) error {
-	for _, timeSeries := range g.TimeSeries {
+	var errs ValidationErrors
+
+	for i, timeSeries := range g.TimeSeries {
 		if len(timeSeries.Value) != timeSeries.NoOfValues {
-			return fmt.Errorf("the number of values does not equal the No-of-values attribute. Expected %d, but got %d", timeSeries.NoOfValues, len(timeSeries.Value))
+			errs = append(errs, timeSeriesError(i, timeSeries, fmt.Errorf("the number of values does not equal the No-of-values attribute. Expected %d, but got %d", timeSeries.NoOfValues, len(timeSeries.Value))))
 		}
 
 		var sum float64
@@ -49,9 +117,339 @@ func ValidateTimeSeriesValues(g *GS2) error {
 		}
 
 		if math.Abs(sum-timeSeries.Sum) > delta {
-			return fmt.Errorf("calculated sum is different from sum attribute. Expected: %f, but calculated %f", timeSeries.Sum, sum)
+			errs = append(errs, timeSeriesError(i, timeSeries, fmt.Errorf("calculated sum is different from sum attribute. Expected: %f, but calculated %f", timeSeries.Sum, sum)))
+		}
+	}
+
+	return errs.err()
+}
+
+// ValidateTimeSeriesPeriod validates that Start, Stop, Step and No-of-values of every time series agree, so Start + No-of-values
+// * Step equals Stop. Steps of whole days are calendar days in the time zone given by the GMT-reference. Stop may be given as
+// 24:00:00 of the day before. If values have explicit times, each value must be within Start and Stop instead, as the values
+// may not be contiguous.
+func ValidateTimeSeriesPeriod(g *GS2) error {
+	return validateTimeSeriesPeriod(g, g.StartMessage.Location())
+}
+
+// ValidateTimeSeriesPeriodIn returns a Validator like ValidateTimeSeriesPeriod, where steps of whole days are calendar days in
+// loc. Use it for daily values in a time zone with daylight saving time, where local days have 23 or 25 hours.
+func ValidateTimeSeriesPeriodIn(loc *time.Location) Validator {
+	return func(g *GS2) error {
+		return validateTimeSeriesPeriod(g, loc)
+	}
+}
+
+func validateTimeSeriesPeriod(g *GS2, loc *time.Location) error {
+	var errs ValidationErrors
+
+	for i, ts := range g.TimeSeries {
+		if err := timeSeriesPeriodError(ts, loc); err != nil {
+			errs = append(errs, timeSeriesError(i, ts, err))
+		}
+	}
+
+	return errs.err()
+}
+
+func timeSeriesPeriodError(ts TimeSeries, loc *time.Location) error {
+	switch {
+	case ts.Start.IsZero() || ts.Stop.IsZero():
+		return fmt.Errorf("start and stop must both be given")
+	case ts.Step <= 0:
+		return fmt.Errorf("step must be positive, but is %v", ts.Step)
+	case ts.Stop.Before(ts.Start):
+		return fmt.Errorf("stop %s is before start %s", formatPeriodTime(ts.Stop, loc), formatPeriodTime(ts.Start, loc))
+	}
+
+	var explicit []int
+	for j, v := range ts.Value {
+		if !v.Time.IsZero() {
+			explicit = append(explicit, j)
 		}
 	}
 
+	if len(explicit) == 0 {
+		if stop := addSteps(ts.Start, ts.Step, ts.NoOfValues, loc); !stop.Equal(ts.Stop) {
+			return fmt.Errorf("start %s + %d values * step %v is %s, but stop is %s", formatPeriodTime(ts.Start, loc), ts.NoOfValues, ts.Step,
+				formatPeriodTime(stop, loc), formatPeriodTime(ts.Stop, loc))
+		}
+
+		return nil
+	}
+
+	var outside []string
+	for _, j := range explicit {
+		t := ts.Value[j].Time
+		if t.Before(ts.Start) || addSteps(t, ts.Step, 1, loc).After(ts.Stop) {
+			outside = append(outside, fmt.Sprintf("%d (%s)", j, formatPeriodTime(t, loc)))
+		}
+	}
+	if len(outside) > 0 {
+		return fmt.Errorf("values %s are outside start %s and stop %s", strings.Join(outside, ", "), formatPeriodTime(ts.Start, loc),
+			formatPeriodTime(ts.Stop, loc))
+	}
+
+	if slots := countSteps(ts.Start, ts.Stop, ts.Step, loc); ts.NoOfValues > slots {
+		return fmt.Errorf("%d values don't fit in the %d steps from start %s to stop %s", ts.NoOfValues, slots, formatPeriodTime(ts.Start, loc),
+			formatPeriodTime(ts.Stop, loc))
+	}
+
 	return nil
 }
+
+// addSteps returns t + n * step. Steps of whole days are added as calendar days in loc.
+func addSteps(t time.Time, step time.Duration, n int, loc *time.Location) time.Time {
+	if step%(24*time.Hour) == 0 {
+		return t.In(loc).AddDate(0, 0, n*int(step/(24*time.Hour))).UTC()
+	}
+
+	return t.Add(time.Duration(n) * step)
+}
+
+// countSteps returns the number of whole steps from start to stop.
+func countSteps(start, stop time.Time, step time.Duration, loc *time.Location) int {
+	n := int(stop.Sub(start) / step)
+	for n > 0 && addSteps(start, step, n, loc).After(stop) {
+		n--
+	}
+	for !addSteps(start, step, n+1, loc).After(stop) {
+		n++
+	}
+
+	return n
+}
+
+func formatPeriodTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(time.RFC3339)
+}
+
+// ValidateVocabulary validates that Direction-of-flow, Type-of-value and the quality of every value are known values from the
+// GS2 1.2 vocabularies, as given by the allowed values of the attributes in Schemas. The decoder keeps unknown values from
+// vendors, so this validator is only for strict checking.
+func ValidateVocabulary(g *GS2) error {
+	var errs ValidationErrors
+
+	for i, mr := range g.MeterReadings {
+		problems := unknownValues("Meter-reading", map[string]string{"Direction-of-flow": string(mr.DirectionOfFlow)})
+		if !mr.Value.Quality.Known() {
+			problems = append(problems, fmt.Sprintf("unknown quality %q", mr.Value.Quality))
+		}
+		if len(problems) > 0 {
+			errs = append(errs, &ObjectError{Object: "Meter-reading", Index: i, Reference: mr.Reference, Err: errors.New(strings.Join(problems, ", "))})
+		}
+	}
+
+	for i, ts := range g.TimeSeries {
+		problems := unknownValues("Time-series", map[string]string{
+			"Type-of-value":     string(ts.TypeOfValue),
+			"Direction-of-flow": string(ts.DirectionOfFlow),
+		})
+		problems = append(problems, unknownQualities(ts, Quality.Known)...)
+
+		if len(problems) > 0 {
+			errs = append(errs, timeSeriesError(i, ts, errors.New(strings.Join(problems, ", "))))
+		}
+	}
+
+	return errs.err()
+}
+
+// unknownValues returns a problem for each non-empty value that isn't one of the allowed values of its attribute in the
+// schema of the block, in the order of the attributes in the schema.
+func unknownValues(block string, values map[string]string) []string {
+	schema, _ := LookupSchema(block)
+
+	var problems []string
+	for _, a := range schema.Attributes {
+		if v, ok := values[a.Name]; ok && v != "" && !a.Allowed(v) {
+			problems = append(problems, fmt.Sprintf("unknown %s %q", a.Name, v))
+		}
+	}
+
+	return problems
+}
+
+// ValidateUTF8 validates that the text attributes of each block are valid UTF-8. The decoder keeps the bytes of text as
+// they are, so files in e.g. ISO 8859-1 are decoded without errors.
+func ValidateUTF8(g *GS2) error {
+	var errs ValidationErrors
+
+	v := reflect.ValueOf(g).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		block := strings.Split(v.Type().Field(i).Tag.Get("gs2"), ",")[0]
+
+		field := v.Field(i)
+		if field.Kind() != reflect.Slice {
+			if err := invalidUTF8(field); err != nil {
+				errs = append(errs, &ObjectError{Object: block, Err: err})
+			}
+			continue
+		}
+
+		for j := 0; j < field.Len(); j++ {
+			if err := invalidUTF8(field.Index(j)); err != nil {
+				errs = append(errs, &ObjectError{Object: block, Index: j, Reference: field.Index(j).FieldByName("Reference").String(), Err: err})
+			}
+		}
+	}
+
+	return errs.err()
+}
+
+func invalidUTF8(v reflect.Value) error {
+	var invalid []string
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.Kind() == reflect.String && !utf8.ValidString(f.String()) {
+			invalid = append(invalid, strings.Split(v.Type().Field(i).Tag.Get("gs2"), ",")[0])
+		}
+	}
+
+	if len(invalid) == 0 {
+		return nil
+	}
+
+	return errors.New("invalid UTF-8 in " + strings.Join(invalid, ", "))
+}
+
+// ValidateValueTimes validates that the explicit times of the values in every time series increase strictly, so no value is
+// before the value preceding it or at the same time. Values without explicit times are not checked.
+func ValidateValueTimes(g *GS2) error {
+	var errs ValidationErrors
+	loc := g.StartMessage.Location()
+
+	for i, ts := range g.TimeSeries {
+		prev := -1
+		for j, v := range ts.Value {
+			if v.Time.IsZero() {
+				continue
+			}
+
+			if prev >= 0 {
+				prevTime := ts.Value[prev].Time
+				var err error
+				switch {
+				case v.Time.Equal(prevTime):
+					err = fmt.Errorf("values %d and %d have the same time %s", prev, j, v.Time.In(loc).Format(time.RFC3339))
+				case v.Time.Before(prevTime):
+					err = fmt.Errorf("value %d at %s is before value %d at %s", j, v.Time.In(loc).Format(time.RFC3339), prev,
+						prevTime.In(loc).Format(time.RFC3339))
+				}
+				if err != nil {
+					oe := timeSeriesError(i, ts, err)
+					oe.Values = []int{prev, j}
+					errs = append(errs, oe)
+					continue
+				}
+			}
+
+			prev = j
+		}
+	}
+
+	return errs.err()
+}
+
+// ValidateMeterReadingOrder validates that the meter readings of every meter and channel are in chronological order without
+// duplicate times, and that the register value never decreases, unless it is explained by the register rolling over. Meter
+// readings without a meter are grouped by reference.
+//
+// A decrease is taken as a rollover when the register value before the decrease has d digits, and the consumption through
+// 10^d is less than a tenth of 10^d, e.g. from 99 950 to 30.
+func ValidateMeterReadingOrder(g *GS2) error {
+	var errs ValidationErrors
+	loc := g.StartMessage.Location()
+
+	type register struct{ meter, channel string }
+	groups := map[register][]int{}
+	var order []register
+	for i, mr := range g.MeterReadings {
+		key := register{mr.Meter, mr.Channel}
+		if mr.Meter == "" {
+			key.meter = "reference " + mr.Reference
+		}
+		if _, exists := groups[key]; !exists {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	for _, key := range order {
+		indexes := groups[key]
+
+		for n := 1; n < len(indexes); n++ {
+			prev, cur := g.MeterReadings[indexes[n-1]], g.MeterReadings[indexes[n]]
+			var err error
+			switch {
+			case cur.Time.Equal(prev.Time):
+				err = fmt.Errorf("same time %s as Meter-reading %d", cur.Time.In(loc).Format(time.RFC3339), indexes[n-1])
+			case cur.Time.Before(prev.Time):
+				err = fmt.Errorf("time %s is before Meter-reading %d at %s", cur.Time.In(loc).Format(time.RFC3339), indexes[n-1],
+					prev.Time.In(loc).Format(time.RFC3339))
+			}
+			if err != nil {
+				errs = append(errs, meterReadingError(indexes[n], cur, indexes[n-1], err))
+			}
+		}
+
+		byTime := append([]int(nil), indexes...)
+		sort.SliceStable(byTime, func(a, b int) bool {
+			return g.MeterReadings[byTime[a]].Time.Before(g.MeterReadings[byTime[b]].Time)
+		})
+
+		for n := 1; n < len(byTime); n++ {
+			prev, cur := g.MeterReadings[byTime[n-1]], g.MeterReadings[byTime[n]]
+			if cur.Value.Value >= prev.Value.Value || rollover(prev.Value.Value, cur.Value.Value) {
+				continue
+			}
+
+			err := fmt.Errorf("register value %s is less than %s of Meter-reading %d", strconv.FormatFloat(cur.Value.Value, 'f', -1, 64),
+				strconv.FormatFloat(prev.Value.Value, 'f', -1, 64), byTime[n-1])
+			errs = append(errs, meterReadingError(byTime[n], cur, byTime[n-1], err))
+		}
+	}
+
+	return errs.err()
+}
+
+// rollover reports whether the register going from prev to cur is explained by the register rolling over.
+func rollover(prev, cur float64) bool {
+	if prev <= 0 || cur < 0 {
+		return false
+	}
+
+	wrap := math.Pow10(int(math.Floor(math.Log10(prev))) + 1)
+	return wrap-prev+cur < wrap/10
+}
+
+// meterReadingError returns an error for the meter reading at index i, where the offending values are the meter readings at
+// indexes other and i.
+func meterReadingError(i int, mr MeterReading, other int, err error) *ObjectError {
+	return &ObjectError{Object: "Meter-reading", Index: i, Reference: mr.Reference, Values: []int{other, i}, Err: err}
+}
+
+// unknownQualities describes the qualities of values in ts that aren't known, with the positions of the values.
+func unknownQualities(ts TimeSeries, known func(Quality) bool) []string {
+	positions := map[Quality][]string{}
+	var order []Quality
+	for j, v := range ts.Value {
+		if known(v.Quality) {
+			continue
+		}
+		if _, exists := positions[v.Quality]; !exists {
+			order = append(order, v.Quality)
+		}
+		positions[v.Quality] = append(positions[v.Quality], strconv.Itoa(j))
+	}
+
+	var problems []string
+	for _, q := range order {
+		problems = append(problems, fmt.Sprintf("unknown quality %q in values %s", q, strings.Join(positions[q], ", ")))
+	}
+
+	return problems
+}
+
+func timeSeriesError(i int, ts TimeSeries, err error) *ObjectError {
+	return &ObjectError{Object: "Time-series", Index: i, Reference: ts.Reference, Err: err}
+}
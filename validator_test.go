@@ -0,0 +1,251 @@
+package gs2
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTimeSeriesValues(t *testing.T) {
+	g := &GS2{
+		TimeSeries: []TimeSeries{
+			{Reference: "ok", Value: []Triplet{{Value: 1}, {Value: 2}}, NoOfValues: 2, Sum: 3},
+			{Reference: "wrongCount", Value: []Triplet{{Value: 1}, {Value: 2}}, NoOfValues: 3, Sum: 3},
+			{Reference: "wrongSum", Value: []Triplet{{Value: 1}, {Value: 2}}, NoOfValues: 2, Sum: 4},
+		},
+	}
+
+	errs := Errors(ValidateTimeSeriesValues(g))
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, but got %d: %v", len(errs), errs)
+	}
+
+	for i, expected := range []int{1, 2} {
+		var objectErr *ObjectError
+		if !errors.As(errs[i], &objectErr) {
+			t.Fatalf("expected an ObjectError, but got %T", errs[i])
+		}
+
+		if objectErr.Object != "Time-series" || objectErr.Index != expected || objectErr.Reference != g.TimeSeries[expected].Reference {
+			t.Errorf("unexpected object in error: %v", objectErr)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	err := error(ValidationErrors{errors.New("first"), &ObjectError{Object: "Time-series", Index: 1, Err: errors.New("second")}})
+
+	errs := Errors(err)
+	var objectErr *ObjectError
+	if len(errs) != 2 || !errors.As(errs[1], &objectErr) || objectErr.Index != 1 {
+		t.Errorf("expected the ObjectError as the second error, but got %v", errs)
+	}
+
+	if err.Error() != "first; Time-series 1: second" {
+		t.Errorf("unexpected error message %q", err.Error())
+	}
+
+	if errs := Errors(errors.New("single")); len(errs) != 1 {
+		t.Errorf("expected a single error, but got %v", errs)
+	}
+}
+
+func TestValidateTimeSeriesPeriod(t *testing.T) {
+	oslo, err := time.LoadLocation("Europe/Oslo")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	day := 24 * time.Hour
+	hourly := []Triplet{{Value: 1}, {Value: 2}, {Value: 3}}
+
+	for i, test := range []struct {
+		ts    TimeSeries
+		loc   *time.Location
+		valid bool
+	}{
+		{TimeSeries{Start: getTime("2020-01-01T00:00:00Z"), Stop: getTime("2020-01-01T03:00:00Z"), Step: time.Hour, NoOfValues: 3, Value: hourly}, time.UTC, true},
+		{TimeSeries{Start: getTime("2020-01-01T00:00:00Z"), Stop: getTime("2020-01-01T04:00:00Z"), Step: time.Hour, NoOfValues: 3, Value: hourly}, time.UTC, false},
+		{TimeSeries{Start: getTime("2020-01-01T00:00:00Z"), Step: time.Hour, NoOfValues: 3, Value: hourly}, time.UTC, false},
+		{TimeSeries{Start: getTime("2020-01-01T00:00:00Z"), Stop: getTime("2020-01-01T03:00:00Z"), NoOfValues: 3, Value: hourly}, time.UTC, false},
+		// Hourly values over the spring DST change, which has 23 hours in local time.
+		{TimeSeries{Start: getTime("2020-03-28T23:00:00Z"), Stop: getTime("2020-03-29T22:00:00Z"), Step: time.Hour, NoOfValues: 23}, oslo, true},
+		// Daily values from local midnight to local midnight over both DST changes.
+		{TimeSeries{Start: getTime("2020-03-28T23:00:00Z"), Stop: getTime("2020-03-29T22:00:00Z"), Step: day, NoOfValues: 1}, oslo, true},
+		{TimeSeries{Start: getTime("2020-10-24T22:00:00Z"), Stop: getTime("2020-10-25T23:00:00Z"), Step: day, NoOfValues: 1}, oslo, true},
+		{TimeSeries{Start: getTime("2020-03-01T23:00:00Z"), Stop: getTime("2020-03-31T22:00:00Z"), Step: day, NoOfValues: 30}, oslo, true},
+		{TimeSeries{Start: getTime("2020-03-28T23:00:00Z"), Stop: getTime("2020-03-29T23:00:00Z"), Step: day, NoOfValues: 1}, oslo, false},
+		{TimeSeries{Start: getTime("2020-03-28T23:00:00Z"), Stop: getTime("2020-03-29T23:00:00Z"), Step: day, NoOfValues: 1}, time.FixedZone("", 3600), true},
+		// Explicit times within the period, with a gap.
+		{TimeSeries{Start: getTime("2020-01-01T00:00:00Z"), Stop: getTime("2020-01-01T03:00:00Z"), Step: time.Hour, NoOfValues: 2,
+			Value: []Triplet{{Value: 1, Time: getTime("2020-01-01T00:00:00Z")}, {Value: 2, Time: getTime("2020-01-01T02:00:00Z")}}}, time.UTC, true},
+		{TimeSeries{Start: getTime("2020-01-01T00:00:00Z"), Stop: getTime("2020-01-01T03:00:00Z"), Step: time.Hour, NoOfValues: 2,
+			Value: []Triplet{{Value: 1, Time: getTime("2020-01-01T00:00:00Z")}, {Value: 2, Time: getTime("2020-01-01T03:00:00Z")}}}, time.UTC, false},
+		{TimeSeries{Start: getTime("2020-01-01T00:00:00Z"), Stop: getTime("2020-01-01T01:00:00Z"), Step: time.Hour, NoOfValues: 2,
+			Value: []Triplet{{Value: 1, Time: getTime("2020-01-01T00:00:00Z")}, {Value: 2}}}, time.UTC, false},
+	} {
+		err := ValidateTimeSeriesPeriodIn(test.loc)(&GS2{TimeSeries: []TimeSeries{test.ts}})
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("%d: expected valid to be %v, but got %v", i, test.valid, err)
+		}
+	}
+}
+
+func TestValidateTimeSeriesPeriod_Decoded(t *testing.T) {
+	src := `##Start-message
+#GMT-reference=+1
+##Time-series
+#Start=2020-01-01.00:00:00
+#Stop=2020-01-02.24:00:00
+#Step=0000-00-01.00:00:00
+#Value=< 1 2 >
+#No-of-values=2
+#Sum=3
+##Time-series
+#Start=2020-01-01.00:00:00
+#Stop=2020-01-01.03:00:00
+#Step=0000-00-00.01:00:00
+#Value=< 1/2020-01-01.00:00:00/ 2/2020-01-01.02:00:00/ >
+#No-of-values=2
+#Sum=3
+##End-message
+#Number-of-objects=4
+`
+
+	g, err := NewDecoder(strings.NewReader(src), DecodeValidators(ValidateTimeSeriesPeriod)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.TimeSeries[0].Step != 24*time.Hour {
+		t.Errorf("expected step of a day, but got %v", g.TimeSeries[0].Step)
+	}
+
+	if expected := getTime("2020-01-01T01:00:00Z"); !g.TimeSeries[1].Value[1].Time.Equal(expected) {
+		t.Errorf("expected value time %v in UTC, but got %v", expected, g.TimeSeries[1].Value[1].Time)
+	}
+}
+
+func TestValidateValueTimes(t *testing.T) {
+	at := func(s string) Triplet { return Triplet{Time: getTime(s)} }
+
+	g := &GS2{
+		TimeSeries: []TimeSeries{
+			{Reference: "ok", Value: []Triplet{at("2020-01-01T00:00:00Z"), {}, at("2020-01-01T02:00:00Z")}},
+			{Reference: "duplicate", Value: []Triplet{at("2020-01-01T00:00:00Z"), at("2020-01-01T01:00:00Z"), at("2020-01-01T01:00:00Z")}},
+			{Reference: "backwards", Value: []Triplet{at("2020-01-01T02:00:00Z"), {}, at("2020-01-01T01:00:00Z"), at("2020-01-01T03:00:00Z")}},
+		},
+	}
+
+	errs := Errors(ValidateValueTimes(g))
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, but got %d: %v", len(errs), errs)
+	}
+
+	for i, expected := range []struct {
+		index  int
+		values []int
+	}{
+		{1, []int{1, 2}},
+		{2, []int{0, 2}},
+	} {
+		var objectErr *ObjectError
+		if !errors.As(errs[i], &objectErr) || objectErr.Index != expected.index || !reflect.DeepEqual(objectErr.Values, expected.values) {
+			t.Errorf("expected error for values %v of time series %d, but got %v", expected.values, expected.index, errs[i])
+		}
+	}
+}
+
+func TestValidateMeterReadingOrder(t *testing.T) {
+	reading := func(meter, channel, time string, value float64) MeterReading {
+		return MeterReading{Meter: meter, Channel: channel, Time: getTime(time), Value: Triplet{Value: value}}
+	}
+
+	for i, test := range []struct {
+		readings []MeterReading
+		values   [][]int
+	}{
+		{
+			[]MeterReading{
+				reading("a", "1", "2020-01-01T00:00:00Z", 100),
+				reading("a", "2", "2020-01-01T00:00:00Z", 5),
+				reading("a", "1", "2020-01-02T00:00:00Z", 110),
+				reading("b", "1", "2020-01-01T00:00:00Z", 1),
+			},
+			nil,
+		},
+		{
+			[]MeterReading{
+				reading("a", "1", "2020-01-02T00:00:00Z", 100),
+				reading("a", "1", "2020-01-01T00:00:00Z", 90),
+			},
+			[][]int{{0, 1}},
+		},
+		{
+			[]MeterReading{
+				reading("a", "1", "2020-01-01T00:00:00Z", 100),
+				reading("a", "1", "2020-01-01T00:00:00Z", 100),
+			},
+			[][]int{{0, 1}},
+		},
+		{
+			[]MeterReading{
+				reading("a", "1", "2020-01-01T00:00:00Z", 100),
+				reading("a", "1", "2020-01-02T00:00:00Z", 90),
+			},
+			[][]int{{0, 1}},
+		},
+		{
+			// The register rolls over from 99 950 to 30.
+			[]MeterReading{
+				reading("a", "1", "2020-01-01T00:00:00Z", 99950),
+				reading("a", "1", "2020-01-02T00:00:00Z", 30),
+			},
+			nil,
+		},
+		{
+			[]MeterReading{
+				reading("a", "1", "2020-01-01T00:00:00Z", 50000),
+				reading("a", "1", "2020-01-02T00:00:00Z", 30),
+			},
+			[][]int{{0, 1}},
+		},
+	} {
+		var values [][]int
+		for _, err := range Errors(ValidateMeterReadingOrder(&GS2{MeterReadings: test.readings})) {
+			var objectErr *ObjectError
+			if !errors.As(err, &objectErr) {
+				t.Fatalf("%d: expected an ObjectError, but got %v", i, err)
+			}
+			values = append(values, objectErr.Values)
+		}
+
+		if !reflect.DeepEqual(values, test.values) {
+			t.Errorf("%d: expected errors for %v, but got %v", i, test.values, values)
+		}
+	}
+}
+
+func TestValidateUTF8(t *testing.T) {
+	g := &GS2{
+		StartMessage:  StartMessage{ID: "0", From: "Tr\xf8ndelag"},
+		MeterReadings: []MeterReading{{Reference: "a", MeterLocation: "Trøndelag"}},
+		TimeSeries:    []TimeSeries{{Reference: "b", Description: "M\xe5lerverdier", Meter: "\xff"}},
+	}
+
+	errs := Errors(ValidateUTF8(g))
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, but got %d: %v", len(errs), errs)
+	}
+
+	for i, expected := range []string{
+		"Start-message 0: invalid UTF-8 in From",
+		"Time-series 0 (b): invalid UTF-8 in Meter, Description",
+	} {
+		if errs[i].Error() != expected {
+			t.Errorf("expected %q, but got %v", expected, errs[i])
+		}
+	}
+}